@@ -0,0 +1,66 @@
+package sqleak
+
+import (
+	"sync"
+	"time"
+)
+
+const (
+	// adaptiveMinSamples is the number of closes of a query from which WithAdaptiveTimeout derives its timeout.
+	adaptiveMinSamples = 100
+	// maxAdaptiveQueries bounds the query fingerprints tracked by WithAdaptiveTimeout.
+	maxAdaptiveQueries = 1024
+)
+
+// WithAdaptiveTimeout learns the distribution of the lifetimes of the resources of every query fingerprint and
+// times out resources after multiplier times the p99 of their own query, but never before min, instead of after the
+// single timeout of WithTimeout: a mixed workload of millisecond lookups and minute-long reports has no single
+// timeout that is right for both. The timeout of WithTimeout applies until a query was closed 100 times.
+// The p99 is estimated from the lifetime buckets growing by a factor of 4, see Histogram.Quantile.
+// Lifetimes of resources reported as leaked aren't learned. Of more than 1024 fingerprints, an arbitrary one is
+// forgotten for every new one. LeakEvent.Timeout is the timeout applied to the resource.
+func WithAdaptiveTimeout(multiplier float64, min time.Duration) Option {
+	return func(ld *monitoredDriver) {
+		ld.adaptive = &adaptiveTimeouts{multiplier: multiplier, min: min, queries: map[string]*lifetimes{}}
+	}
+}
+
+type adaptiveTimeouts struct {
+	multiplier float64
+	min        time.Duration
+
+	mu      sync.Mutex
+	queries map[string]*lifetimes // by query fingerprint
+}
+
+// timeout returns the timeout for a resource of the query with the given fingerprint.
+func (a *adaptiveTimeouts) timeout(fingerprint string, fallback time.Duration) time.Duration {
+	a.mu.Lock()
+	l := a.queries[fingerprint]
+	a.mu.Unlock()
+
+	if l == nil || l.count.Load() < adaptiveMinSamples {
+		return fallback
+	}
+
+	return max(a.min, time.Duration(a.multiplier*float64(l.histogram().Quantile(0.99))))
+}
+
+// observe records the lifetime of a closed resource of the query with the given fingerprint.
+func (a *adaptiveTimeouts) observe(fingerprint string, lifetime time.Duration) {
+	a.mu.Lock()
+	l := a.queries[fingerprint]
+	if l == nil {
+		if len(a.queries) >= maxAdaptiveQueries {
+			for evicted := range a.queries {
+				delete(a.queries, evicted)
+				break
+			}
+		}
+		l = &lifetimes{}
+		a.queries[fingerprint] = l
+	}
+	a.mu.Unlock()
+
+	l.observe(lifetime)
+}
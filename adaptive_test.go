@@ -0,0 +1,90 @@
+//go:build !sqleak_noop
+
+package sqleak
+
+import (
+	"context"
+	"strconv"
+	"testing"
+	"time"
+)
+
+func TestAdaptiveTimeout(t *testing.T) {
+	var events []LeakEvent
+	mc, fc, _ := newTestConn(t,
+		WithTimeout(time.Minute),
+		WithAdaptiveTimeout(10, 5*time.Millisecond),
+		WithOnLeak(func(ev LeakEvent) { events = append(events, ev) }),
+	)
+	ctx := context.Background()
+
+	// Lookups closed after a millisecond, differing only in their parameters.
+	for i := range adaptiveMinSamples {
+		rows, err := mc.QueryContext(ctx, "SELECT * FROM users WHERE id = "+strconv.Itoa(i), nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		fc.Advance(time.Millisecond)
+		rows.Close()
+	}
+
+	lookup, _ := mc.QueryContext(ctx, "SELECT * FROM users WHERE id = 42", nil)
+	defer lookup.Close()
+	report, _ := mc.QueryContext(ctx, "SELECT * FROM reports", nil)
+	defer report.Close()
+	fc.Advance(20 * time.Millisecond)
+
+	if len(events) != 1 {
+		t.Fatalf("got %d events, want the lookup only", len(events))
+	}
+	if ev := events[0]; ev.ResourceID != adaptiveMinSamples+1 || ev.Timeout != 10*time.Millisecond {
+		t.Errorf("unexpected event %+v", ev)
+	}
+
+	fc.Advance(time.Minute)
+	if len(events) != 2 || events[1].Query != "SELECT * FROM reports" || events[1].Timeout != time.Minute {
+		t.Errorf("expected the report to time out after the global timeout, got %+v", events)
+	}
+}
+
+func TestAdaptiveTimeoutIgnoresLeaks(t *testing.T) {
+	var timeouts []time.Duration
+	mc, fc, _ := newTestConn(t,
+		WithTimeout(time.Minute),
+		WithAdaptiveTimeout(10, 5*time.Millisecond),
+		WithOnLeak(func(ev LeakEvent) {
+			if ev.Type == EventLeak {
+				timeouts = append(timeouts, ev.Timeout)
+			}
+		}),
+	)
+	query := func(lifetime time.Duration) {
+		rows, err := mc.QueryContext(context.Background(), "SELECT * FROM users", nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		fc.Advance(lifetime)
+		rows.Close()
+	}
+
+	for range adaptiveMinSamples {
+		query(time.Millisecond)
+	}
+	// 2% of the resources leak and are closed late, every round.
+	for range 5 {
+		for range 98 {
+			query(time.Millisecond)
+		}
+		query(time.Second)
+		query(time.Second)
+	}
+
+	if len(timeouts) != 10 {
+		t.Fatalf("got %d leaks, want 10", len(timeouts))
+	}
+	for i, timeout := range timeouts {
+		if timeout != 10*time.Millisecond {
+			t.Errorf("leak %d timed out after %s, want the learned 10ms", i, timeout)
+		}
+	}
+}
@@ -0,0 +1,70 @@
+package sqleak
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"regexp"
+	"sort"
+	"strings"
+)
+
+// WithQueryAnonymizer replaces the query text of every reported event with the result of anonymize,
+// so that no raw SQL leaves the process. See HMACAnonymizer and HMACTablesAnonymizer.
+// anonymize is only called when an event is reported, not when resources are opened.
+func WithQueryAnonymizer(anonymize func(query string) string) Option {
+	return func(ld *monitoredDriver) {
+		ld.anonymize = anonymize
+	}
+}
+
+func (d *Detector) anonymizeQuery(query string) string {
+	if d.anonymize == nil || query == "" {
+		return query
+	}
+
+	return d.anonymize(query)
+}
+
+// HMACAnonymizer returns an anonymizer replacing query text with a keyed HMAC-SHA256 fingerprint like "hmac:3f2a9c...".
+// Identical statements yield identical fingerprints, so leaks can still be correlated by statement,
+// while the SQL text cannot be recovered without the key.
+func HMACAnonymizer(key []byte) func(query string) string {
+	return func(query string) string {
+		mac := hmac.New(sha256.New, key)
+		mac.Write([]byte(query))
+		return "hmac:" + hex.EncodeToString(mac.Sum(nil)[:16])
+	}
+}
+
+// HMACTablesAnonymizer is like HMACAnonymizer but additionally keeps the names of the tables the query references,
+// e.g. "hmac:3f2a9c... tables=orders,users".
+func HMACTablesAnonymizer(key []byte) func(query string) string {
+	fingerprint := HMACAnonymizer(key)
+
+	return func(query string) string {
+		tables := queryTables(query)
+		if len(tables) == 0 {
+			return fingerprint(query)
+		}
+		return fingerprint(query) + " tables=" + strings.Join(tables, ",")
+	}
+}
+
+var tableRefPattern = regexp.MustCompile(`(?i)\b(?:FROM|JOIN|INTO|UPDATE|TABLE)\s+([` + "`" + `"\[]?[\w.]+[` + "`" + `"\]]?)`)
+
+// queryTables extracts the sorted, deduplicated table names following FROM, JOIN, INTO, UPDATE and TABLE.
+func queryTables(query string) []string {
+	seen := map[string]bool{}
+	var tables []string
+	for _, m := range tableRefPattern.FindAllStringSubmatch(query, -1) {
+		table := strings.Trim(m[1], "`\"[]")
+		if !seen[table] {
+			seen[table] = true
+			tables = append(tables, table)
+		}
+	}
+	sort.Strings(tables)
+
+	return tables
+}
@@ -0,0 +1,29 @@
+package sqleak
+
+import (
+	"database/sql/driver"
+)
+
+// WithCaptureArgs records the bound parameters of queries producing Rows, so reports show which values a leaked
+// result set was queried with. Every parameter is passed through redact when the resource is opened and only its
+// result is kept, so personal data and secrets can be masked before they reach any log. A nil redact disables capture.
+// Parameters are only captured for sampled resources.
+func WithCaptureArgs(redact func(driver.NamedValue) string) Option {
+	return func(ld *monitoredDriver) {
+		ld.redactArg = redact
+	}
+}
+
+// captureArgs renders args with the redactor set by WithCaptureArgs, nil if capture is disabled.
+func (d *Detector) captureArgs(args []driver.NamedValue) []string {
+	if d.redactArg == nil || len(args) == 0 {
+		return nil
+	}
+
+	captured := make([]string, len(args))
+	for i, arg := range args {
+		captured[i] = d.redactArg(arg)
+	}
+
+	return captured
+}
@@ -0,0 +1,53 @@
+package sqleak
+
+import (
+	"context"
+	"maps"
+	"sort"
+	"strings"
+)
+
+// WithContextAttrs calls extract with the context every monitored resource is opened with and adds the attributes
+// it returns to the resource's leak reports and snapshots as LeakEvent.Attrs, e.g. request, tenant or user IDs
+// telling which request produced a leak. Labels from Annotate take precedence over extracted attributes.
+// Resources opened without a context, by Prepare or Begin, have none. extract is called on every open and should be
+// cheap.
+func WithContextAttrs(extract func(ctx context.Context) map[string]string) Option {
+	return func(ld *monitoredDriver) {
+		ld.extractAttrs = extract
+	}
+}
+
+// contextAttrs returns the attributes of the context a resource is opened with, see WithContextAttrs and Annotate.
+func (d *Detector) contextAttrs(ctx context.Context) map[string]string {
+	labels := contextLabels(ctx)
+	if d.extractAttrs == nil {
+		return labels
+	}
+
+	attrs := d.extractAttrs(ctx)
+	if len(labels) == 0 {
+		return attrs
+	}
+	merged := make(map[string]string, len(attrs)+len(labels))
+	maps.Copy(merged, attrs)
+	maps.Copy(merged, labels)
+
+	return merged
+}
+
+// describeAttrs renders attributes as space separated key=value pairs, ordered by key.
+func describeAttrs(attrs map[string]string) string {
+	keys := make([]string, 0, len(attrs))
+	for key := range attrs {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	pairs := make([]string, len(keys))
+	for i, key := range keys {
+		pairs[i] = key + "=" + attrs[key]
+	}
+
+	return strings.Join(pairs, " ")
+}
@@ -0,0 +1,49 @@
+//go:build !sqleak_noop
+
+package sqleak
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"time"
+)
+
+type tenantKey struct{}
+
+func TestContextAttrs(t *testing.T) {
+	var events []LeakEvent
+	mc, fc, logOutput := newTestConn(t,
+		WithTimeout(time.Second),
+		WithContextAttrs(func(ctx context.Context) map[string]string {
+			tenant, _ := ctx.Value(tenantKey{}).(string)
+			return map[string]string{"tenant": tenant, "request_id": "r-1"}
+		}),
+		WithOnLeak(func(ev LeakEvent) { events = append(events, ev) }),
+	)
+
+	ctx := context.WithValue(context.Background(), tenantKey{}, "acme")
+	rows, _ := mc.QueryContext(ctx, "SELECT 1", nil)
+	defer rows.Close()
+
+	if snapshot := mc.detector.Snapshot(); len(snapshot) != 1 || snapshot[0].Attrs["tenant"] != "acme" {
+		t.Errorf("expected the attributes in the snapshot, got %+v", snapshot)
+	}
+
+	fc.Advance(time.Second)
+	if len(events) != 1 || events[0].Attrs["tenant"] != "acme" {
+		t.Fatalf("expected the attributes in the event, got %+v", events)
+	}
+	if want := ", request_id=r-1 tenant=acme"; !strings.Contains(logOutput.String(), want) {
+		t.Errorf("expected %q in log, got:\n%s", want, logOutput.String())
+	}
+
+	b, err := events[0].MarshalJSON()
+	if err != nil {
+		t.Fatal(err)
+	}
+	decoded, err := DecodeLeakEvent(b)
+	if err != nil || decoded.Attrs["request_id"] != "r-1" {
+		t.Errorf("attributes lost in JSON: %s", b)
+	}
+}
@@ -0,0 +1,58 @@
+package sqleak
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"time"
+)
+
+// ErrAutoClosed is returned by Rows closed by WithAutoClose when the application reads from them again.
+var ErrAutoClosed = errors.New("sqleak: leaked Rows were closed by WithAutoClose")
+
+// WithAutoClose closes leaked Rows once they are still open a grace period after the first leak report, trading
+// a possibly confused caller for a pool that doesn't run dry. Closing the driver's Rows ends the query on the
+// connection; database/sql hands the connection back to the pool as soon as the application touches the sql.Rows
+// again, whose Next then returns false and Err ErrAutoClosed, or once the query's context is done. Connections of
+// Rows that are never touched again stay checked out, as database/sql doesn't let drivers release them.
+// Each auto-close is logged, instead of the report of a leaked resource closed late.
+func WithAutoClose(grace time.Duration) Option {
+	return func(ld *monitoredDriver) {
+		ld.autoClose = grace
+	}
+}
+
+// reclaimGrace returns the grace period after which a leaked resource of the kind is reclaimed, 0 for never.
+func (d *Detector) reclaimGrace(kind Kind) time.Duration {
+	if kind == KindRows {
+		return d.autoClose
+	}
+
+	return 0
+}
+
+// scheduleReclaim arms the reclaiming of the resource after the grace period following its first leak report.
+func (m *monitor) scheduleReclaim() {
+	grace := m.detector.reclaimGrace(m.kind)
+	if grace <= 0 || m.reclaim == nil {
+		return
+	}
+
+	m.detector.clock.AfterFunc(grace, func() {
+		if m.state.Load() != stateLeaked {
+			return
+		}
+
+		ok, err := m.reclaim()
+		if !ok {
+			return // closed by the application in the meantime
+		}
+
+		line := fmt.Sprintf("%sauto-closed leaked %s after %s (leak #%d, resource #%d)", m.detector.logPrefix(),
+			m.kind, m.detector.clock.Now().Sub(m.openedAt).Round(time.Millisecond), m.leakID.Load(), m.id)
+		if err != nil {
+			line += fmt.Sprintf(": %v", err)
+		}
+		log.Print(line)
+	})
+}
@@ -0,0 +1,49 @@
+//go:build !sqleak_noop
+
+package sqleak
+
+import (
+	"context"
+	"database/sql/driver"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestAutoClose(t *testing.T) {
+	var closed []time.Duration
+	mc, fc, logOutput := newTestConn(t,
+		WithTimeout(time.Second),
+		WithAutoClose(2*time.Second),
+		WithHooks(Hooks{OnClose: func(_ Resource, d time.Duration) { closed = append(closed, d) }}),
+	)
+	ctx := context.Background()
+
+	leaked, _ := mc.QueryContext(ctx, "SELECT 1", nil)
+	inTime, _ := mc.QueryContext(ctx, "SELECT 2", nil)
+	fc.Advance(time.Second / 2)
+	if err := inTime.Close(); err != nil {
+		t.Fatal(err)
+	}
+	fc.Advance(time.Second / 2)
+	fc.Advance(2 * time.Second)
+
+	if err := leaked.Next(make([]driver.Value, 1)); !errors.Is(err, ErrAutoClosed) {
+		t.Errorf("expected Next of auto-closed Rows to fail with ErrAutoClosed, got %v", err)
+	}
+	if err := leaked.Close(); err != nil {
+		t.Errorf("expected closing auto-closed Rows to succeed, got %v", err)
+	}
+	if len(closed) != 2 || closed[1] != 3*time.Second {
+		t.Errorf("expected the auto-close to be reported to hooks once after 3s, got %v", closed)
+	}
+
+	out := logOutput.String()
+	if want := "auto-closed leaked Rows after 3s (leak #1, resource #1)"; !strings.Contains(out, want) {
+		t.Errorf("expected %q in log, got:\n%s", want, out)
+	}
+	if strings.Contains(out, "closed late") {
+		t.Errorf("expected no closed late report for auto-closed Rows, got:\n%s", out)
+	}
+}
@@ -0,0 +1,91 @@
+package sqleak
+
+import (
+	"errors"
+	"log"
+	"time"
+)
+
+// ErrForcedRollback is returned when committing a Tx, or using its connection, after WithAutoRollback rolled it back.
+var ErrForcedRollback = errors.New("sqleak: transaction was rolled back by WithAutoRollback")
+
+// WithAutoRollback rolls back transactions still open deadline after they began, so forgotten transactions don't hold
+// locks until the database runs into an outage. Each forced rollback is reported as an EventForcedRollback.
+// Afterwards, queries on the transaction and its Commit fail with ErrForcedRollback, so statements meant for the
+// transaction don't silently run outside of it; Rollback succeeds and hands the connection back to the pool.
+// The deadline applies regardless of sampling, and is meant to be well beyond the leak timeout.
+func WithAutoRollback(deadline time.Duration) Option {
+	return func(ld *monitoredDriver) {
+		ld.autoRollback = deadline
+	}
+}
+
+// forceRollback rolls back the Tx at the WithAutoRollback deadline unless the application ended it already.
+// The rollback and its report run unlocked, as ending the Tx meanwhile is a no-op once it's marked done.
+func (mt *monitoredTx) forceRollback() {
+	mt.mu.Lock()
+	if mt.done {
+		mt.mu.Unlock()
+		return
+	}
+	mt.done = true
+	mt.rolledBack = true
+	mt.monitoredConn.rolledBack.Store(true)
+
+	m := mt.monitor
+	m.invalidateConn()
+	m.reclaimed.Store(true)
+	m.markClosed()
+	mt.mu.Unlock()
+
+	mt.monitoredConn.whenIdle(mt.rollbackForced)
+}
+
+// rollbackForced rolls back the Tx for forceRollback and reports it, once its connection is idle.
+func (mt *monitoredTx) rollbackForced() {
+	m := mt.monitor
+	err := mt.Tx.Rollback()
+
+	ev := m.leakEvent()
+	ev.Type = EventForcedRollback
+	ev.Age = m.detector.clock.Now().Sub(m.openedAt)
+	ev.quiet = false
+	m.detector.report(ev)
+	if err != nil {
+		log.Printf("%sforced rollback of Tx #%d failed: %v", m.detector.logPrefix(), m.id, err)
+	}
+}
+
+// enter marks the start of a driver call on the connection, serialized with forced rollbacks with WithAutoRollback.
+func (mc *monitoredConn) enter() {
+	if mc.detector.autoRollback > 0 {
+		mc.busy.Lock()
+	}
+}
+
+// leave marks the end of a driver call, running a forced rollback deferred meanwhile.
+func (mc *monitoredConn) leave() {
+	if mc.detector.autoRollback <= 0 {
+		return
+	}
+
+	for {
+		if rollback := mc.pendingRollback.Swap(nil); rollback != nil {
+			(*rollback)()
+		}
+		mc.busy.Unlock()
+		// A rollback deferred after the swap, while the lock was still held, is run by the first to get the lock.
+		if mc.pendingRollback.Load() == nil || !mc.busy.TryLock() {
+			return
+		}
+	}
+}
+
+// whenIdle runs rollback right away if no driver call is in progress on the connection, or once the call in progress
+// returns otherwise: database/sql promises drivers that a connection is never used concurrently.
+func (mc *monitoredConn) whenIdle(rollback func()) {
+	mc.pendingRollback.Store(&rollback)
+	if mc.busy.TryLock() {
+		mc.leave()
+	}
+}
@@ -0,0 +1,147 @@
+//go:build !sqleak_noop
+
+package sqleak
+
+import (
+	"context"
+	"database/sql/driver"
+	"errors"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestAutoRollback(t *testing.T) {
+	var events []LeakEvent
+	mc, fc, logOutput := newTestConn(t,
+		WithTimeout(time.Second),
+		WithAutoRollback(time.Minute),
+		WithOnLeak(func(ev LeakEvent) { events = append(events, ev) }),
+	)
+	ctx := context.Background()
+
+	committed, _ := mc.BeginTx(ctx, driver.TxOptions{})
+	fc.Advance(30 * time.Second)
+	if err := committed.Commit(); err != nil {
+		t.Fatal(err)
+	}
+
+	tx, _ := mc.BeginTx(ctx, driver.TxOptions{})
+	fc.Advance(time.Minute)
+
+	if len(events) != 4 {
+		t.Fatalf("got %d events, want 2 leaks, a close and a forced rollback", len(events))
+	}
+	ev := events[3]
+	if ev.Type != EventForcedRollback || ev.Kind != KindTx || ev.LeakID != 2 || ev.Age != time.Minute {
+		t.Errorf("unexpected event %+v", ev)
+	}
+	if want := "leaked transaction rolled back: Tx rolled back by sqleak after 1m0s (leak #2, resource #2"; !strings.Contains(logOutput.String(), want) {
+		t.Errorf("expected %q in log, got:\n%s", want, logOutput.String())
+	}
+
+	if _, err := mc.QueryContext(ctx, "SELECT 1", nil); !errors.Is(err, ErrForcedRollback) {
+		t.Errorf("expected queries in the rolled back Tx to fail with ErrForcedRollback, got %v", err)
+	}
+	if err := tx.Commit(); !errors.Is(err, ErrForcedRollback) {
+		t.Errorf("expected Commit to fail with ErrForcedRollback, got %v", err)
+	}
+	rows, err := mc.QueryContext(ctx, "SELECT 1", nil)
+	if err != nil {
+		t.Fatalf("expected the connection to be usable once the Tx ended, got %v", err)
+	}
+	rows.Close()
+	if len(events) != 4 {
+		t.Errorf("expected no closed late report after the forced rollback, got %+v", events[4:])
+	}
+}
+
+// blockingConn blocks queries until release is closed and records whether its Tx is rolled back during one.
+type blockingConn struct {
+	fakeConn
+	started, release chan struct{}
+	inUse            atomic.Bool
+	concurrent       atomic.Bool
+	rolledBack       atomic.Bool
+}
+
+func (c *blockingConn) BeginTx(context.Context, driver.TxOptions) (driver.Tx, error) {
+	return blockingTx{c}, nil
+}
+
+func (c *blockingConn) QueryContext(context.Context, string, []driver.NamedValue) (driver.Rows, error) {
+	c.inUse.Store(true)
+	defer c.inUse.Store(false)
+	close(c.started)
+	<-c.release
+	return &fakeRows{}, nil
+}
+
+type blockingTx struct{ conn *blockingConn }
+
+func (tx blockingTx) Commit() error { return nil }
+
+func (tx blockingTx) Rollback() error {
+	tx.conn.concurrent.Store(tx.conn.inUse.Load())
+	tx.conn.rolledBack.Store(true)
+	return nil
+}
+
+func TestAutoRollbackReportUnlocked(t *testing.T) {
+	var tx driver.Tx
+	rolledBack := make(chan error, 1)
+	mc, fc, _ := newTestConn(t,
+		WithTimeout(time.Hour),
+		WithAutoRollback(time.Minute),
+		WithOnLeak(func(ev LeakEvent) {
+			if ev.Type == EventForcedRollback {
+				rolledBack <- tx.Rollback() // deadlocks if reported while holding the Tx
+			}
+		}),
+	)
+
+	tx, _ = mc.BeginTx(context.Background(), driver.TxOptions{})
+	fc.Advance(time.Minute)
+	if err := <-rolledBack; err != nil {
+		t.Errorf("expected Rollback from the hook to succeed, got %v", err)
+	}
+}
+
+func TestAutoRollbackDuringQuery(t *testing.T) {
+	var events atomic.Int64
+	mc, fc, _ := newTestConn(t,
+		WithTimeout(time.Hour),
+		WithAutoRollback(time.Minute),
+		WithOnLeak(func(LeakEvent) { events.Add(1) }),
+	)
+	conn := &blockingConn{started: make(chan struct{}), release: make(chan struct{})}
+	mc.Conn = conn
+
+	tx, _ := mc.BeginTx(context.Background(), driver.TxOptions{})
+	defer tx.Rollback()
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		rows, err := mc.QueryContext(context.Background(), "SELECT pg_sleep(120)", nil)
+		if err == nil {
+			rows.Close()
+		}
+	}()
+	<-conn.started
+
+	fc.Advance(time.Minute)
+	if conn.rolledBack.Load() || events.Load() != 0 {
+		t.Error("expected the forced rollback to wait for the query in progress")
+	}
+
+	close(conn.release)
+	<-done
+	if !conn.rolledBack.Load() || conn.concurrent.Load() {
+		t.Errorf("expected the Tx to be rolled back once the query returned, rolled back %v, during the query %v",
+			conn.rolledBack.Load(), conn.concurrent.Load())
+	}
+	if events.Load() != 1 {
+		t.Errorf("expected the forced rollback to be reported, got %d events", events.Load())
+	}
+}
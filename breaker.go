@@ -0,0 +1,85 @@
+package sqleak
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"sync"
+	"sync/atomic"
+)
+
+// ErrCircuitOpen is returned by queries and executions rejected by WithCircuitBreaker.
+var ErrCircuitOpen = errors.New("sqleak: circuit breaker open")
+
+// WithCircuitBreaker fails new Query and Exec calls fast with an error wrapping ErrCircuitOpen while more than
+// maxLeaked resources that were reported as leaked are still open. Instead of the pool silently starving the
+// application until every request times out, callers get an explicit error they can handle, and queries are
+// accepted again as soon as enough leaked resources are closed. Tripping and recovering are logged.
+func WithCircuitBreaker(maxLeaked int) Option {
+	return func(ld *monitoredDriver) {
+		ld.breaker = &circuitBreaker{limit: int64(maxLeaked)}
+	}
+}
+
+// BreakerStats describes the state of the circuit breaker configured with WithCircuitBreaker.
+type BreakerStats struct {
+	Limit int
+	// Leaked is the number of leaked resources still open.
+	Leaked int
+	// Open reports whether queries are currently rejected.
+	Open bool
+	// Rejected counts the queries and executions rejected so far.
+	Rejected int64
+}
+
+type circuitBreaker struct {
+	limit    int64
+	leaked   atomic.Int64
+	open     atomic.Bool
+	rejected atomic.Int64
+
+	mu sync.Mutex // serializes tripping and recovering, so the state follows the latest count
+}
+
+// countLeakedOpen counts a resource reported as leaked, delta is -1 once it is closed.
+func (d *Detector) countLeakedOpen(delta int64) {
+	b := d.breaker
+	if b == nil {
+		return
+	}
+
+	b.leaked.Add(delta)
+
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	leaked := b.leaked.Load()
+	if open := leaked > b.limit; b.open.Load() != open {
+		b.open.Store(open)
+		if open {
+			log.Printf("%scircuit breaker open: %d leaked resources still open, rejecting queries until they are closed", d.logPrefix(), leaked)
+		} else {
+			log.Printf("%scircuit breaker closed: %d leaked resources still open, accepting queries again", d.logPrefix(), leaked)
+		}
+	}
+}
+
+// checkBreaker returns an error wrapping ErrCircuitOpen if the breaker rejects new queries.
+func (d *Detector) checkBreaker() error {
+	b := d.breaker
+	if b == nil || !b.open.Load() {
+		return nil
+	}
+
+	b.rejected.Add(1)
+	return fmt.Errorf("%w: more than %d leaked resources still open", ErrCircuitOpen, b.limit)
+}
+
+func (b *circuitBreaker) stats() BreakerStats {
+	return BreakerStats{
+		Limit:    int(b.limit),
+		Leaked:   int(b.leaked.Load()),
+		Open:     b.open.Load(),
+		Rejected: b.rejected.Load(),
+	}
+}
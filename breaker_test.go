@@ -0,0 +1,77 @@
+//go:build !sqleak_noop
+
+package sqleak
+
+import (
+	"context"
+	"errors"
+	"io"
+	"log"
+	"os"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestCircuitBreaker(t *testing.T) {
+	mc, fc, logOutput := newTestConn(t, WithTimeout(time.Second), WithCircuitBreaker(1))
+	ctx := context.Background()
+
+	first, _ := mc.QueryContext(ctx, "SELECT 1", nil)
+	second, _ := mc.QueryContext(ctx, "SELECT 2", nil)
+	fc.Advance(time.Second)
+
+	if _, err := mc.QueryContext(ctx, "SELECT 3", nil); !errors.Is(err, ErrCircuitOpen) {
+		t.Errorf("expected Query to fail with ErrCircuitOpen, got %v", err)
+	}
+	if _, err := mc.ExecContext(ctx, "DELETE FROM t", nil); !errors.Is(err, ErrCircuitOpen) {
+		t.Errorf("expected Exec to fail with ErrCircuitOpen, got %v", err)
+	}
+	if s := mc.detector.Stats().Breaker; !s.Open || s.Leaked != 2 || s.Rejected != 2 {
+		t.Errorf("unexpected breaker stats %+v", s)
+	}
+
+	first.Close()
+	rows, err := mc.QueryContext(ctx, "SELECT 3", nil)
+	if err != nil {
+		t.Fatalf("expected queries to be accepted once enough leaked resources are closed, got %v", err)
+	}
+	rows.Close()
+	second.Close()
+
+	for _, want := range []string{"circuit breaker open: 2 leaked resources still open", "circuit breaker closed: 1 leaked resources still open"} {
+		if !strings.Contains(logOutput.String(), want) {
+			t.Errorf("expected %q in log, got:\n%s", want, logOutput.String())
+		}
+	}
+}
+
+func TestCircuitBreakerConcurrent(t *testing.T) {
+	log.SetOutput(io.Discard)
+	t.Cleanup(func() { log.SetOutput(os.Stderr) })
+
+	d := newDetector(time.Second, "")
+	d.breaker = &circuitBreaker{limit: 5}
+	for range 5 {
+		d.countLeakedOpen(1)
+	}
+
+	// Leaks and closes racing around the limit, ending at the limit.
+	var wg sync.WaitGroup
+	for range 8 {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for range 1000 {
+				d.countLeakedOpen(1)
+				d.countLeakedOpen(-1)
+			}
+		}()
+	}
+	wg.Wait()
+
+	if s := d.breaker.stats(); s.Leaked != 5 || s.Open {
+		t.Errorf("expected the breaker closed at the limit, got %+v", s)
+	}
+}
@@ -0,0 +1,106 @@
+package sqleak
+
+import (
+	"sync"
+	"time"
+)
+
+// budgetBuckets is the granularity of the sliding leak budget window.
+const budgetBuckets = 24
+
+// WithLeakBudget tracks an error budget of at most maxLeaks confirmed leaks per window, e.g. 10 per 24 hours.
+// A leak counts against the budget when it is first reported, repeated warnings for the same resource don't.
+// onExhausted, if not nil, is called once when the budget is exceeded and again only after it recovered.
+// The remaining budget is exposed as Stats().Budget. The window slides with a granularity of window/24.
+// There is no budget by default, a window below 1 keeps it off.
+func WithLeakBudget(maxLeaks int, window time.Duration, onExhausted func(BudgetStats)) Option {
+	return func(ld *monitoredDriver) {
+		if window <= 0 {
+			return
+		}
+		ld.budget = &leakBudget{
+			limit:       maxLeaks,
+			window:      window,
+			onExhausted: onExhausted,
+		}
+	}
+}
+
+// BudgetStats describes the state of the leak budget configured with WithLeakBudget.
+type BudgetStats struct {
+	Limit  int
+	Window time.Duration
+	// Used is the number of leaks detected within the window.
+	Used int
+	// Remaining is Limit - Used, or zero if the budget is exceeded.
+	Remaining int
+	// Exhausted reports whether more than Limit leaks were detected within the window.
+	Exhausted bool
+}
+
+type leakBudget struct {
+	limit       int
+	window      time.Duration
+	onExhausted func(BudgetStats)
+
+	mu        sync.Mutex
+	buckets   [budgetBuckets]int
+	current   int64 // index of the current bucket since the zero time
+	exhausted bool
+}
+
+// bucketWidth is at least a nanosecond, for windows shorter than budgetBuckets nanoseconds.
+func (b *leakBudget) bucketWidth() time.Duration {
+	return max(b.window/budgetBuckets, 1)
+}
+
+// advanceLocked expires buckets that slid out of the window.
+func (b *leakBudget) advanceLocked(now time.Time) {
+	idx := now.UnixNano() / int64(b.bucketWidth())
+	if idx <= b.current {
+		return
+	}
+
+	for i := b.current + 1; i <= idx && i <= b.current+budgetBuckets; i++ {
+		b.buckets[i%budgetBuckets] = 0
+	}
+	b.current = idx
+}
+
+func (b *leakBudget) statsLocked() BudgetStats {
+	used := 0
+	for _, n := range b.buckets {
+		used += n
+	}
+
+	return BudgetStats{
+		Limit:     b.limit,
+		Window:    b.window,
+		Used:      used,
+		Remaining: max(b.limit-used, 0),
+		Exhausted: used > b.limit,
+	}
+}
+
+func (b *leakBudget) stats(now time.Time) BudgetStats {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	b.advanceLocked(now)
+	return b.statsLocked()
+}
+
+func (b *leakBudget) record(now time.Time) {
+	b.mu.Lock()
+	b.advanceLocked(now)
+	b.buckets[b.current%budgetBuckets]++
+
+	stats := b.statsLocked()
+	fire := stats.Exhausted && !b.exhausted
+	b.exhausted = stats.Exhausted
+	b.mu.Unlock()
+
+	if fire && b.onExhausted != nil {
+		b.onExhausted(stats)
+	}
+}
@@ -0,0 +1,22 @@
+package sqleak
+
+import "context"
+
+// watchCancel flags Rows and Tx once the context they were opened with is done. database/sql closes Rows and rolls
+// back Tx on cancellation by itself, so a cancelled resource isn't reported as leaked even if its close has not
+// arrived yet when the timeout elapses, e.g. while a call to Next still holds the Rows. Stmt outlive the context they
+// are prepared with.
+func (m *monitor) watchCancel(ctx context.Context) {
+	if m.kind == KindStmt || ctx.Done() == nil {
+		return
+	}
+
+	m.unwatch = context.AfterFunc(ctx, func() { m.canceled.Store(true) })
+}
+
+// unwatchCancel releases the watch of the context, once the resource is closed.
+func (m *monitor) unwatchCancel() {
+	if m.unwatch != nil {
+		m.unwatch()
+	}
+}
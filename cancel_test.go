@@ -0,0 +1,34 @@
+//go:build !sqleak_noop
+
+package sqleak
+
+import (
+	"context"
+	"testing"
+	"time"
+)
+
+func TestCanceledContextNotReported(t *testing.T) {
+	var events []LeakEvent
+	mc, fc, _ := newTestConn(t,
+		WithTimeout(time.Second),
+		WithOnLeak(func(ev LeakEvent) { events = append(events, ev) }),
+	)
+
+	// The close by database/sql is still pending when the timeout elapses.
+	ctx, cancel := context.WithCancel(context.Background())
+	canceled, _ := mc.QueryContext(ctx, "SELECT 1", nil)
+	defer canceled.Close()
+	cancel()
+	ctx, cancel = context.WithCancel(context.Background())
+	defer cancel()
+	active, _ := mc.QueryContext(ctx, "SELECT 2", nil)
+	defer active.Close()
+
+	time.Sleep(10 * time.Millisecond) // let the AfterFunc run
+	fc.Advance(time.Second)
+
+	if len(events) != 1 || events[0].Query != "SELECT 2" {
+		t.Errorf("expected only the Rows of the active context to leak, got %+v", events)
+	}
+}
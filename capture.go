@@ -0,0 +1,57 @@
+package sqleak
+
+import (
+	"encoding/hex"
+	"hash/fnv"
+	"strconv"
+)
+
+// Capture is what sqleak recorded about the call site that opened a resource.
+// Other instrumentation wrapping the same driver, e.g. otelsql or custom wrappers, can reuse it via CaptureOf
+// for caller attribution instead of capturing the stack a second time.
+type Capture struct {
+	// Stack is the stack trace of the opening goroutine, trimmed unless WithFullStacks. It is empty with WithCallerOnly.
+	Stack string
+	// Frames is Stack parsed into individual frames, innermost call first.
+	Frames []Frame
+	// Fingerprint identifies the call site by its frames, it is stable for the same code path within a build.
+	Fingerprint string
+	Kind        Kind
+}
+
+// CaptureOf returns the Capture of a driver.Rows, driver.Stmt or driver.Tx created by a sqleak driver, as seen by
+// a wrapper around it. It returns false for other values, and for resources without a captured stack
+// because they were not sampled or WithoutStacks is set.
+func CaptureOf(resource any) (Capture, bool) {
+	r, ok := resource.(interface{ sqleakMonitor() *monitor })
+	if !ok {
+		return Capture{}, false
+	}
+
+	m := r.sqleakMonitor()
+	if m.stack == nil && m.callers == nil {
+		return Capture{}, false
+	}
+
+	stack, frames := m.capturedStack()
+	return Capture{
+		Stack:       stack,
+		Frames:      frames,
+		Fingerprint: fingerprint(frames),
+		Kind:        m.kind,
+	}, true
+}
+
+// fingerprint hashes the function and position of frames, ignoring arguments and goroutine IDs.
+func fingerprint(frames []Frame) string {
+	h := fnv.New64a()
+	for _, f := range frames {
+		_, _ = h.Write([]byte(f.Function + " " + f.File + ":" + strconv.Itoa(f.Line) + "\n"))
+	}
+
+	return hex.EncodeToString(h.Sum(nil))
+}
+
+func (r *monitoredRows) sqleakMonitor() *monitor { return r.monitor }
+func (s *monitoredStmt) sqleakMonitor() *monitor { return s.monitor }
+func (mt *monitoredTx) sqleakMonitor() *monitor  { return mt.monitor }
@@ -0,0 +1,64 @@
+//go:build !sqleak_noop
+
+package sqleak
+
+import (
+	"context"
+	"database/sql/driver"
+	"testing"
+)
+
+func TestCaptureOf(t *testing.T) {
+	// This package's own frames would be trimmed, leaving only the test runner's.
+	mc, _, _ := newTestConn(t, WithFullStacks())
+	ctx := context.Background()
+
+	var resources []any
+	for i := 0; i < 2; i++ {
+		rows, _ := mc.QueryContext(ctx, "SELECT 1", nil)
+		defer rows.Close()
+		resources = append(resources, rows)
+	}
+	stmt, _ := mc.PrepareContext(ctx, "SELECT 1")
+	defer stmt.Close()
+	tx, _ := mc.BeginTx(ctx, driver.TxOptions{})
+	defer tx.Rollback()
+	resources = append(resources, stmt, tx)
+
+	var captures []Capture
+	for _, r := range resources {
+		c, ok := CaptureOf(r)
+		if !ok {
+			t.Fatalf("no capture for %T", r)
+		}
+		if c.Stack == "" || len(c.Frames) == 0 || c.Fingerprint == "" {
+			t.Errorf("incomplete capture for %T: %+v", r, c)
+		}
+		captures = append(captures, c)
+	}
+
+	if captures[0].Fingerprint != captures[1].Fingerprint {
+		t.Errorf("same call site got different fingerprints %s and %s", captures[0].Fingerprint, captures[1].Fingerprint)
+	}
+	if captures[0].Fingerprint == captures[2].Fingerprint {
+		t.Errorf("different call sites got the same fingerprint %s", captures[0].Fingerprint)
+	}
+	if captures[3].Kind != KindTx {
+		t.Errorf("got kind %s, want %s", captures[3].Kind, KindTx)
+	}
+
+	if _, ok := CaptureOf(&fakeRows{}); ok {
+		t.Error("expected no capture for a resource not created by sqleak")
+	}
+}
+
+func TestCaptureOfWithoutStacks(t *testing.T) {
+	mc, _, _ := newTestConn(t, WithoutStacks())
+
+	rows, _ := mc.QueryContext(context.Background(), "SELECT 1", nil)
+	defer rows.Close()
+
+	if _, ok := CaptureOf(rows); ok {
+		t.Error("expected no capture with WithoutStacks")
+	}
+}
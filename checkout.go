@@ -0,0 +1,102 @@
+package sqleak
+
+import (
+	"log"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// WithCheckoutWarning warns when a connection stays checked out of the pool for longer than max, with the stack of
+// the goroutine that checked it out. A connection is checked out from the moment database/sql hands it out, opened
+// or reused, until it's returned to the pool or closed. It catches connections pinned by other means than Rows and
+// Tx, e.g. a sql.Conn from DB.Conn that is never closed. Another line is logged once such a connection is returned.
+// A stack trace is captured on every checkout, Stats().LongCheckouts counts the warnings.
+func WithCheckoutWarning(max time.Duration) Option {
+	return func(ld *monitoredDriver) {
+		ld.checkout = &checkoutWarning{max: max}
+	}
+}
+
+type checkoutWarning struct {
+	max   time.Duration
+	conns atomic.Uint64 // connection IDs
+	count atomic.Int64
+}
+
+// connCheckout tracks the checkout of a single connection.
+type connCheckout struct {
+	id uint64
+
+	mu     sync.Mutex
+	out    bool
+	gen    uint64 // incremented by every checkout and return, invalidating the timer of the previous checkout
+	since  time.Time
+	stack  []byte
+	timer  timer
+	warned bool
+}
+
+// checkedOut starts the checkout of the connection, with WithCheckoutWarning.
+func (mc *monitoredConn) checkedOut() {
+	c := mc.checkout
+	if c == nil {
+		return
+	}
+	d := mc.detector
+	stack := d.currentStack()
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if c.timer != nil {
+		c.timer.Stop()
+	}
+	c.gen++
+	gen := c.gen
+	c.out, c.warned = true, false
+	c.since, c.stack = d.clock.Now(), stack
+	c.timer = d.clock.AfterFunc(d.checkout.max, func() { mc.checkoutExceeded(gen) })
+}
+
+// returned ends the checkout of the connection, logging how long it took if it was warned about.
+func (mc *monitoredConn) returned() {
+	c := mc.checkout
+	if c == nil {
+		return
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if !c.out {
+		return
+	}
+	c.gen++
+	c.out, c.stack = false, nil
+	c.timer.Stop()
+
+	if c.warned {
+		log.Printf("%sconnection #%d returned to the pool after %s", mc.detector.logPrefix(), c.id,
+			mc.detector.clock.Now().Sub(c.since).Round(time.Millisecond))
+	}
+}
+
+func (mc *monitoredConn) checkoutExceeded(gen uint64) {
+	c, d := mc.checkout, mc.detector
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if !c.out || c.gen != gen {
+		return
+	}
+	c.warned = true
+	d.checkout.count.Add(1)
+
+	stack := string(c.stack)
+	if !d.fullStacks {
+		stack = trimStack(stack)
+	}
+	log.Printf("%sconnection #%d checked out for more than %s, by:\n%s", d.logPrefix(), c.id, d.checkout.max, stack)
+}
@@ -0,0 +1,43 @@
+//go:build !sqleak_noop
+
+package sqleak
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestCheckoutWarning(t *testing.T) {
+	mc, fc, logOutput := newTestConn(t, WithTimeout(time.Hour), WithCheckoutWarning(time.Minute))
+
+	// Opened connections start checked out. Returned in time, the checkout starts over once the pool reuses it.
+	fc.Advance(30 * time.Second)
+	_ = mc.IsValid()
+	if err := mc.ResetSession(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	fc.Advance(45 * time.Second)
+	if out := logOutput.String(); out != "" {
+		t.Fatalf("expected no warning, got:\n%s", out)
+	}
+
+	fc.Advance(45 * time.Second)
+	_ = mc.IsValid()
+	fc.Advance(time.Hour)
+
+	out := logOutput.String()
+	if want := "connection #1 checked out for more than 1m0s, by:\ngoroutine "; !strings.Contains(out, want) {
+		t.Errorf("expected %q in log, got:\n%s", want, out)
+	}
+	if want := "connection #1 returned to the pool after 1m30s\n"; !strings.Contains(out, want) {
+		t.Errorf("expected %q in log, got:\n%s", want, out)
+	}
+	if n := strings.Count(out, "checked out for"); n != 1 {
+		t.Errorf("got %d warnings, want 1:\n%s", n, out)
+	}
+	if n := mc.detector.Stats().LongCheckouts; n != 1 {
+		t.Errorf("got %d long checkouts, want 1", n)
+	}
+}
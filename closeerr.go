@@ -0,0 +1,52 @@
+package sqleak
+
+// CloseErrorMode decides how resources whose Close, Commit or Rollback failed are treated, see WithCloseErrors.
+type CloseErrorMode string
+
+const (
+	// CloseErrorIgnore treats resources as closed even if closing them failed, the default.
+	CloseErrorIgnore CloseErrorMode = "ignore"
+	// CloseErrorKeepOpen keeps monitoring resources whose close failed as still open, so they are reported as leaked
+	// once their timeout elapses.
+	CloseErrorKeepOpen CloseErrorMode = "keep_open"
+	// CloseErrorReport treats resources whose close failed as closed, reporting each failed close as an
+	// EventCloseFailed with the error.
+	CloseErrorReport CloseErrorMode = "report"
+)
+
+// WithCloseErrors sets how resources whose Close, Commit or Rollback failed are treated. A failed rollback often
+// means the transaction is still open on the database, holding its locks. With a mode other than CloseErrorIgnore,
+// resources are marked closed only once the driver's close returned, so a leak timeout elapsing meanwhile is
+// still reported.
+func WithCloseErrors(mode CloseErrorMode) Option {
+	return func(ld *monitoredDriver) {
+		ld.closeErrors = mode
+	}
+}
+
+// closeWith runs the Close, Commit or Rollback op of the resource and marks it closed, before or, with
+// WithCloseErrors, after close depending on its error.
+func (m *monitor) closeWith(op string, close func() error) error {
+	d := m.detector
+	if d.closeErrors == "" || d.closeErrors == CloseErrorIgnore {
+		m.markClosed()
+		return close()
+	}
+
+	err := close()
+	if err != nil && d.closeErrors == CloseErrorKeepOpen {
+		return err
+	}
+	m.markClosed()
+
+	if err != nil {
+		ev := m.leakEvent()
+		ev.Type = EventCloseFailed
+		ev.Age = d.clock.Now().Sub(m.openedAt)
+		ev.Error = op + ": " + err.Error()
+		ev.quiet = false
+		d.report(ev)
+	}
+
+	return err
+}
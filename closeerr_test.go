@@ -0,0 +1,56 @@
+//go:build !sqleak_noop
+
+package sqleak
+
+import (
+	"context"
+	"database/sql/driver"
+	"strings"
+	"testing"
+	"time"
+)
+
+// failingTx fails to roll back.
+type failingTx struct{ fakeTx }
+
+func (failingTx) Rollback() error { return driver.ErrBadConn }
+
+func TestCloseErrors(t *testing.T) {
+	for _, tt := range []struct {
+		mode  CloseErrorMode
+		types []EventType
+	}{
+		{CloseErrorIgnore, nil},
+		{CloseErrorKeepOpen, []EventType{EventLeak}},
+		{CloseErrorReport, []EventType{EventCloseFailed}},
+	} {
+		t.Run(string(tt.mode), func(t *testing.T) {
+			var types []EventType
+			var events []LeakEvent
+			mc, fc, logOutput := newTestConn(t,
+				WithTimeout(time.Second),
+				WithCloseErrors(tt.mode),
+				WithOnLeak(func(ev LeakEvent) { types, events = append(types, ev.Type), append(events, ev) }),
+			)
+
+			tx := newMonitoredTx(context.Background(), failingTx{}, mc)
+			if err := tx.Rollback(); err != driver.ErrBadConn {
+				t.Fatalf("expected the rollback error, got %v", err)
+			}
+			fc.Advance(time.Second)
+
+			if len(types) != len(tt.types) || len(types) > 0 && types[0] != tt.types[0] {
+				t.Fatalf("got events %v, want %v", types, tt.types)
+			}
+			if tt.mode != CloseErrorReport {
+				return
+			}
+			if events[0].Error != "Rollback: driver: bad connection" {
+				t.Errorf("unexpected error %q", events[0].Error)
+			}
+			if want := "failed to close resource: Tx failed to close after 0s, Rollback: driver: bad connection"; !strings.Contains(logOutput.String(), want) {
+				t.Errorf("expected %q in log, got:\n%s", want, logOutput.String())
+			}
+		})
+	}
+}
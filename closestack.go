@@ -0,0 +1,49 @@
+package sqleak
+
+import "fmt"
+
+// WithCloseStacks records the stack of every Close, Commit and Rollback, so that the EventClosedLate of a leaked
+// resource shows both ends of its lifecycle: who opened it and who eventually closed it, and whether that was
+// another goroutine than the opening one. WithMisuseDetection reports double closes with the recorded stack.
+// It captures a stack trace per close, in addition to the one per open.
+func WithCloseStacks() Option {
+	return func(ld *monitoredDriver) {
+		ld.closeStacks = true
+	}
+}
+
+// recordsCloseStacks reports whether the stacks closing resources are recorded.
+func (d *Detector) recordsCloseStacks() bool {
+	return d.closeStacks || d.misuse != nil
+}
+
+// attachCloseStack sets the stack recorded when the resource was closed, with WithCloseStacks.
+func (m *monitor) attachCloseStack(ev *LeakEvent) {
+	if !m.detector.closeStacks {
+		return
+	}
+	p := m.closedBy.Load()
+	if p == nil {
+		return
+	}
+
+	stack := string(*p)
+	ev.CloseGoroutine = goroutineID(*p)
+	ev.CloseFrames = parseStack(stack)
+	if !m.detector.fullStacks {
+		stack, ev.CloseFrames = trimStack(stack), trimFrames(ev.CloseFrames)
+	}
+	ev.CloseStack = stack
+}
+
+// describeCloser names the goroutine that closed the resource, empty unless WithCloseStacks is set.
+func (ev LeakEvent) describeCloser() string {
+	switch {
+	case ev.CloseGoroutine == 0:
+		return ""
+	case ev.Goroutine != 0 && ev.Goroutine != ev.CloseGoroutine:
+		return fmt.Sprintf("closed by goroutine %d, not the opening goroutine %d", ev.CloseGoroutine, ev.Goroutine)
+	}
+
+	return fmt.Sprintf("closed by goroutine %d", ev.CloseGoroutine)
+}
@@ -0,0 +1,42 @@
+//go:build !sqleak_noop
+
+package sqleak
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestCloseStacks(t *testing.T) {
+	var events []LeakEvent
+	mc, fc, logOutput := newTestConn(t,
+		WithTimeout(time.Second),
+		WithCloseStacks(),
+		WithOnLeak(func(ev LeakEvent) { events = append(events, ev) }),
+	)
+
+	rows, _ := mc.QueryContext(context.Background(), "SELECT 1", nil)
+	fc.Advance(time.Second)
+	closed := make(chan struct{})
+	go func() {
+		defer close(closed)
+		rows.Close()
+	}()
+	<-closed
+
+	if len(events) != 2 {
+		t.Fatalf("got %d events, want a leak and its late close", len(events))
+	}
+	ev := events[1]
+	if ev.Type != EventClosedLate || ev.CloseGoroutine == 0 || ev.CloseGoroutine == ev.Goroutine || len(ev.CloseFrames) == 0 {
+		t.Errorf("unexpected late close %+v", ev)
+	}
+	if !strings.Contains(ev.CloseStack, "TestCloseStacks.func") {
+		t.Errorf("expected the closing goroutine's stack, got:\n%s", ev.CloseStack)
+	}
+	if want := ", not the opening goroutine "; !strings.Contains(logOutput.String(), want) {
+		t.Errorf("expected %q in log, got:\n%s", want, logOutput.String())
+	}
+}
@@ -0,0 +1,79 @@
+package sqleak
+
+import (
+	"database/sql/driver"
+	"fmt"
+	"strings"
+)
+
+// WithColumnMetadata records the column names and database type names of every Rows when the query returns,
+// and includes them in Rows leak reports, e.g. "returning 14 columns including 2 BYTEA". Wide results and large
+// column types hint at how much memory a leaked cursor pins on the server and in the driver.
+func WithColumnMetadata() Option {
+	return func(ld *monitoredDriver) {
+		ld.columnMetadata = true
+	}
+}
+
+// Column describes a column of a result set, see WithColumnMetadata.
+type Column struct {
+	Name string `json:"name"`
+	// DatabaseType is the driver's database type name, e.g. "BYTEA", or empty if the driver doesn't report it.
+	DatabaseType string `json:"database_type,omitempty"`
+}
+
+// largeColumnTypes are database type names of values that are potentially large, as reported by drivers.
+var largeColumnTypes = map[string]bool{
+	"BYTEA": true, "JSON": true, "JSONB": true, "XML": true, "TEXT": true,
+	"BLOB": true, "TINYBLOB": true, "MEDIUMBLOB": true, "LONGBLOB": true,
+	"MEDIUMTEXT": true, "LONGTEXT": true, "CLOB": true,
+}
+
+func rowsColumns(rows driver.Rows) []Column {
+	names := rows.Columns()
+	typed, _ := rows.(driver.RowsColumnTypeDatabaseTypeName)
+
+	columns := make([]Column, len(names))
+	for i, name := range names {
+		columns[i].Name = name
+		if typed != nil {
+			columns[i].DatabaseType = typed.ColumnTypeDatabaseTypeName(i)
+		}
+	}
+
+	return columns
+}
+
+// describeColumns summarizes columns for the text report, counting large column types by type.
+func describeColumns(columns []Column) string {
+	desc := fmt.Sprintf("returning %d columns", len(columns))
+	if len(columns) == 1 {
+		desc = "returning 1 column"
+	}
+
+	var (
+		types  []string
+		counts = map[string]int{}
+	)
+	for _, c := range columns {
+		typ := strings.ToUpper(c.DatabaseType)
+		if !largeColumnTypes[typ] {
+			continue
+		}
+		if counts[typ] == 0 {
+			types = append(types, typ)
+		}
+		counts[typ]++
+	}
+
+	if len(types) == 0 {
+		return desc
+	}
+
+	large := make([]string, len(types))
+	for i, typ := range types {
+		large[i] = fmt.Sprintf("%d %s", counts[typ], typ)
+	}
+
+	return desc + " including " + strings.Join(large, ", ")
+}
@@ -0,0 +1,401 @@
+package sqleak
+
+import (
+	"encoding/json"
+	"slices"
+	"time"
+)
+
+// Config is the effective configuration of a Detector after defaults and options have been applied.
+// Options taking functions are represented by whether they are set.
+type Config struct {
+	Name            string
+	Driver          string
+	Timeout         time.Duration
+	RepeatInterval  time.Duration
+	StackBufferSize int
+	Verbose         bool
+	JSONOutput      bool
+	DevOutput       bool
+	RuntimeTrace    bool
+	PprofProfiles   bool
+	InvalidateConn  bool
+	StrictClose     bool
+	PoolStats       bool
+	FailMode        FailMode
+	CloseErrors     CloseErrorMode
+	FullStacks      bool
+	CallerOnly      bool
+	WithoutStacks   bool
+	ColumnMetadata  bool
+	OpenerStack     bool
+	FullDumpOnLeak  bool
+	Serverless      bool
+	Disabled        bool
+
+	OwnerResolver   bool
+	StackFilter     bool
+	OnLeakCallbacks int
+	OnOpenHooks     int
+	OnCloseHooks    int
+	Sinks           int
+	SingleLineLog   bool
+	QueryAnonymizer bool
+	CaptureArgs     bool
+	QueryNormalizer bool
+	TraceExtractor  bool
+	ContextAttrs    bool
+	ContextSampling bool
+	ShouldMonitor   bool
+
+	// SamplePerSite and SampleWindow are set by WithAdaptiveSampling, FirstCaptures by WithFirstCaptures.
+	// SampleRate is the fraction of resources monitored, 1 unless WithSampleRate is set.
+	SamplePerSite int
+	SampleRate    float64
+	SampleWindow  time.Duration
+	FirstCaptures int
+
+	// HoldMaxOpenConns and HoldInterval are set by WithConnHoldLimit.
+	HoldMaxOpenConns int
+	HoldInterval     time.Duration
+
+	// Deduplication and DedupSummaryInterval are set by WithDeduplication.
+	Deduplication        bool
+	DedupSummaryInterval time.Duration
+
+	// BudgetLimit and BudgetWindow are set by WithLeakBudget.
+	BudgetLimit  int
+	BudgetWindow time.Duration
+
+	// EscalateErrorAfter and EscalateCriticalAfter are set by WithSeverityEscalation, 0 for ignored levels.
+	EscalateErrorAfter    float64
+	EscalateCriticalAfter float64
+
+	// AutoCloseGrace is set by WithAutoClose.
+	AutoCloseGrace time.Duration
+	// AutoRollback is the deadline set by WithAutoRollback.
+	AutoRollback time.Duration
+	// LongTxThreshold is set by WithLongTxThreshold.
+	LongTxThreshold time.Duration
+	// StallTimeout is the idle period set by WithStallTimeout.
+	StallTimeout time.Duration
+	// IdleTimeoutKinds are the kinds whose timeout counts from their last activity, set by WithIdleTimeout.
+	IdleTimeoutKinds []Kind
+
+	// SummaryInterval is set by WithSummaryInterval.
+	SummaryInterval time.Duration
+	// SlowConnectThreshold is set by WithSlowConnectThreshold.
+	SlowConnectThreshold time.Duration
+	// CheckoutWarning is the checkout duration set by WithCheckoutWarning.
+	CheckoutWarning time.Duration
+	// SlowQueryThreshold is set by WithSlowQueryThreshold.
+	SlowQueryThreshold time.Duration
+	// RepreparedCount and RepreparedWindow are set by WithRepreparedWarning.
+	RepreparedCount  int
+	RepreparedWindow time.Duration
+	// UnusedStmtWarning is set by WithUnusedStmtWarning.
+	UnusedStmtWarning bool
+	// MisuseDetection is set by WithMisuseDetection.
+	MisuseDetection bool
+	// CloseStacks is set by WithCloseStacks.
+	CloseStacks bool
+	// ManualTxTracking is set by WithManualTxTracking.
+	ManualTxTracking bool
+
+	// BreakerMaxLeaked is set by WithCircuitBreaker.
+	BreakerMaxLeaked int
+
+	// Suppressions are the patterns set by WithSuppressions.
+	Suppressions []string
+
+	// AdaptiveMultiplier and AdaptiveMinTimeout are set by WithAdaptiveTimeout.
+	AdaptiveMultiplier float64
+	AdaptiveMinTimeout time.Duration
+
+	// Health is set by WithHealthThresholds.
+	Health HealthThresholds
+}
+
+// Config returns the effective configuration of the Detector.
+func (d *Detector) Config() Config {
+	if noop {
+		return Config{Disabled: true}
+	}
+	c := Config{
+		Name:            d.name,
+		Driver:          d.driverName,
+		Timeout:         d.timeout,
+		RepeatInterval:  d.repeatInterval,
+		StackBufferSize: d.stackBufferSize,
+		Verbose:         d.verbose,
+		JSONOutput:      d.jsonOutput,
+		DevOutput:       d.devOutput,
+		RuntimeTrace:    d.runtimeTrace,
+		PprofProfiles:   d.pprofProfiles,
+		InvalidateConn:  d.invalidateConn,
+		StrictClose:     d.strictClose,
+		PoolStats:       d.poolStats != nil || d.poolFromOpen,
+		FailMode:        FailLog,
+		CloseErrors:     CloseErrorIgnore,
+		FullStacks:      d.fullStacks,
+		CallerOnly:      d.callerOnly,
+		WithoutStacks:   d.withoutStacks,
+		ColumnMetadata:  d.columnMetadata,
+		OpenerStack:     d.openerStack,
+		CloseStacks:     d.closeStacks,
+		MisuseDetection: d.misuse != nil,
+		FullDumpOnLeak:  d.fullDump != nil,
+		Serverless:      d.serverless,
+		Disabled:        d.disabled,
+		OwnerResolver:   d.ownerResolver != nil,
+		StackFilter:     d.stackFilter != nil,
+		OnLeakCallbacks: len(d.onLeak),
+		OnOpenHooks:     len(d.onOpen),
+		OnCloseHooks:    len(d.onClose),
+		Sinks:           len(d.sinks),
+		SingleLineLog:   d.singleLineLog,
+		QueryAnonymizer: d.anonymize != nil,
+		CaptureArgs:     d.redactArg != nil,
+		QueryNormalizer: d.normalize != nil,
+		TraceExtractor:  d.extractTrace != nil,
+		ContextAttrs:    d.extractAttrs != nil,
+		ContextSampling: d.sampleContext != nil,
+		ShouldMonitor:   d.shouldMonitor != nil,
+		SampleRate:      1,
+		AutoCloseGrace:  d.autoClose,
+		AutoRollback:    d.autoRollback,
+		LongTxThreshold: d.longTx,
+		StallTimeout:    d.stallTimeout,
+		Health:          d.health,
+	}
+
+	if d.failMode != "" {
+		c.FailMode = d.failMode
+	}
+	if d.closeErrors != "" {
+		c.CloseErrors = d.closeErrors
+	}
+	if d.sampler != nil {
+		c.SamplePerSite = int(d.sampler.perSite)
+		c.SampleWindow = d.sampler.window
+		c.FirstCaptures = int(d.sampler.firstN)
+		c.SampleRate = d.sampler.rate
+	}
+	if d.hold != nil {
+		c.HoldMaxOpenConns = d.hold.maxOpenConns
+		c.HoldInterval = d.hold.interval
+	}
+	if d.dedup != nil {
+		c.Deduplication = true
+		c.DedupSummaryInterval = d.dedup.interval
+	}
+	if d.budget != nil {
+		c.BudgetLimit = d.budget.limit
+		c.BudgetWindow = d.budget.window
+	}
+	if d.summary != nil {
+		c.SummaryInterval = d.summary.interval
+	}
+	if d.slowConnect != nil {
+		c.SlowConnectThreshold = d.slowConnect.threshold
+	}
+	if d.checkout != nil {
+		c.CheckoutWarning = d.checkout.max
+	}
+	if d.slowQuery != nil {
+		c.SlowQueryThreshold = d.slowQuery.threshold
+	}
+	if d.reprepared != nil {
+		c.RepreparedCount = d.reprepared.count
+		c.RepreparedWindow = d.reprepared.window
+	}
+	c.UnusedStmtWarning = d.unusedStmts != nil
+	c.ManualTxTracking = d.manualTx != nil
+	for _, kind := range []Kind{KindRows, KindStmt, KindTx} {
+		if d.idleTimeout[kindIndex(kind)] {
+			c.IdleTimeoutKinds = append(c.IdleTimeoutKinds, kind)
+		}
+	}
+	if d.suppress != nil {
+		c.Suppressions = slices.Clone(d.suppress.patterns)
+	}
+	if d.adaptive != nil {
+		c.AdaptiveMultiplier = d.adaptive.multiplier
+		c.AdaptiveMinTimeout = d.adaptive.min
+	}
+	if d.breaker != nil {
+		c.BreakerMaxLeaked = int(d.breaker.limit)
+	}
+	if d.escalation != nil {
+		c.EscalateErrorAfter = d.escalation.errorAfter
+		c.EscalateCriticalAfter = d.escalation.criticalAfter
+	}
+
+	return c
+}
+
+// jsonDuration encodes a duration as a Go duration string, omitted when zero.
+type jsonDuration time.Duration
+
+func (d jsonDuration) MarshalJSON() ([]byte, error) {
+	return json.Marshal(time.Duration(d).String())
+}
+
+// MarshalJSON encodes the configuration with durations as Go duration strings and unset options omitted.
+func (c Config) MarshalJSON() ([]byte, error) {
+	optional := func(d time.Duration) *jsonDuration {
+		if d == 0 {
+			return nil
+		}
+		v := jsonDuration(d)
+		return &v
+	}
+
+	return json.Marshal(struct {
+		Name             string        `json:"name,omitempty"`
+		Driver           string        `json:"driver"`
+		Timeout          jsonDuration  `json:"timeout"`
+		RepeatInterval   *jsonDuration `json:"repeat_interval,omitempty"`
+		StackBufferSize  int           `json:"stack_buffer_size"`
+		Verbose          bool          `json:"verbose"`
+		JSONOutput       bool          `json:"json_output"`
+		DevOutput        bool          `json:"dev_output"`
+		RuntimeTrace     bool          `json:"runtime_trace"`
+		PprofProfiles    bool          `json:"pprof_profiles"`
+		InvalidateConn   bool          `json:"invalidate_conn"`
+		StrictClose      bool          `json:"strict_close"`
+		PoolStats        bool          `json:"pool_stats"`
+		FailMode         FailMode      `json:"fail_mode"`
+		CloseErrors      string        `json:"close_errors"`
+		FullStacks       bool          `json:"full_stacks"`
+		CallerOnly       bool          `json:"caller_only"`
+		WithoutStacks    bool          `json:"without_stacks"`
+		ColumnMetadata   bool          `json:"column_metadata"`
+		OpenerStack      bool          `json:"opener_stack"`
+		FullDumpOnLeak   bool          `json:"full_dump_on_leak"`
+		Serverless       bool          `json:"serverless"`
+		Disabled         bool          `json:"disabled"`
+		OwnerResolver    bool          `json:"owner_resolver"`
+		StackFilter      bool          `json:"stack_filter"`
+		OnLeakCallbacks  int           `json:"on_leak_callbacks"`
+		OnOpenHooks      int           `json:"on_open_hooks"`
+		OnCloseHooks     int           `json:"on_close_hooks"`
+		Sinks            int           `json:"sinks"`
+		SingleLineLog    bool          `json:"single_line_log"`
+		QueryAnonymizer  bool          `json:"query_anonymizer"`
+		CaptureArgs      bool          `json:"capture_args"`
+		QueryNormalizer  bool          `json:"query_normalizer"`
+		TraceExtractor   bool          `json:"trace_extractor"`
+		ContextAttrs     bool          `json:"context_attrs"`
+		ContextSampling  bool          `json:"context_sampling"`
+		ShouldMonitor    bool          `json:"should_monitor"`
+		SamplePerSite    int           `json:"sample_per_site,omitempty"`
+		SampleWindow     *jsonDuration `json:"sample_window,omitempty"`
+		SampleRate       float64       `json:"sample_rate"`
+		FirstCaptures    int           `json:"first_captures,omitempty"`
+		HoldMaxOpenConns int           `json:"hold_max_open_conns,omitempty"`
+		HoldInterval     *jsonDuration `json:"hold_interval,omitempty"`
+		Deduplication    bool          `json:"deduplication"`
+		DedupSummary     *jsonDuration `json:"dedup_summary_interval,omitempty"`
+		BudgetLimit      int           `json:"budget_limit,omitempty"`
+		BudgetWindow     *jsonDuration `json:"budget_window,omitempty"`
+		EscalateError    float64       `json:"escalate_error_after,omitempty"`
+		EscalateCritical float64       `json:"escalate_critical_after,omitempty"`
+		AutoCloseGrace   *jsonDuration `json:"auto_close_grace,omitempty"`
+		AutoRollback     *jsonDuration `json:"auto_rollback_deadline,omitempty"`
+		LongTxThreshold  *jsonDuration `json:"long_tx_threshold,omitempty"`
+		StallTimeout     *jsonDuration `json:"stall_timeout,omitempty"`
+		IdleTimeoutKinds []Kind        `json:"idle_timeout_kinds,omitempty"`
+		SummaryInterval  *jsonDuration `json:"summary_interval,omitempty"`
+		SlowConnect      *jsonDuration `json:"slow_connect_threshold,omitempty"`
+		CheckoutWarning  *jsonDuration `json:"checkout_warning,omitempty"`
+		SlowQuery        *jsonDuration `json:"slow_query_threshold,omitempty"`
+		RepreparedCount  int           `json:"reprepared_count,omitempty"`
+		RepreparedWindow *jsonDuration `json:"reprepared_window,omitempty"`
+		UnusedStmts      bool          `json:"unused_stmt_warning"`
+		MisuseDetection  bool          `json:"misuse_detection"`
+		CloseStacks      bool          `json:"close_stacks"`
+		ManualTxTracking bool          `json:"manual_tx_tracking"`
+		BreakerMaxLeaked int           `json:"breaker_max_leaked,omitempty"`
+		AdaptiveFactor   float64       `json:"adaptive_multiplier,omitempty"`
+		AdaptiveMin      *jsonDuration `json:"adaptive_min_timeout,omitempty"`
+		Suppressions     []string      `json:"suppressions,omitempty"`
+		HealthTotal      int           `json:"health_max_leaked,omitempty"`
+		HealthRows       int           `json:"health_max_leaked_rows,omitempty"`
+		HealthStmt       int           `json:"health_max_leaked_stmt,omitempty"`
+		HealthTx         int           `json:"health_max_leaked_tx,omitempty"`
+	}{
+		Name:             c.Name,
+		Driver:           c.Driver,
+		Timeout:          jsonDuration(c.Timeout),
+		RepeatInterval:   optional(c.RepeatInterval),
+		StackBufferSize:  c.StackBufferSize,
+		Verbose:          c.Verbose,
+		JSONOutput:       c.JSONOutput,
+		DevOutput:        c.DevOutput,
+		RuntimeTrace:     c.RuntimeTrace,
+		PprofProfiles:    c.PprofProfiles,
+		InvalidateConn:   c.InvalidateConn,
+		StrictClose:      c.StrictClose,
+		PoolStats:        c.PoolStats,
+		FailMode:         c.FailMode,
+		CloseErrors:      string(c.CloseErrors),
+		FullStacks:       c.FullStacks,
+		CallerOnly:       c.CallerOnly,
+		WithoutStacks:    c.WithoutStacks,
+		ColumnMetadata:   c.ColumnMetadata,
+		OpenerStack:      c.OpenerStack,
+		FullDumpOnLeak:   c.FullDumpOnLeak,
+		Serverless:       c.Serverless,
+		Disabled:         c.Disabled,
+		OwnerResolver:    c.OwnerResolver,
+		StackFilter:      c.StackFilter,
+		OnLeakCallbacks:  c.OnLeakCallbacks,
+		OnOpenHooks:      c.OnOpenHooks,
+		OnCloseHooks:     c.OnCloseHooks,
+		Sinks:            c.Sinks,
+		SingleLineLog:    c.SingleLineLog,
+		QueryAnonymizer:  c.QueryAnonymizer,
+		CaptureArgs:      c.CaptureArgs,
+		QueryNormalizer:  c.QueryNormalizer,
+		TraceExtractor:   c.TraceExtractor,
+		ContextAttrs:     c.ContextAttrs,
+		ContextSampling:  c.ContextSampling,
+		ShouldMonitor:    c.ShouldMonitor,
+		SamplePerSite:    c.SamplePerSite,
+		SampleWindow:     optional(c.SampleWindow),
+		SampleRate:       c.SampleRate,
+		FirstCaptures:    c.FirstCaptures,
+		HoldMaxOpenConns: c.HoldMaxOpenConns,
+		HoldInterval:     optional(c.HoldInterval),
+		Deduplication:    c.Deduplication,
+		DedupSummary:     optional(c.DedupSummaryInterval),
+		BudgetLimit:      c.BudgetLimit,
+		BudgetWindow:     optional(c.BudgetWindow),
+		EscalateError:    c.EscalateErrorAfter,
+		EscalateCritical: c.EscalateCriticalAfter,
+		AutoCloseGrace:   optional(c.AutoCloseGrace),
+		AutoRollback:     optional(c.AutoRollback),
+		LongTxThreshold:  optional(c.LongTxThreshold),
+		StallTimeout:     optional(c.StallTimeout),
+		IdleTimeoutKinds: c.IdleTimeoutKinds,
+		SummaryInterval:  optional(c.SummaryInterval),
+		SlowConnect:      optional(c.SlowConnectThreshold),
+		CheckoutWarning:  optional(c.CheckoutWarning),
+		SlowQuery:        optional(c.SlowQueryThreshold),
+		RepreparedCount:  c.RepreparedCount,
+		RepreparedWindow: optional(c.RepreparedWindow),
+		UnusedStmts:      c.UnusedStmtWarning,
+		MisuseDetection:  c.MisuseDetection,
+		CloseStacks:      c.CloseStacks,
+		ManualTxTracking: c.ManualTxTracking,
+		BreakerMaxLeaked: c.BreakerMaxLeaked,
+		AdaptiveFactor:   c.AdaptiveMultiplier,
+		AdaptiveMin:      optional(c.AdaptiveMinTimeout),
+		Suppressions:     c.Suppressions,
+		HealthTotal:      c.Health.Total,
+		HealthRows:       c.Health.Rows,
+		HealthStmt:       c.Health.Stmt,
+		HealthTx:         c.Health.Tx,
+	})
+}
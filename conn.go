@@ -5,7 +5,8 @@ import (
 	"database/sql"
 	"database/sql/driver"
 	"errors"
-	"time"
+	"sync"
+	"sync/atomic"
 )
 
 var (
@@ -23,14 +24,39 @@ var (
 
 type monitoredConn struct {
 	driver.Conn
-	timeout time.Duration
+	detector *Detector
+	database *database   // nil if opened without a data source name
+	inTx     atomic.Bool // Rows opened within a Tx don't pin the connection on their own
+
+	rolledBack atomic.Bool // the Tx in progress was rolled back by WithAutoRollback
+	invalid    atomic.Bool // a resource of the connection leaked, with WithInvalidateConn
+
+	checkout *connCheckout   // nil without WithCheckoutWarning
+	prepares *prepareTracker // nil without WithRepreparedWarning
+	manualTx *manualTxState  // nil without WithManualTxTracking
+
+	tx atomic.Pointer[monitor] // of the Tx in progress
+
+	busy            sync.Mutex             // held during driver calls with WithAutoRollback, see whenIdle
+	pendingRollback atomic.Pointer[func()] // forced rollback waiting for the driver call in progress
 }
 
-func newMonitoredConn(conn driver.Conn, timeout time.Duration) *monitoredConn {
-	return &monitoredConn{
-		Conn:    conn,
-		timeout: timeout,
+func newMonitoredConn(conn driver.Conn, d *Detector) *monitoredConn {
+	mc := &monitoredConn{
+		Conn:     conn,
+		detector: d,
 	}
+	if d.checkout != nil {
+		mc.checkout = &connCheckout{id: d.checkout.conns.Add(1)}
+	}
+	if d.reprepared != nil {
+		mc.prepares = &prepareTracker{queries: make(map[string]*preparedQuery)}
+	}
+	if d.manualTx != nil {
+		mc.manualTx = &manualTxState{}
+	}
+
+	return mc
 }
 
 func (mc *monitoredConn) Ping(ctx context.Context) (err error) {
@@ -43,62 +69,131 @@ func (mc *monitoredConn) Ping(ctx context.Context) (err error) {
 	return pinger.Ping(ctx)
 }
 
-func (mc *monitoredConn) Exec(query string, args []driver.Value) (driver.Result, error) {
-	execer, ok := mc.Conn.(driver.Execer) // nolint
-	if !ok {
-		return nil, driver.ErrSkip
-	}
+func (mc *monitoredConn) Close() error {
+	mc.returned()
+	return mc.Conn.Close()
+}
 
-	return execer.Exec(query, args)
+// Exec and Query are the legacy variants of ExecContext and QueryContext. All four share exec and query,
+// so a resource is monitored the same way no matter which variant database/sql or the driver supports.
+func (mc *monitoredConn) Exec(query string, args []driver.Value) (driver.Result, error) {
+	return mc.exec(context.Background(), query, valueToNamedValue(args))
 }
 
 func (mc *monitoredConn) ExecContext(ctx context.Context, query string, args []driver.NamedValue) (driver.Result, error) {
-	execer, ok := mc.Conn.(driver.ExecerContext)
-	if !ok {
-		return nil, driver.ErrSkip
-	}
-
-	return execer.ExecContext(ctx, query, args)
+	return mc.exec(ctx, query, args)
 }
 
 func (mc *monitoredConn) Query(query string, args []driver.Value) (driver.Rows, error) {
-	queryer, ok := mc.Conn.(driver.Queryer) // nolint
+	return mc.query(context.Background(), query, valueToNamedValue(args))
+}
+
+func (mc *monitoredConn) QueryContext(ctx context.Context, query string, args []driver.NamedValue) (driver.Rows, error) {
+	return mc.query(ctx, query, args)
+}
+
+// exec prefers the driver's ExecerContext and falls back to its Execer, like database/sql does for unwrapped drivers.
+func (mc *monitoredConn) exec(ctx context.Context, query string, args []driver.NamedValue) (driver.Result, error) {
+	if err := mc.admit(); err != nil {
+		return nil, err
+	}
+	mc.enter()
+	defer mc.leave()
+	defer mc.detector.queryDone(mc.detector.queryStarted(), query)
+	mc.trackManualTx(query)
+	mc.touchTx()
+
+	if execer, ok := mc.Conn.(driver.ExecerContext); ok {
+		return execer.ExecContext(ctx, query, args)
+	}
+
+	execer, ok := mc.Conn.(driver.Execer) // nolint
 	if !ok {
 		return nil, driver.ErrSkip
 	}
 
-	rows, err := queryer.Query(query, args)
+	dargs, err := namedValueToValue(args)
 	if err != nil {
 		return nil, err
 	}
 
-	return newMonitoredRows(rows, mc.timeout), nil
+	select {
+	default:
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	}
+
+	return execer.Exec(query, dargs)
 }
 
-func (mc *monitoredConn) QueryContext(ctx context.Context, query string, args []driver.NamedValue) (driver.Rows, error) {
-	queryer, ok := mc.Conn.(driver.QueryerContext)
-	if !ok {
-		return nil, driver.ErrSkip
+// query prefers the driver's QueryerContext and falls back to its Queryer, like database/sql does for unwrapped drivers.
+func (mc *monitoredConn) query(ctx context.Context, query string, args []driver.NamedValue) (rows driver.Rows, err error) {
+	if err = mc.admit(); err != nil {
+		return nil, err
 	}
+	mc.enter()
+	defer mc.leave()
+	defer mc.detector.queryDone(mc.detector.queryStarted(), query)
+	mc.trackManualTx(query)
+	mc.touchTx()
+
+	if queryer, ok := mc.Conn.(driver.QueryerContext); ok {
+		if rows, err = queryer.QueryContext(ctx, query, args); err != nil {
+			return nil, err
+		}
+	} else {
+		queryer, ok := mc.Conn.(driver.Queryer) // nolint
+		if !ok {
+			return nil, driver.ErrSkip
+		}
 
-	rows, err := queryer.QueryContext(ctx, query, args)
-	if err != nil {
-		return nil, err
+		var dargs []driver.Value
+		if dargs, err = namedValueToValue(args); err != nil {
+			return nil, err
+		}
+
+		select {
+		default:
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		}
+
+		if rows, err = queryer.Query(query, dargs); err != nil {
+			return nil, err
+		}
 	}
 
-	return newMonitoredRows(rows, mc.timeout), nil
+	return newMonitoredRows(ctx, rows, mc, query, args), nil
+}
+
+// admit returns an error if a query or execution must not run on the connection.
+func (mc *monitoredConn) admit() error {
+	if mc.invalid.Load() {
+		return driver.ErrBadConn
+	}
+	if mc.rolledBack.Load() {
+		return ErrForcedRollback
+	}
+
+	return mc.detector.checkBreaker()
 }
 
 func (mc *monitoredConn) Prepare(query string) (driver.Stmt, error) {
+	mc.enter()
+	defer mc.leave()
+
 	stmt, err := mc.Conn.Prepare(query)
 	if err != nil {
 		return nil, err
 	}
 
-	return newMonitoredStmt(stmt, mc), nil
+	return newMonitoredStmt(context.Background(), stmt, mc, query), nil
 }
 
 func (mc *monitoredConn) PrepareContext(ctx context.Context, query string) (stmt driver.Stmt, err error) {
+	mc.enter()
+	defer mc.leave()
+
 	if preparer, ok := mc.Conn.(driver.ConnPrepareContext); ok {
 		if stmt, err = preparer.PrepareContext(ctx, query); err != nil {
 			return nil, err
@@ -116,7 +211,7 @@ func (mc *monitoredConn) PrepareContext(ctx context.Context, query string) (stmt
 		}
 	}
 
-	return newMonitoredStmt(stmt, mc), nil
+	return newMonitoredStmt(ctx, stmt, mc, query), nil
 }
 
 func (mc *monitoredConn) Begin() (driver.Tx, error) {
@@ -125,7 +220,7 @@ func (mc *monitoredConn) Begin() (driver.Tx, error) {
 		return nil, err
 	}
 
-	return newMonitoredTx(tx, mc.timeout), nil
+	return newMonitoredTx(context.Background(), tx, mc), nil
 }
 
 func (mc *monitoredConn) BeginTx(ctx context.Context, opts driver.TxOptions) (driver.Tx, error) {
@@ -135,7 +230,7 @@ func (mc *monitoredConn) BeginTx(ctx context.Context, opts driver.TxOptions) (dr
 			return nil, err
 		}
 
-		return newMonitoredTx(tx, mc.timeout), nil
+		return newMonitoredTx(ctx, tx, mc), nil
 	}
 
 	// Check the transaction level. If the transaction level is non-default
@@ -155,10 +250,15 @@ func (mc *monitoredConn) BeginTx(ctx context.Context, opts driver.TxOptions) (dr
 		return nil, err
 	}
 
-	return newMonitoredTx(tx, mc.timeout), nil
+	return newMonitoredTx(ctx, tx, mc), nil
 }
 
 func (mc *monitoredConn) ResetSession(ctx context.Context) (err error) {
+	if mc.invalid.Load() {
+		return driver.ErrBadConn
+	}
+	mc.checkedOut()
+
 	sessionResetter, ok := mc.Conn.(driver.SessionResetter)
 	if !ok {
 		// Driver does not implement, there is nothing to do.
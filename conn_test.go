@@ -0,0 +1,86 @@
+//go:build !sqleak_noop
+
+package sqleak
+
+import (
+	"context"
+	"database/sql/driver"
+	"strings"
+	"testing"
+	"time"
+)
+
+// TestLegacyAndContextPaths checks that Rows are monitored identically whichever API variant is used,
+// and whichever variant the driver implements.
+func TestLegacyAndContextPaths(t *testing.T) {
+	ctx := context.Background()
+	paths := map[string]func(*monitoredConn) (driver.Rows, error){
+		"Conn.Query": func(mc *monitoredConn) (driver.Rows, error) {
+			return mc.Query("SELECT 1", []driver.Value{int64(1)})
+		},
+		"Conn.QueryContext": func(mc *monitoredConn) (driver.Rows, error) {
+			return mc.QueryContext(ctx, "SELECT 1", []driver.NamedValue{{Ordinal: 1, Value: int64(1)}})
+		},
+		"Stmt.Query": func(mc *monitoredConn) (driver.Rows, error) {
+			stmt, _ := mc.Prepare("SELECT 1")
+			defer stmt.Close()
+			return stmt.Query([]driver.Value{int64(1)}) //nolint:staticcheck
+		},
+		"Stmt.QueryContext": func(mc *monitoredConn) (driver.Rows, error) {
+			stmt, _ := mc.PrepareContext(ctx, "SELECT 1")
+			defer stmt.Close()
+			return stmt.(driver.StmtQueryContext).QueryContext(ctx, []driver.NamedValue{{Ordinal: 1, Value: int64(1)}})
+		},
+	}
+
+	for _, conn := range []struct {
+		name string
+		conn driver.Conn
+	}{
+		{"context", fakeConn{}},
+		{"legacy", legacyConn{}},
+	} {
+		for name, query := range paths {
+			t.Run(conn.name+"/"+name, func(t *testing.T) {
+				mc, fc, logOutput := newTestConn(t, WithTimeout(time.Second))
+				mc = newMonitoredConn(conn.conn, mc.detector)
+
+				rows, err := query(mc)
+				if err != nil {
+					t.Fatalf("query failed: %v", err)
+				}
+				if _, ok := rows.(*monitoredRows); !ok {
+					t.Fatalf("got %T, want *monitoredRows", rows)
+				}
+
+				fc.Advance(2 * time.Second)
+				_ = rows.Close()
+
+				if got := strings.Count(logOutput.String(), "likely resource leak detected: Rows not closed"); got != 1 {
+					t.Errorf("got %d Rows leak warnings, want 1:\n%s", got, logOutput.String())
+				}
+			})
+		}
+	}
+}
+
+func TestLegacyExecFallback(t *testing.T) {
+	mc, _, _ := newTestConn(t)
+
+	if _, err := mc.Exec("DELETE FROM t", nil); err != driver.ErrSkip {
+		t.Errorf("Exec without driver support = %v, want driver.ErrSkip", err)
+	}
+
+	mc = newMonitoredConn(legacyConn{}, mc.detector)
+	ctx, cancel := context.WithCancel(context.Background())
+	if _, err := mc.ExecContext(ctx, "DELETE FROM t", nil); err != nil {
+		t.Errorf("ExecContext via legacy Execer failed: %v", err)
+	}
+	cancel()
+	if _, err := mc.ExecContext(ctx, "DELETE FROM t", nil); err != context.Canceled {
+		t.Errorf("ExecContext with canceled context = %v, want context.Canceled", err)
+	}
+	if _, err := mc.ExecContext(context.Background(), "DELETE FROM t", []driver.NamedValue{{Name: "id", Value: 1}}); err == nil {
+		t.Error("expected error for named parameters on a legacy Execer")
+	}
+}
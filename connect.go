@@ -0,0 +1,63 @@
+package sqleak
+
+import (
+	"bytes"
+	"log"
+	"sync/atomic"
+	"time"
+)
+
+// slowConnectWarningInterval rate limits the warnings of WithSlowConnectThreshold.
+const slowConnectWarningInterval = time.Minute
+
+// WithSlowConnectThreshold warns when opening a new connection takes longer than threshold, listing the resources
+// open at that time. Slow connects are how pool starvation shows first: database/sql only opens connections when
+// none is idle, and a server at its connection limit, e.g. due to connections pinned by leaked Rows or transactions,
+// makes new ones wait. The warning is logged at most once per minute, Stats().SlowConnects counts every slow connect.
+func WithSlowConnectThreshold(threshold time.Duration) Option {
+	return func(ld *monitoredDriver) {
+		ld.slowConnect = &slowConnect{
+			threshold: threshold,
+			limiter:   dumpLimiter{interval: slowConnectWarningInterval},
+		}
+	}
+}
+
+type slowConnect struct {
+	threshold time.Duration
+	limiter   dumpLimiter
+	count     atomic.Int64
+}
+
+// connectStarted returns the start of opening a connection, see connectDone.
+func (d *Detector) connectStarted() time.Time {
+	if d.slowConnect == nil {
+		return time.Time{}
+	}
+
+	return d.clock.Now()
+}
+
+// connectDone warns if opening a connection that started at start was slow, with WithSlowConnectThreshold.
+func (d *Detector) connectDone(start time.Time) {
+	s := d.slowConnect
+	if s == nil {
+		return
+	}
+
+	now := d.clock.Now()
+	took := now.Sub(start)
+	if took <= s.threshold {
+		return
+	}
+
+	s.count.Add(1)
+	if !s.limiter.allow(now) {
+		return
+	}
+
+	var table bytes.Buffer
+	writeOpenResources(&table, []*Detector{d})
+	log.Printf("%sslow connect: opening a connection took %s, more than %s, the pool may be starved by open resources:\n%s",
+		d.logPrefix(), took.Round(time.Millisecond), s.threshold, table.String())
+}
@@ -0,0 +1,64 @@
+//go:build !sqleak_noop
+
+package sqleak
+
+import (
+	"bytes"
+	"context"
+	"database/sql/driver"
+	"log"
+	"os"
+	"strings"
+	"testing"
+	"time"
+)
+
+// slowDriver takes connectTime on the fake clock to open a connection.
+type slowDriver struct {
+	fakeDriver
+	clock       *fakeClock
+	connectTime time.Duration
+}
+
+func (d *slowDriver) Open(name string) (driver.Conn, error) {
+	d.clock.Advance(d.connectTime)
+	return d.fakeDriver.Open(name)
+}
+
+func TestSlowConnectThreshold(t *testing.T) {
+	var logOutput bytes.Buffer
+	log.SetOutput(&logOutput)
+	t.Cleanup(func() { log.SetOutput(os.Stderr) })
+
+	fc := newFakeClock()
+	sd := &slowDriver{clock: fc}
+	d := WrapDriver(sd, withClock(fc), WithTimeout(time.Hour), WithSlowConnectThreshold(time.Second)).(*monitoredDriver)
+	t.Cleanup(d.stop)
+
+	conn, err := d.Open("")
+	if err != nil {
+		t.Fatal(err)
+	}
+	rows, _ := conn.(*monitoredConn).QueryContext(context.Background(), "SELECT 1", nil)
+	defer rows.Close()
+
+	sd.connectTime = 2 * time.Second
+	for i := 0; i < 2; i++ {
+		if _, err := d.Open(""); err != nil {
+			t.Fatal(err)
+		}
+	}
+	sd.connectTime = time.Second / 2
+	_, _ = d.Open("")
+
+	out := logOutput.String()
+	if want := "slow connect: opening a connection took 2s, more than 1s, the pool may be starved by open resources:\nsqleak: 1 open resources:"; !strings.Contains(out, want) {
+		t.Errorf("expected %q in log, got:\n%s", want, out)
+	}
+	if n := strings.Count(out, "slow connect"); n != 1 {
+		t.Errorf("expected a single warning per minute, got %d:\n%s", n, out)
+	}
+	if n := d.Stats().SlowConnects; n != 2 {
+		t.Errorf("got %d slow connects, want 2", n)
+	}
+}
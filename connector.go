@@ -3,6 +3,7 @@ package sqleak
 import (
 	"context"
 	"database/sql/driver"
+	"errors"
 	"io"
 )
 
@@ -11,23 +12,35 @@ var _ io.Closer = (*monitoredConnector)(nil)
 
 type monitoredConnector struct {
 	driver.Connector
-	driver *monitoredDriver
+	driver   *monitoredDriver
+	database *database
 }
 
-func newMonitoredConnector(connector driver.Connector, driver *monitoredDriver) *monitoredConnector {
+func newMonitoredConnector(connector driver.Connector, driver *monitoredDriver, database *database) *monitoredConnector {
 	return &monitoredConnector{
 		Connector: connector,
 		driver:    driver,
+		database:  database,
 	}
 }
 
 func (c *monitoredConnector) Connect(ctx context.Context) (driver.Conn, error) {
+	if noop {
+		return c.Connector.Connect(ctx)
+	}
+
+	start := c.driver.connectStarted()
 	conn, err := c.Connector.Connect(ctx)
+	c.driver.connectDone(start)
 	if err != nil {
 		return nil, err
 	}
 
-	return newMonitoredConn(conn, c.driver.timeout), nil
+	mc := newMonitoredConn(conn, c.driver.Detector)
+	mc.checkedOut()
+	mc.database = c.database
+
+	return mc, nil
 }
 
 func (c *monitoredConnector) Driver() driver.Driver {
@@ -35,10 +48,13 @@ func (c *monitoredConnector) Driver() driver.Driver {
 }
 
 func (c *monitoredConnector) Close() error {
+	c.driver.stop()
+	leakErr := c.driver.closeError()
+
 	// database/sql uses a type assertion to check if connectors implement io.Closer.
 	// The type assertion does not pass through to monitoredConnector.Connector, so we explicitly implement it here.
 	if closer, ok := c.Connector.(interface{ Close() error }); ok {
-		return closer.Close()
+		return errors.Join(leakErr, closer.Close())
 	}
-	return nil
+	return leakErr
 }
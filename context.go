@@ -0,0 +1,65 @@
+package sqleak
+
+import (
+	"context"
+	"maps"
+	"time"
+)
+
+type (
+	timeoutKey struct{}
+	ignoreKey  struct{}
+	labelsKey  struct{}
+)
+
+// ContextWithTimeout returns a copy of ctx overriding the leak timeout of the Rows, Stmt or Tx opened with it, e.g.
+// by DB.QueryContext or DB.BeginTx, for queries legitimately holding Rows for longer than the timeout of WithTimeout,
+// like nightly reports. It also takes precedence over WithAdaptiveTimeout. Rows from a Stmt or Tx use the context
+// they are queried with, not the one of the Stmt or Tx.
+func ContextWithTimeout(ctx context.Context, timeout time.Duration) context.Context {
+	return context.WithValue(ctx, timeoutKey{}, timeout)
+}
+
+// contextTimeout returns the leak timeout set by ContextWithTimeout, if any.
+func contextTimeout(ctx context.Context) (time.Duration, bool) {
+	timeout, ok := ctx.Value(timeoutKey{}).(time.Duration)
+	return timeout, ok
+}
+
+// Ignore returns a copy of ctx excluding the Rows, Stmt or Tx opened with it from monitoring entirely, for call paths
+// known to hold resources for long like migrations and bulk exports. They are never reported, are not counted in Stats
+// nor passed to hooks, and neither WithAutoClose nor WithAutoRollback end them. Rows from a Stmt or Tx are ignored
+// only if queried with such a context.
+func Ignore(ctx context.Context) context.Context {
+	return context.WithValue(ctx, ignoreKey{}, true)
+}
+
+// ignored reports whether ctx is from Ignore.
+func ignored(ctx context.Context) bool {
+	return ctx.Value(ignoreKey{}) != nil
+}
+
+// Annotate returns a copy of ctx carrying the given labels, alternating keys and values as in pprof.Labels, in
+// addition to the labels ctx carries already. The labels of the context a resource is opened with are added to its
+// leak reports and snapshots as LeakEvent.Attrs and passed to hooks as Resource.Attrs, e.g. for promsqleak.WithLabels,
+// without tying the application to a tracing framework. Annotate panics on an odd number of arguments.
+func Annotate(ctx context.Context, keyValues ...string) context.Context {
+	if len(keyValues)%2 != 0 {
+		panic("sqleak: odd number of arguments to Annotate")
+	}
+
+	parent := contextLabels(ctx)
+	labels := make(map[string]string, len(parent)+len(keyValues)/2)
+	maps.Copy(labels, parent)
+	for i := 0; i < len(keyValues); i += 2 {
+		labels[keyValues[i]] = keyValues[i+1]
+	}
+
+	return context.WithValue(ctx, labelsKey{}, labels)
+}
+
+// contextLabels returns the labels added to ctx by Annotate, nil without.
+func contextLabels(ctx context.Context) map[string]string {
+	labels, _ := ctx.Value(labelsKey{}).(map[string]string)
+	return labels
+}
@@ -0,0 +1,113 @@
+//go:build !sqleak_noop
+
+package sqleak
+
+import (
+	"context"
+	"database/sql/driver"
+	"maps"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestContextWithTimeout(t *testing.T) {
+	var events []LeakEvent
+	mc, fc, _ := newTestConn(t,
+		WithTimeout(time.Second),
+		WithOnLeak(func(ev LeakEvent) { events = append(events, ev) }),
+	)
+	ctx := ContextWithTimeout(context.Background(), 10*time.Minute)
+
+	report, _ := mc.QueryContext(ctx, "SELECT * FROM nightly_report", nil)
+	defer report.Close()
+	tx, _ := mc.BeginTx(ctx, driver.TxOptions{})
+	defer tx.Rollback()
+	lookup, _ := mc.QueryContext(context.Background(), "SELECT 1", nil)
+	defer lookup.Close()
+
+	fc.Advance(5 * time.Minute)
+	if len(events) != 1 || events[0].Query != "SELECT 1" {
+		t.Fatalf("expected only the lookup to leak, got %+v", events)
+	}
+
+	fc.Advance(5 * time.Minute)
+	if len(events) != 3 || events[1].Timeout != 10*time.Minute || events[2].Kind != KindTx {
+		t.Errorf("expected the report and the Tx to leak after 10m, got %+v", events[1:])
+	}
+}
+
+func TestIgnore(t *testing.T) {
+	var events []LeakEvent
+	mc, fc, _ := newTestConn(t,
+		WithTimeout(time.Second),
+		WithAutoRollback(2*time.Second),
+		WithOnLeak(func(ev LeakEvent) { events = append(events, ev) }),
+	)
+	ctx := Ignore(context.Background())
+
+	export, _ := mc.QueryContext(ctx, "SELECT * FROM orders", nil)
+	defer export.Close()
+	migration, _ := mc.BeginTx(ctx, driver.TxOptions{})
+	fc.Advance(time.Minute)
+
+	if len(events) != 0 {
+		t.Errorf("expected no events, got %+v", events)
+	}
+	if stats := mc.detector.Stats(); stats.Rows.Open != 0 || stats.Tx.Open != 0 {
+		t.Errorf("expected ignored resources not to be counted, got %+v", stats)
+	}
+	if err := migration.Commit(); err != nil {
+		t.Errorf("expected the ignored Tx not to be rolled back, got %v", err)
+	}
+}
+
+func TestAnnotate(t *testing.T) {
+	var opened []Resource
+	var events []LeakEvent
+	mc, fc, _ := newTestConn(t,
+		WithTimeout(time.Second),
+		WithContextAttrs(func(context.Context) map[string]string { return map[string]string{"route": "/extracted"} }),
+		WithHooks(Hooks{OnOpen: func(r Resource) { opened = append(opened, r) }}),
+		WithOnLeak(func(ev LeakEvent) { events = append(events, ev) }),
+	)
+
+	parent := Annotate(context.Background(), "tenant", "acme")
+	ctx := Annotate(parent, "route", "/orders")
+	rows, _ := mc.QueryContext(ctx, "SELECT 1", nil)
+	defer rows.Close()
+	fc.Advance(time.Second)
+
+	want := map[string]string{"tenant": "acme", "route": "/orders"}
+	if len(opened) != 1 || !maps.Equal(opened[0].Attrs, want) {
+		t.Errorf("expected the labels in the hook, got %+v", opened)
+	}
+	if len(events) != 1 || !maps.Equal(events[0].Attrs, want) {
+		t.Errorf("expected the labels in the event, got %+v", events)
+	}
+	if labels := contextLabels(parent); len(labels) != 1 {
+		t.Errorf("expected Annotate not to modify the parent's labels, got %v", labels)
+	}
+}
+
+func TestShouldMonitor(t *testing.T) {
+	var events []LeakEvent
+	mc, fc, _ := newTestConn(t,
+		WithTimeout(time.Second),
+		WithShouldMonitor(func(query string) bool { return !strings.HasPrefix(query, "LISTEN") }),
+		WithOnLeak(func(ev LeakEvent) { events = append(events, ev) }),
+	)
+	ctx := context.Background()
+
+	listen, _ := mc.QueryContext(ctx, "LISTEN jobs", nil)
+	defer listen.Close()
+	rows, _ := mc.QueryContext(ctx, "SELECT 1", nil)
+	defer rows.Close()
+	tx, _ := mc.BeginTx(ctx, driver.TxOptions{})
+	defer tx.Rollback()
+	fc.Advance(time.Second)
+
+	if len(events) != 2 || events[0].Query != "SELECT 1" || events[1].Kind != KindTx {
+		t.Errorf("expected the LISTEN query not to be monitored, got %+v", events)
+	}
+}
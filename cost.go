@@ -0,0 +1,70 @@
+package sqleak
+
+// CostClass estimates what a leaked resource costs the database server, see LeakEvent.Cost.
+type CostClass string
+
+const (
+	// CostUnknown is used for resources and drivers sqleak has no estimate for.
+	CostUnknown CostClass = ""
+	// CostClientBuffered marks Rows whose results were read completely: nothing is pending on the server,
+	// only the pool connection stays pinned until the Rows are closed.
+	CostClientBuffered CostClass = "client_buffered"
+	// CostServerCursor marks Rows with unread results: the server still holds the cursor, its snapshot or locks.
+	CostServerCursor CostClass = "server_cursor"
+)
+
+// Severity ranks leak events by their estimated impact.
+type Severity string
+
+const (
+	SeverityInfo     Severity = "info"
+	SeverityWarning  Severity = "warning"
+	SeverityError    Severity = "error"
+	SeverityCritical Severity = "critical"
+)
+
+// classify estimates the server-side cost of a leaked resource from its kind, the database behind the driver,
+// whether its results were read completely and whether it is part of a transaction.
+func classify(kind Kind, family dbFamily, drained, inTx bool) (CostClass, Severity) {
+	switch kind {
+	case KindTx:
+		return CostUnknown, SeverityCritical
+	case KindStmt:
+		return CostUnknown, SeverityWarning
+	}
+
+	if drained {
+		return CostClientBuffered, SeverityInfo
+	}
+
+	switch family {
+	case familySQLite:
+		// The open read statement holds a shared lock on the database file, blocking writers.
+		return CostServerCursor, SeverityCritical
+	case familyPostgres:
+		// The backend keeps the portal and, inside a transaction, the snapshot that blocks VACUUM.
+		if inTx {
+			return CostServerCursor, SeverityCritical
+		}
+		return CostServerCursor, SeverityWarning
+	case familyMySQL:
+		// The unread result set stays on the connection; inside a transaction InnoDB keeps its locks and undo history.
+		if inTx {
+			return CostServerCursor, SeverityCritical
+		}
+		return CostServerCursor, SeverityWarning
+	default:
+		return CostUnknown, SeverityWarning
+	}
+}
+
+func (c CostClass) describe() string {
+	switch c {
+	case CostClientBuffered:
+		return "fully read client-side, harmless to the server"
+	case CostServerCursor:
+		return "server-side cursor still open"
+	default:
+		return ""
+	}
+}
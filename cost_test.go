@@ -0,0 +1,81 @@
+//go:build !sqleak_noop
+
+package sqleak
+
+import (
+	"context"
+	"database/sql/driver"
+	"encoding/json"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestCostClassification(t *testing.T) {
+	var events []LeakEvent
+	mc, fc, logOutput := newTestConn(t,
+		WithTimeout(time.Second),
+		WithOnLeak(func(ev LeakEvent) { events = append(events, ev) }),
+	)
+	mc.detector.driverName = "mysql"
+	ctx := context.Background()
+
+	drained, _ := mc.QueryContext(ctx, "SELECT 1", nil)
+	defer drained.Close()
+	dest := make([]driver.Value, 1)
+	for drained.Next(dest) == nil {
+	}
+
+	unread, _ := mc.QueryContext(ctx, "SELECT 2", nil)
+	defer unread.Close()
+
+	tx, _ := mc.BeginTx(ctx, driver.TxOptions{})
+	defer tx.Rollback()
+	inTx, _ := mc.QueryContext(ctx, "SELECT 3", nil)
+	defer inTx.Close()
+
+	fc.Advance(time.Second)
+
+	want := []struct {
+		kind     Kind
+		cost     CostClass
+		severity Severity
+		fetched  int64
+	}{
+		{KindRows, CostClientBuffered, SeverityInfo, 1},
+		{KindRows, CostServerCursor, SeverityWarning, 0},
+		{KindTx, CostUnknown, SeverityCritical, 0},
+		{KindRows, CostServerCursor, SeverityCritical, 0},
+	}
+	if len(events) != len(want) {
+		t.Fatalf("got %d events, want %d", len(events), len(want))
+	}
+	for i, w := range want {
+		ev := events[i]
+		if ev.Kind != w.kind || ev.Cost != w.cost || ev.Severity != w.severity || ev.RowsFetched != w.fetched {
+			t.Errorf("event %d: got %s/%q/%s/%d, want %s/%q/%s/%d",
+				i, ev.Kind, ev.Cost, ev.Severity, ev.RowsFetched, w.kind, w.cost, w.severity, w.fetched)
+		}
+	}
+
+	for _, want := range []string{
+		"exhausted Rows not closed: Rows exhausted but not explicitly closed within 1s after opening (leak #1, resource #1, query \"SELECT 1\", fully read client-side, harmless to the server, 1 row fetched, opened ",
+		"likely resource leak detected: Rows not closed within 1s after opening (leak #2, resource #2, query \"SELECT 2\", server-side cursor still open, 0 rows fetched, opened ",
+	} {
+		if !strings.Contains(logOutput.String(), want) {
+			t.Errorf("expected %q in log, got:\n%s", want, logOutput.String())
+		}
+	}
+
+	b, err := json.Marshal(events[0])
+	if err != nil {
+		t.Fatal(err)
+	}
+	decoded, err := DecodeLeakEvent(b)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if decoded.Cost != CostClientBuffered || decoded.Severity != SeverityInfo || decoded.RowsFetched != 1 {
+		t.Errorf("classification lost in JSON round trip: %s", b)
+	}
+}
@@ -0,0 +1,54 @@
+//go:build !sqleak_noop
+
+package sqleak
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestParseDSN(t *testing.T) {
+	for _, c := range []struct {
+		dsn, name, redacted string
+	}{
+		{"postgres://app:s3cret@db:5432/orders?sslmode=disable", "orders", "postgres://app:xxxxx@db:5432/orders?sslmode=disable"},
+		{"sqlserver://sa@db?database=orders&password=s3cret", "orders", "sqlserver://sa@db?database=orders&password=xxxxx"},
+		{"host=db dbname=orders user=app password='s3 cret'", "orders", "host=db dbname=orders user=app password=xxxxx"},
+		{"Server=db;Database=orders;User Id=app;Password=s3cret;", "orders", "Server=db;Database=orders;User Id=app;Password=xxxxx"},
+		{"app:s3c@r/et@tcp(db:3306)/orders?parseTime=true", "orders", "app:xxxxx@tcp(db:3306)/orders?parseTime=true"},
+		{"app@unix(/tmp/mysql.sock)/orders", "orders", "app@unix(/tmp/mysql.sock)/orders"},
+		{"file:orders.db?_auth_user=app&_auth_pass=s3cret", "orders.db", "file:orders.db?_auth_user=app&_auth_pass=xxxxx"},
+		{":memory:", ":memory:", ":memory:"},
+		{"postgres://app:s3cret@db:port/orders", "", "postgres://xxxxx"},
+		{"host=db password=s3;cret user=app", "unknown", ""},
+		{"host=db password=a;s3 user=x", "unknown", ""},
+		{"host=db password=s3 cret user=app", "unknown", ""},
+	} {
+		db := parseDSN(c.dsn)
+		if db == nil || db.name != c.name || db.dsn != c.redacted {
+			t.Errorf("parseDSN(%q) = %+v, want name %q and DSN %q", c.dsn, db, c.name, c.redacted)
+		}
+		if db != nil && strings.Contains(db.dsn, "s3") {
+			t.Errorf("parseDSN(%q) leaks the password: %q", c.dsn, db.dsn)
+		}
+	}
+
+	if db := parseDSN(""); db != nil {
+		t.Errorf("expected no database for an empty DSN, got %+v", db)
+	}
+}
+
+func TestDatabaseInReport(t *testing.T) {
+	mc, fc, logOutput := newTestConn(t, WithTimeout(time.Second))
+	mc.database = parseDSN("postgres://app:s3cret@db/orders")
+
+	rows, _ := mc.QueryContext(context.Background(), "SELECT 1", nil)
+	defer rows.Close()
+	fc.Advance(time.Second)
+
+	if want := `, db orders (postgres://app:xxxxx@db/orders))`; !strings.Contains(logOutput.String(), want) {
+		t.Errorf("expected %q in log, got:\n%s", want, logOutput.String())
+	}
+}
@@ -0,0 +1,68 @@
+package sqleak
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"strings"
+)
+
+// DB is a *sql.DB with leak detection instrumentation, adding methods to inspect its leaks at runtime without
+// looking up its Detector or parsing logs.
+type DB struct {
+	*sql.DB
+	detector *Detector
+}
+
+// OpenHandle is like Open, returning the DB as a *DB.
+func OpenHandle(driverName, dataSourceName string, opts ...Option) (*DB, error) {
+	db, err := Open(driverName, dataSourceName, opts...)
+	if err != nil {
+		return nil, err
+	}
+
+	return HandleOf(db), nil
+}
+
+// HandleOf returns db as a *DB, or nil if db was not opened with Open, OpenDB or a driver from WrapDriver.
+func HandleOf(db *sql.DB) *DB {
+	d := DetectorOf(db)
+	if d == nil {
+		return nil
+	}
+
+	return &DB{DB: db, detector: d}
+}
+
+// Detector returns the Detector instrumenting the DB.
+func (db *DB) Detector() *Detector {
+	return db.detector
+}
+
+// LeakStats returns the Stats of the Detector instrumenting the DB.
+func (db *DB) LeakStats() Stats {
+	return db.detector.Stats()
+}
+
+// Snapshot lists the resources currently open through the DB, oldest first, see Detector.Snapshot.
+func (db *DB) Snapshot() []OpenResource {
+	return db.detector.Snapshot()
+}
+
+// VerifyNoLeaks returns an error describing every resource still open through the DB, or nil if there are none.
+// It is meant to run once the DB should be idle, e.g. at the end of a test or before shutting down: resources that
+// are open but within their timeout count as well.
+func (db *DB) VerifyNoLeaks() error {
+	events := db.detector.Outstanding()
+	if len(events) == 0 {
+		return nil
+	}
+
+	var b strings.Builder
+	fmt.Fprintf(&b, "sqleak: %s", summarizeOutstanding(events))
+	for _, ev := range events {
+		b.WriteString("\n" + ev.Text())
+	}
+
+	return errors.New(b.String())
+}
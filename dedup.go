@@ -0,0 +1,73 @@
+package sqleak
+
+import (
+	"fmt"
+	"sync"
+	"time"
+)
+
+// WithDeduplication logs only the first leak of every call site in full, identified by its stack fingerprint
+// (see Capture.Fingerprint). Further leaks from the same call site are not logged, except for a single line
+// "leak at <site> seen N times" at most once per summaryInterval; a summaryInterval of 0 disables the summaries.
+// Callbacks and subscribers still receive every event, with LeakEvent.SiteCount set.
+func WithDeduplication(summaryInterval time.Duration) Option {
+	return func(ld *monitoredDriver) {
+		ld.dedup = &deduplicator{interval: summaryInterval}
+	}
+}
+
+type deduplicator struct {
+	interval time.Duration
+	sites    sync.Map // site key to *dedupSite
+}
+
+type dedupSite struct {
+	mu         sync.Mutex
+	count      int64
+	lastReport time.Time
+}
+
+// observe counts a leak at site and returns the number of leaks seen there, and whether to log this one.
+func (dd *deduplicator) observe(site string, now time.Time) (int64, bool) {
+	v, _ := dd.sites.LoadOrStore(site, &dedupSite{})
+	s := v.(*dedupSite)
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.count++
+	if s.count == 1 || (dd.interval > 0 && now.Sub(s.lastReport) >= dd.interval) {
+		s.lastReport = now
+		return s.count, true
+	}
+
+	return s.count, false
+}
+
+// dedupKey identifies the call site of a monitor, by kind and query shape if no stack was captured.
+func (m *monitor) dedupKey() string {
+	_, frames := m.capturedStack()
+	if len(frames) == 0 {
+		return fmt.Sprintf("%s %s", m.kind, m.detector.queryFingerprint(m.query))
+	}
+
+	return fingerprint(frames)
+}
+
+// summary is the single line logged instead of the full report for repeated leaks of a call site.
+func (ev LeakEvent) summary() string {
+	return fmt.Sprintf("%s leak at %s seen %d times (leak #%d)", ev.Kind, ev.site(), ev.SiteCount, ev.LeakID)
+}
+
+// site describes where the resource was opened: its innermost frame, else its query.
+func (ev LeakEvent) site() string {
+	if len(ev.Frames) > 0 {
+		f := ev.Frames[0]
+		return fmt.Sprintf("%s (%s:%d)", f.Function, f.File, f.Line)
+	}
+	if ev.Query != "" {
+		return fmt.Sprintf("query %q", ev.Query)
+	}
+
+	return "unknown call site"
+}
@@ -0,0 +1,58 @@
+//go:build !sqleak_noop
+
+package sqleak
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestDeduplication(t *testing.T) {
+	var events []LeakEvent
+	mc, fc, logOutput := newTestConn(t,
+		WithTimeout(time.Second),
+		WithFullStacks(), // this package's own frames would be trimmed, making all call sites look the same
+		WithDeduplication(10*time.Second),
+		WithOnLeak(func(ev LeakEvent) { events = append(events, ev) }),
+	)
+	ctx := context.Background()
+
+	leak := func(n int) {
+		for i := 0; i < n; i++ {
+			rows, _ := mc.QueryContext(ctx, "SELECT 1", nil)
+			t.Cleanup(func() { _ = rows.Close() })
+		}
+	}
+
+	// Both rounds leak from the same call site, the first round also from another one.
+	for round, n := range []int{5, 2} {
+		leak(n)
+
+		if round == 0 {
+			other, _ := mc.QueryContext(ctx, "SELECT 1", nil)
+			defer other.Close()
+			fc.Advance(time.Second)
+
+			if got := strings.Count(logOutput.String(), "[running]:"); got != 2 {
+				t.Errorf("got %d full reports, want one per call site:\n%s", got, logOutput.String())
+			}
+			if strings.Contains(logOutput.String(), "seen") {
+				t.Errorf("did not expect a summary within the summary interval:\n%s", logOutput.String())
+			}
+			if len(events) != 6 || events[4].SiteCount != 5 || events[5].SiteCount != 1 {
+				t.Errorf("expected every event to be delivered with its site count, got %d events", len(events))
+			}
+
+			logOutput.Reset()
+			fc.Advance(10 * time.Second)
+		}
+	}
+	fc.Advance(time.Second)
+
+	lines := strings.Split(strings.TrimSpace(logOutput.String()), "\n")
+	if len(lines) != 1 || !strings.Contains(lines[0], "likely resource leak detected: Rows leak at ") || !strings.Contains(lines[0], " seen 6 times (leak #7)") {
+		t.Errorf("expected a single summary line, got:\n%s", logOutput.String())
+	}
+}
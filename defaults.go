@@ -0,0 +1,82 @@
+package sqleak
+
+import (
+	"log"
+	"os"
+	"strconv"
+	"sync"
+	"time"
+)
+
+// Environment variables overriding the configuration of every Detector created afterwards, including options passed
+// in code, so leak detection can be tuned or turned off in a deployed binary.
+const (
+	// EnvTimeout sets the leak timeout as a Go duration, e.g. "2m", see WithTimeout.
+	EnvTimeout = "SQLEAK_TIMEOUT"
+	// EnvDisable turns monitoring off if true, e.g. "1": resources are passed through unmonitored, as with Ignore.
+	EnvDisable = "SQLEAK_DISABLE"
+	// EnvSampleRate sets the fraction of resources monitored, e.g. "0.01", see WithSampleRate.
+	EnvSampleRate = "SQLEAK_SAMPLE_RATE"
+)
+
+var defaults struct {
+	mu   sync.Mutex
+	opts []Option
+}
+
+// SetDefaults sets options applied to every Detector created afterwards by Open, OpenDB, WrapConnector, WrapDriver
+// and Register, before the options passed to them, replacing the defaults set before. SetDefaults() clears them.
+func SetDefaults(opts ...Option) {
+	defaults.mu.Lock()
+	defer defaults.mu.Unlock()
+
+	defaults.opts = append([]Option(nil), opts...)
+}
+
+// configure applies the defaults, opts and environment overrides, in that order, and starts the Detector.
+// With the sqleak_noop build tag only opts are applied, for WithDriverWrapper, and nothing is started.
+func (d *monitoredDriver) configure(opts []Option) {
+	if noop {
+		for _, opt := range opts {
+			opt(d)
+		}
+		return
+	}
+
+	defaults.mu.Lock()
+	for _, opt := range defaults.opts {
+		opt(d)
+	}
+	defaults.mu.Unlock()
+
+	for _, opt := range opts {
+		opt(d)
+	}
+	d.applyEnv()
+	d.start()
+}
+
+// applyEnv applies the environment overrides, ignoring invalid values with a warning.
+func (d *monitoredDriver) applyEnv() {
+	if v, ok := os.LookupEnv(EnvTimeout); ok {
+		if timeout, err := time.ParseDuration(v); err == nil && timeout > 0 {
+			d.timeout = timeout
+		} else {
+			log.Printf("%signoring invalid %s=%q", d.logPrefix(), EnvTimeout, v)
+		}
+	}
+	if v, ok := os.LookupEnv(EnvDisable); ok {
+		if disabled, err := strconv.ParseBool(v); err == nil {
+			d.disabled = disabled
+		} else {
+			log.Printf("%signoring invalid %s=%q", d.logPrefix(), EnvDisable, v)
+		}
+	}
+	if v, ok := os.LookupEnv(EnvSampleRate); ok {
+		if rate, err := strconv.ParseFloat(v, 64); err == nil {
+			WithSampleRate(rate)(d)
+		} else {
+			log.Printf("%signoring invalid %s=%q", d.logPrefix(), EnvSampleRate, v)
+		}
+	}
+}
@@ -0,0 +1,66 @@
+//go:build !sqleak_noop
+
+package sqleak
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestSetDefaults(t *testing.T) {
+	SetDefaults(WithTimeout(time.Minute), WithName("defaults"))
+	t.Cleanup(func() { SetDefaults() })
+
+	mc, _, _ := newTestConn(t, WithName("orders"))
+	if c := mc.detector.Config(); c.Timeout != time.Minute || c.Name != "orders" {
+		t.Errorf("expected the default timeout and the name passed in code, got %v and %q", c.Timeout, c.Name)
+	}
+
+	SetDefaults()
+	mc, _, _ = newTestConn(t)
+	if c := mc.detector.Config(); c.Timeout != 30*time.Second || c.Name != "" {
+		t.Errorf("expected the cleared defaults to no longer apply, got %v and %q", c.Timeout, c.Name)
+	}
+}
+
+func TestEnvOverrides(t *testing.T) {
+	t.Setenv(EnvTimeout, "2m")
+	t.Setenv(EnvSampleRate, "0.5")
+
+	mc, _, _ := newTestConn(t, WithTimeout(time.Second), WithSampleRate(1))
+	if c := mc.detector.Config(); c.Timeout != 2*time.Minute || c.SampleRate != 0.5 || c.Disabled {
+		t.Errorf("expected the environment to override the options, got %+v", c)
+	}
+
+	t.Setenv(EnvTimeout, "soon")
+	mc, _, logOutput := newTestConn(t, WithTimeout(time.Second))
+	if c := mc.detector.Config(); c.Timeout != time.Second {
+		t.Errorf("expected an invalid timeout to be ignored, got %v", c.Timeout)
+	}
+	if !strings.Contains(logOutput.String(), `ignoring invalid SQLEAK_TIMEOUT="soon"`) {
+		t.Errorf("expected a warning about the invalid timeout, got:\n%s", logOutput.String())
+	}
+}
+
+func TestEnvDisable(t *testing.T) {
+	t.Setenv(EnvDisable, "true")
+
+	var events []LeakEvent
+	mc, fc, _ := newTestConn(t,
+		WithTimeout(time.Second),
+		WithOnLeak(func(ev LeakEvent) { events = append(events, ev) }),
+	)
+
+	rows, _ := mc.QueryContext(context.Background(), "SELECT 1", nil)
+	defer rows.Close()
+	fc.Advance(time.Minute)
+
+	if len(events) != 0 {
+		t.Errorf("expected no leaks with monitoring disabled, got %+v", events)
+	}
+	if s := mc.detector.Stats(); s.Rows.Open != 0 || !mc.detector.Config().Disabled {
+		t.Errorf("expected the Rows to be unmonitored, got %+v", s.Rows)
+	}
+}
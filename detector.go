@@ -0,0 +1,182 @@
+package sqleak
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// Detector holds the configuration and runtime state shared by all connections of a wrapped driver.
+// Use DetectorOf to access the Detector of a *sql.DB opened with Open, or DetectorFromDriver for WrapDriver.
+type Detector struct {
+	name           string
+	timeout        time.Duration
+	clock          clock
+	driverName     string
+	verbose        bool
+	jsonOutput     bool
+	fullStacks     bool
+	callerOnly     bool
+	withoutStacks  bool
+	columnMetadata bool
+	openerStack    bool
+	singleLineLog  bool
+	devOutput      bool
+	runtimeTrace   bool
+	pprofProfiles  bool
+	invalidateConn bool
+	strictClose    bool
+	failMode       FailMode
+	closeErrors    CloseErrorMode
+	poolFromOpen   bool
+	closeStacks    bool
+	fullDump       *dumpLimiter
+
+	repeatInterval  time.Duration
+	escalation      *escalation
+	stackBufferSize int
+	stackBuffers    sync.Pool
+
+	ownerResolver func(Frame) string
+	stackFilter   func(Frame) bool
+	onLeak        []func(LeakEvent)
+	onOpen        []func(Resource)
+	onClose       []func(Resource, time.Duration)
+	sinks         []Sink
+	anonymize     func(query string) string
+	redactArg     func(driver.NamedValue) string
+	normalize     func(query string) string
+	extractTrace  func(context.Context) Trace
+	extractAttrs  func(context.Context) map[string]string
+	sampleContext func(context.Context) bool
+	shouldMonitor func(query string) bool
+	onStrictClose func(*LeakError)
+	poolStats     func() sql.DBStats
+
+	sampler *siteSampler
+
+	overhead      overhead
+	subscribers   subscribers
+	droppedEvents atomic.Int64
+	leakIDs       atomic.Uint64
+	resourceIDs   [3]atomic.Uint64 // by kindIndex
+	kinds         [3]kindCounters  // by kindIndex
+
+	hold       *holdTracker
+	summary    *summaryLogger
+	budget     *leakBudget
+	health     HealthThresholds
+	breaker    *circuitBreaker
+	dedup      *deduplicator
+	serverless bool
+	disabled   bool
+
+	autoClose    time.Duration
+	autoRollback time.Duration
+	longTx       time.Duration
+	stallTimeout time.Duration
+	idleTimeout  [3]bool // by kindIndex
+	slowConnect  *slowConnect
+	checkout     *checkoutWarning
+	slowQuery    *slowQuery
+	reprepared   *repreparedWarning
+	unusedStmts  *unusedStmts
+	misuse       *misuseDetector
+	manualTx     *manualTxTracking
+	adaptive     *adaptiveTimeouts
+	suppress     *suppressions
+
+	sources    leakSources
+	open       sync.Map // *monitor of every open resource
+	queryLeaks sync.Map // queryLeakKey to *atomic.Int64, only with WithoutStacks
+}
+
+func newDetector(timeout time.Duration, driverName string) *Detector {
+	return &Detector{
+		timeout:         timeout,
+		clock:           realClock{},
+		driverName:      driverName,
+		stackBufferSize: defaultStackBufferSize,
+	}
+}
+
+// DetectorOf returns the Detector instrumenting db, or nil if db was not opened with Open or a driver from WrapDriver.
+func DetectorOf(db *sql.DB) *Detector {
+	return DetectorFromDriver(db.Driver())
+}
+
+// DetectorFromDriver returns the Detector of a driver returned by WrapDriver, or nil for any other driver.
+func DetectorFromDriver(d driver.Driver) *Detector {
+	if md, ok := d.(*monitoredDriver); ok {
+		return md.Detector
+	}
+
+	return nil
+}
+
+// nextResourceID assigns the next ID of a resource of the given kind.
+func (d *Detector) nextResourceID(kind Kind) uint64 {
+	return d.resourceIDs[kindIndex(kind)].Add(1)
+}
+
+func kindIndex(kind Kind) int {
+	switch kind {
+	case KindStmt:
+		return 1
+	case KindTx:
+		return 2
+	}
+
+	return 0
+}
+
+// logPrefix starts the log lines of a Detector set up WithName.
+func (d *Detector) logPrefix() string {
+	return namePrefix(d.name)
+}
+
+func namePrefix(name string) string {
+	if name == "" {
+		return ""
+	}
+
+	return "[" + name + "] "
+}
+
+// start launches background work once all options have been applied.
+func (d *Detector) start() {
+	d.stackBuffers.New = func() any {
+		buf := make([]byte, d.stackBufferSize)
+		return &buf
+	}
+	if d.hold != nil {
+		d.hold.overhead = &d.overhead
+		d.hold.logPrefix = d.logPrefix()
+		d.hold.start(d.clock)
+	}
+	if d.sampler != nil {
+		d.sampler.overhead = &d.overhead
+	}
+	if d.summary != nil {
+		d.summary.start(d)
+	}
+	register(d)
+}
+
+// stop ends background work, it is called when the sql.DB is closed.
+func (d *Detector) stop() {
+	if d.serverless {
+		d.CheckDeadlines()
+	}
+	if d.hold != nil {
+		d.hold.stop()
+	}
+	if d.summary != nil {
+		d.summary.stop()
+	}
+	d.subscribers.close()
+	unregister(d)
+}
@@ -0,0 +1,579 @@
+//go:build !sqleak_noop
+
+package sqleak
+
+import (
+	"bytes"
+	"context"
+	"database/sql/driver"
+	"fmt"
+	"log"
+	"os"
+	"reflect"
+	"regexp"
+	"strings"
+	"testing"
+	"time"
+	"unicode/utf8"
+)
+
+// newTestConn returns a monitored fake connection driven by a fake clock, with log output captured.
+// The log buffer is only safe to read on the test goroutine as long as timers fire via fakeClock.Advance.
+func newTestConn(t *testing.T, opts ...Option) (*monitoredConn, *fakeClock, *bytes.Buffer) {
+	t.Helper()
+
+	var logOutput bytes.Buffer
+	log.SetOutput(&logOutput)
+	t.Cleanup(func() { log.SetOutput(os.Stderr) })
+
+	fc := newFakeClock()
+	d := WrapDriver(fakeDriver{}, append([]Option{withClock(fc)}, opts...)...)
+	t.Cleanup(d.(*monitoredDriver).stop)
+
+	conn, err := d.Open("")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return conn.(*monitoredConn), fc, &logOutput
+}
+
+func TestConnHoldLimit(t *testing.T) {
+	mc, fc, logOutput := newTestConn(t,
+		WithTimeout(time.Hour),
+		WithConnHoldLimit(2, 10*time.Second),
+	)
+	ctx := context.Background()
+
+	// Interval 1: one Rows held for 4s, one Tx held for the whole interval, with Rows inside it not counted.
+	rows, _ := mc.QueryContext(ctx, "SELECT 1", nil)
+	fc.Advance(4 * time.Second)
+	_ = rows.Close()
+
+	tx, _ := mc.BeginTx(ctx, driver.TxOptions{})
+	txRows, _ := mc.QueryContext(ctx, "SELECT 1", nil)
+	fc.Advance(6 * time.Second)
+
+	if got, want := mc.detector.HoldStats().Held, 10*time.Second; got != want {
+		t.Errorf("held in first interval = %s, want %s", got, want)
+	}
+	if strings.Contains(logOutput.String(), "saturated") {
+		t.Errorf("did not expect saturation warning at 50%% utilization:\n%s", logOutput.String())
+	}
+
+	// Interval 2: the Tx carries over, Rows on a second connection saturate the pool.
+	mc2 := newMonitoredConn(fakeConn{}, mc.detector)
+	rows, _ = mc2.QueryContext(ctx, "SELECT 1", nil)
+	fc.Advance(9 * time.Second)
+	_ = rows.Close()
+
+	if got, want := mc.detector.HoldStats().Current, 18*time.Second; got != want {
+		t.Errorf("current hold = %s, want %s", got, want)
+	}
+
+	fc.Advance(time.Second)
+	_ = txRows.Close()
+	_ = tx.Commit()
+
+	stats := mc.detector.HoldStats()
+	if got, want := stats.Held, 19*time.Second; got != want {
+		t.Errorf("held in second interval = %s, want %s", got, want)
+	}
+	if got, want := stats.Utilization(), 0.95; got != want {
+		t.Errorf("utilization = %v, want %v", got, want)
+	}
+	if !strings.Contains(logOutput.String(), "connection pool likely saturated") {
+		t.Errorf("expected saturation warning, got:\n%s", logOutput.String())
+	}
+}
+
+func TestConnHoldLimitInvalid(t *testing.T) {
+	for _, limit := range [][2]int{{0, 10}, {2, 0}, {-1, -1}} {
+		mc, _, _ := newTestConn(t, WithConnHoldLimit(limit[0], time.Duration(limit[1])*time.Second))
+		if c := mc.detector.Config(); c.HoldMaxOpenConns != 0 || c.HoldInterval != 0 {
+			t.Errorf("expected WithConnHoldLimit(%d, %ds) to keep tracking off, got %+v", limit[0], limit[1], c)
+		}
+	}
+}
+
+func TestParseStack(t *testing.T) {
+	stack := `goroutine 6 [running]:
+github.com/saiko-tech/sqleak.newMonitor(0x5f5e100, {0x6b0ca8, 0x4})
+	/src/sqleak/monitor.go:47 +0x57
+github.com/org/app/store.(*Store).List(0xc0000100a8)
+	/src/app/store/store.go:133 +0x56
+created by testing.(*T).Run in goroutine 1
+	/usr/lib/go/src/testing/testing.go:1851 +0x413
+`
+
+	want := []Frame{
+		{Function: "github.com/saiko-tech/sqleak.newMonitor", File: "/src/sqleak/monitor.go", Line: 47},
+		{Function: "github.com/org/app/store.(*Store).List", File: "/src/app/store/store.go", Line: 133},
+		{Function: "testing.(*T).Run", File: "/usr/lib/go/src/testing/testing.go", Line: 1851},
+	}
+
+	got := parseStack(stack)
+	if len(got) != len(want) {
+		t.Fatalf("got %d frames, want %d: %+v", len(got), len(want), got)
+	}
+	for i := range want {
+		if got[i] != want[i] {
+			t.Errorf("frame %d = %+v, want %+v", i, got[i], want[i])
+		}
+	}
+}
+
+func TestTrimStack(t *testing.T) {
+	stack := `goroutine 6 [running]:
+github.com/saiko-tech/sqleak.newMonitor(0x5f5e100, {0x6b0ca8, 0x4})
+	/src/sqleak/monitor.go:47 +0x57
+database/sql.(*DB).query(0xc000100000)
+	/usr/lib/go/src/database/sql/sql.go:1754 +0x57
+github.com/org/app/store.(*Store).List(0xc0000100a8)
+	/src/app/store/store.go:133 +0x56
+database/sql.(*DB).QueryContext(0xc000100000)
+	/usr/lib/go/src/database/sql/sql.go:1731 +0x57
+created by testing.(*T).Run in goroutine 1
+	/usr/lib/go/src/testing/testing.go:1851 +0x413
+`
+
+	want := `goroutine 6 [running]:
+github.com/org/app/store.(*Store).List(0xc0000100a8)
+	/src/app/store/store.go:133 +0x56
+database/sql.(*DB).QueryContext(0xc000100000)
+	/usr/lib/go/src/database/sql/sql.go:1731 +0x57
+created by testing.(*T).Run in goroutine 1
+	/usr/lib/go/src/testing/testing.go:1851 +0x413
+`
+	if got := trimStack(stack); got != want {
+		t.Errorf("trimStack() =\n%s\nwant:\n%s", got, want)
+	}
+
+	frames := trimFrames(parseStack(stack))
+	if len(frames) != 3 || frames[0].Function != "github.com/org/app/store.(*Store).List" {
+		t.Errorf("unexpected trimmed frames: %+v", frames)
+	}
+
+	infraOnly := "goroutine 6 [running]:\nruntime.main()\n\t/usr/lib/go/src/runtime/proc.go:283 +0x28b\n"
+	if got := trimStack(infraOnly); got != infraOnly {
+		t.Errorf("stack of infrastructure frames only should be kept, got:\n%s", got)
+	}
+}
+
+func TestStackFilter(t *testing.T) {
+	stack := `goroutine 6 [running]:
+github.com/org/app/store.(*Store).List(0xc0000100a8)
+	/src/app/store/store.go:133 +0x56
+github.com/other/lib.Retry(0xc0000100a8)
+	/src/lib/retry.go:20 +0x56
+github.com/org/app/api.(*Server).Items(0xc0000100a8)
+	/src/app/api/items.go:42 +0x56
+created by net/http.(*Server).Serve in goroutine 1
+	/usr/lib/go/src/net/http/server.go:3454 +0x485
+`
+
+	md := &monitoredDriver{Detector: &Detector{}}
+	WithStackFilter("github.com/org/")(md)
+	keep := md.stackFilter
+
+	want := `goroutine 6 [running]:
+github.com/org/app/store.(*Store).List(0xc0000100a8)
+	/src/app/store/store.go:133 +0x56
+github.com/org/app/api.(*Server).Items(0xc0000100a8)
+	/src/app/api/items.go:42 +0x56
+`
+	if got := filterStack(stack, keep); got != want {
+		t.Errorf("filterStack() =\n%s\nwant:\n%s", got, want)
+	}
+
+	frames := filterFrames(parseStack(stack), keep)
+	if len(frames) != 2 || frames[1] != (Frame{Function: "github.com/org/app/api.(*Server).Items", File: "/src/app/api/items.go", Line: 42}) {
+		t.Errorf("unexpected filtered frames: %+v", frames)
+	}
+
+	none := func(Frame) bool { return false }
+	if got := filterStack(stack, none); got != stack {
+		t.Errorf("stack without matching frames should be kept, got:\n%s", got)
+	}
+}
+
+func TestAdaptiveSampling(t *testing.T) {
+	mc, fc, _ := newTestConn(t, WithAdaptiveSampling(10, time.Second))
+	ctx := context.Background()
+
+	query := func() *monitor {
+		rows, err := mc.QueryContext(ctx, "SELECT 1", nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		_ = rows.Close()
+		return rows.(*monitoredRows).monitor
+	}
+
+	hotSampled := 0
+	for window := 0; window < 3; window++ {
+		for i := 0; i < 1000; i++ {
+			if query().sampled { // hot call site
+				hotSampled++
+			}
+		}
+		rare := query() // rare call site
+		if !rare.sampled {
+			t.Errorf("window %d: rare call site was not sampled", window)
+		}
+		fc.Advance(time.Second)
+	}
+
+	// About 10 per window are expected, the first window additionally samples its first 10 opens.
+	if hotSampled < 10 || hotSampled > 150 {
+		t.Errorf("hot call site sampled %d times in 3 windows, want about 40", hotSampled)
+	}
+}
+
+func TestFirstCaptures(t *testing.T) {
+	mc, _, _ := newTestConn(t, WithAdaptiveSampling(1, time.Hour), WithFirstCaptures(50))
+
+	for i := 0; i < 50; i++ {
+		rows, err := mc.QueryContext(context.Background(), "SELECT 1", nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		_ = rows.Close()
+
+		if m := rows.(*monitoredRows).monitor; !m.sampled || len(m.stack) == 0 {
+			t.Fatalf("open %d: expected full capture within the first 50 opens", i+1)
+		}
+	}
+}
+
+func TestSampleRate(t *testing.T) {
+	mc, fc, logOutput := newTestConn(t, WithTimeout(time.Second), WithSampleRate(0.1))
+
+	const opens = 2000
+	sampled := 0
+	for i := 0; i < opens; i++ {
+		rows, err := mc.QueryContext(context.Background(), "SELECT 1", nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if m := rows.(*monitoredRows).monitor; m.sampled {
+			sampled++
+		} else if len(m.stack) != 0 {
+			t.Fatal("unsampled resource should not capture a stack")
+		}
+	}
+
+	if sampled < opens/20 || sampled > opens/5 {
+		t.Errorf("sampled %d of %d resources at rate 0.1, want about %d", sampled, opens, opens/10)
+	}
+
+	fc.Advance(2 * time.Second)
+	if got := strings.Count(logOutput.String(), "likely resource leak detected"); got != sampled {
+		t.Errorf("got %d leak warnings, want one per sampled resource (%d)", got, sampled)
+	}
+
+	none, _, _ := newTestConn(t, WithSampleRate(0))
+	rows, err := none.QueryContext(context.Background(), "SELECT 1", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if rows.(*monitoredRows).monitor.sampled {
+		t.Error("rate 0 should monitor no resources")
+	}
+}
+
+func TestContextSampling(t *testing.T) {
+	type sampledKey struct{}
+	var events []LeakEvent
+	mc, fc, _ := newTestConn(t,
+		WithTimeout(time.Second),
+		WithContextSampling(func(ctx context.Context) bool { return ctx.Value(sampledKey{}) != nil }),
+		WithOnLeak(func(ev LeakEvent) { events = append(events, ev) }),
+	)
+
+	sampled, _ := mc.QueryContext(context.WithValue(context.Background(), sampledKey{}, true), "SELECT 1", nil)
+	defer sampled.Close()
+	unsampled, _ := mc.QueryContext(context.Background(), "SELECT 2", nil)
+	defer unsampled.Close()
+	if m := unsampled.(*monitoredRows).monitor; m.sampled || len(m.stack) != 0 {
+		t.Error("resource opened with an unsampled context should not be monitored")
+	}
+
+	fc.Advance(time.Second)
+	if len(events) != 1 || events[0].Query != "SELECT 1" {
+		t.Errorf("expected only the sampled resource to leak, got %+v", events)
+	}
+}
+
+func TestQueryInReport(t *testing.T) {
+	mc, fc, logOutput := newTestConn(t, WithTimeout(time.Second))
+
+	stmt, _ := mc.PrepareContext(context.Background(), "SELECT id\n\tFROM users\n\tWHERE name = ?")
+	defer stmt.Close()
+	fc.Advance(time.Second)
+
+	if want := `Stmt not closed within 1s after opening (leak #1, resource #1, query "SELECT id FROM users WHERE name = ?", never executed, opened `; !strings.Contains(logOutput.String(), want) {
+		t.Errorf("expected %q in log, got:\n%s", want, logOutput.String())
+	}
+
+	long := "SELECT " + strings.Repeat("ä", maxHeadlineQueryLen)
+	got := shortenQuery(long)
+	if !strings.HasSuffix(got, "...") || len(got) > maxHeadlineQueryLen+len("...") || !utf8.ValidString(got) {
+		t.Errorf("shortenQuery() = %q, want a valid prefix of at most %d bytes followed by ...", got, maxHeadlineQueryLen)
+	}
+}
+
+func TestName(t *testing.T) {
+	mc, fc, logOutput := newTestConn(t, WithTimeout(time.Second), WithName("orders-replica"), WithJSONOutput())
+
+	rows, _ := mc.QueryContext(context.Background(), "SELECT 1", nil)
+	defer rows.Close()
+	fc.Advance(time.Second)
+
+	ev, err := DecodeLeakEvent(logOutput.Bytes())
+	if err != nil {
+		t.Fatalf("failed to decode %q: %v", logOutput.String(), err)
+	}
+	if ev.Name != "orders-replica" {
+		t.Errorf("got name %q in JSON output", ev.Name)
+	}
+	if want := "[orders-replica] likely resource leak detected: "; !strings.HasPrefix(ev.Text(), want) {
+		t.Errorf("got %q, want prefix %q", ev.Text(), want)
+	}
+	if d := mc.detector; d.Stats().Name != "orders-replica" || d.Config().Name != "orders-replica" {
+		t.Errorf("expected name in stats and config, got %q and %q", d.Stats().Name, d.Config().Name)
+	}
+}
+
+func TestResourceIDs(t *testing.T) {
+	mc, fc, logOutput := newTestConn(t, WithTimeout(time.Second))
+	ctx := context.Background()
+
+	first, _ := mc.QueryContext(ctx, "SELECT 1", nil)
+	_ = first.Close()
+	stmt, _ := mc.PrepareContext(ctx, "SELECT 2")
+	defer stmt.Close()
+	rows, _ := mc.QueryContext(ctx, "SELECT 3", nil)
+
+	if got := []uint64{first.(*monitoredRows).monitor.id, stmt.(*monitoredStmt).monitor.id, rows.(*monitoredRows).monitor.id}; got[0] != 1 || got[1] != 1 || got[2] != 2 {
+		t.Errorf("got resource IDs %v, want them numbered per kind", got)
+	}
+
+	fc.Advance(2 * time.Second)
+	_ = rows.Close()
+	if want := "(leak #2, resource #2)"; !strings.Contains(logOutput.String(), "Rows closed 2s after opening, 1s after the timeout "+want) {
+		t.Errorf("expected resource ID in the follow-up, got:\n%s", logOutput.String())
+	}
+}
+
+func TestOpenerGoroutine(t *testing.T) {
+	for name, opts := range map[string][]Option{
+		"stack":         nil,
+		"caller only":   {WithCallerOnly()},
+		"without stack": {WithoutStacks()},
+	} {
+		t.Run(name, func(t *testing.T) {
+			mc, fc, logOutput := newTestConn(t, append(opts, WithTimeout(time.Second))...)
+
+			var (
+				rows   driver.Rows
+				opener uint64
+			)
+			done := make(chan struct{})
+			go func() {
+				defer close(done)
+				opener = currentGoroutineID()
+				rows, _ = mc.QueryContext(context.Background(), "SELECT 1", nil)
+			}()
+			<-done
+			defer rows.Close()
+
+			if opener == 0 || opener == currentGoroutineID() {
+				t.Fatalf("unexpected opener goroutine %d", opener)
+			}
+			if got := rows.(*monitoredRows).monitor.leakEvent().Goroutine; got != opener {
+				t.Errorf("got goroutine %d, want %d", got, opener)
+			}
+
+			fc.Advance(time.Second)
+			if want := fmt.Sprintf("opened 2025-01-01T00:00:00.000Z by goroutine %d", opener); !strings.Contains(logOutput.String(), want) {
+				t.Errorf("expected %q in log, got:\n%s", want, logOutput.String())
+			}
+		})
+	}
+}
+
+func TestRepeatInterval(t *testing.T) {
+	mc, fc, logOutput := newTestConn(t, WithTimeout(5*time.Second), WithRepeatInterval(10*time.Second))
+
+	rows, err := mc.QueryContext(context.Background(), "SELECT 1", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	fc.Advance(5 * time.Second)
+	fc.Advance(10 * time.Second)
+	fc.Advance(10 * time.Second)
+	_ = rows.Close()
+	fc.Advance(10 * time.Second)
+
+	out := logOutput.String()
+	if n := strings.Count(out, "likely resource leak detected"); n != 3 {
+		t.Errorf("got %d warnings, want 3:\n%s", n, out)
+	}
+	for _, want := range []string{`(leak #1, resource #1, query "SELECT 1", 0 rows fetched, warning #2, open for 15s, opened `, `(leak #1, resource #1, query "SELECT 1", 0 rows fetched, warning #3, open for 25s, opened `, "Rows closed 25s after opening, 20s after the timeout (leak #1, resource #1)"} {
+		if !strings.Contains(out, want) {
+			t.Errorf("expected %q in log:\n%s", want, out)
+		}
+	}
+	if n := fc.Pending(); n != 0 {
+		t.Errorf("%d timers still pending after close", n)
+	}
+}
+
+func TestLeakBudget(t *testing.T) {
+	var exhausted []BudgetStats
+	mc, fc, _ := newTestConn(t,
+		WithTimeout(time.Second),
+		WithRepeatInterval(time.Second),
+		WithLeakBudget(2, 24*time.Hour, func(s BudgetStats) { exhausted = append(exhausted, s) }),
+	)
+
+	leak := func() {
+		if _, err := mc.QueryContext(context.Background(), "SELECT 1", nil); err != nil {
+			t.Fatal(err)
+		}
+		fc.Advance(time.Second)
+	}
+
+	leak()
+	leak() // also repeats the first warning, which must not count
+	if got := mc.detector.Stats().Budget; got.Used != 2 || got.Remaining != 0 || got.Exhausted {
+		t.Errorf("after 2 leaks: %+v", got)
+	}
+	if len(exhausted) != 0 {
+		t.Errorf("budget exhausted early: %+v", exhausted)
+	}
+
+	leak()
+	leak()
+	if len(exhausted) != 1 || exhausted[0].Used != 3 {
+		t.Errorf("expected a single exhaustion callback at 3 leaks, got %+v", exhausted)
+	}
+
+	fc.Advance(25 * time.Hour)
+	if got := mc.detector.Stats().Budget; got.Used != 0 || got.Remaining != 2 || got.Exhausted {
+		t.Errorf("after the window passed: %+v", got)
+	}
+}
+
+func TestLeakBudgetShortWindow(t *testing.T) {
+	for _, window := range []time.Duration{0, -time.Second, 10} {
+		mc, fc, _ := newTestConn(t, WithTimeout(time.Second), WithLeakBudget(2, window, nil))
+
+		rows, _ := mc.QueryContext(context.Background(), "SELECT 1", nil)
+		fc.Advance(time.Second)
+		rows.Close()
+
+		got := mc.detector.Stats().Budget
+		if window <= 0 && got != (BudgetStats{}) {
+			t.Errorf("expected a window of %s to keep the budget off, got %+v", window, got)
+		}
+		if window > 0 && got.Used != 1 {
+			t.Errorf("expected the leak in a window of %s, got %+v", window, got)
+		}
+	}
+}
+
+func TestKindStats(t *testing.T) {
+	mc, fc, _ := newTestConn(t, WithTimeout(time.Second))
+	ctx := context.Background()
+
+	leaked, _ := mc.QueryContext(ctx, "SELECT 1", nil)
+	tx, _ := mc.BeginTx(ctx, driver.TxOptions{})
+	fc.Advance(time.Second / 2)
+	_ = tx.Commit()
+	fc.Advance(time.Second / 2)
+	open, _ := mc.QueryContext(ctx, "SELECT 2", nil)
+	defer open.Close()
+
+	// counts drops the lifetime histogram, see TestLifetimeHistogram.
+	counts := func(s KindStats) KindStats {
+		s.Lifetime = Histogram{}
+		return s
+	}
+
+	stats := mc.detector.Stats()
+	if got, want := counts(stats.Rows), (KindStats{Open: 2, Leaked: 1, MaxAge: time.Second}); !reflect.DeepEqual(got, want) {
+		t.Errorf("Rows stats %+v, want %+v", got, want)
+	}
+	if got, want := counts(stats.Tx), (KindStats{MaxAge: time.Second / 2}); !reflect.DeepEqual(got, want) {
+		t.Errorf("Tx stats %+v, want %+v", got, want)
+	}
+
+	fc.Advance(time.Second / 2)
+	_ = leaked.Close()
+	if got, want := counts(mc.detector.Stats().Rows), (KindStats{Open: 1, Leaked: 1, MaxAge: 1500 * time.Millisecond}); !reflect.DeepEqual(got, want) {
+		t.Errorf("Rows stats after closing the leak %+v, want %+v", got, want)
+	}
+}
+
+func TestWithoutStacks(t *testing.T) {
+	mc, fc, logOutput := newTestConn(t, WithTimeout(time.Second), WithoutStacks())
+	ctx := context.Background()
+
+	for _, query := range []string{"SELECT 1", "SELECT 1", "SELECT 2"} {
+		rows, _ := mc.QueryContext(ctx, query, nil)
+		defer rows.Close()
+	}
+	tx, _ := mc.BeginTx(ctx, driver.TxOptions{})
+	defer tx.Rollback()
+	fc.Advance(2 * time.Second)
+
+	out := regexp.MustCompile(`by goroutine \d+`).ReplaceAllString(logOutput.String(), "by goroutine N")
+	for _, want := range []string{
+		`Rows not closed within 1s after opening (leak #1, resource #1, query "SELECT 1", 0 rows fetched, opened 2025-01-01T00:00:00.000Z by goroutine N); 1 Rows leaked from this query` + "\n",
+		`Rows not closed within 1s after opening (leak #2, resource #2, query "SELECT 1", 0 rows fetched, opened 2025-01-01T00:00:00.000Z by goroutine N); 2 Rows leaked from this query` + "\n",
+		`Rows not closed within 1s after opening (leak #3, resource #3, query "SELECT 2", 0 rows fetched, opened 2025-01-01T00:00:00.000Z by goroutine N); 1 Rows leaked from this query` + "\n",
+		`Tx not closed within 1s after opening (leak #4, resource #1, opened 2025-01-01T00:00:00.000Z by goroutine N); 1 Tx leaked` + "\n",
+	} {
+		if !strings.Contains(out, want) {
+			t.Errorf("expected %q in log, got:\n%s", want, out)
+		}
+	}
+	if strings.Contains(out, "[running]") {
+		t.Errorf("did not expect stacks in log:\n%s", out)
+	}
+	if n := mc.detector.Stats().Overhead.StackCaptures; n != 0 {
+		t.Errorf("got %d stack captures, want 0", n)
+	}
+}
+
+func TestStackBufferSize(t *testing.T) {
+	var events []LeakEvent
+	mc, fc, logOutput := newTestConn(t,
+		WithTimeout(time.Second),
+		WithStackBufferSize(256),
+		WithOnLeak(func(ev LeakEvent) { events = append(events, ev) }),
+	)
+
+	rows, _ := mc.QueryContext(context.Background(), "SELECT 1", nil)
+	defer rows.Close()
+	fc.Advance(time.Second)
+
+	if len(events) != 1 || !events[0].StackTruncated {
+		t.Fatalf("expected a truncated stack, got %+v", events)
+	}
+	if !strings.HasSuffix(events[0].Stack, "\n...truncated\n") || len(events[0].Stack) > 256+len(truncatedMarker) {
+		t.Errorf("unexpected truncated stack:\n%s", events[0].Stack)
+	}
+	if !strings.Contains(logOutput.String(), "...truncated") {
+		t.Errorf("expected truncation marker in log, got:\n%s", logOutput.String())
+	}
+	for _, f := range events[0].Frames {
+		if f.Line == 0 {
+			t.Errorf("partial frame parsed from truncated stack: %+v", f)
+		}
+	}
+}
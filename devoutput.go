@@ -0,0 +1,147 @@
+package sqleak
+
+import (
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"strings"
+	"time"
+)
+
+// WithDevOutput formats reports for reading in a terminal during local development: a one-line summary of what
+// leaked, for how long and from which query, followed by the application frames of the stack only, leaving out
+// the runtime, database/sql, sqleak and the standard library. The summary is colored by severity if the standard
+// logger writes to a terminal and NO_COLOR is not set. Reports are written without the logger's prefix and
+// timestamps, times are relative to the report instead.
+func WithDevOutput() Option {
+	return func(ld *monitoredDriver) {
+		ld.devOutput = true
+	}
+}
+
+// ANSI escape sequences of WithDevOutput.
+const (
+	ansiReset  = "\x1b[0m"
+	ansiBold   = "\x1b[1m"
+	ansiDim    = "\x1b[2m"
+	ansiRed    = "\x1b[31m"
+	ansiGreen  = "\x1b[32m"
+	ansiYellow = "\x1b[33m"
+	ansiCyan   = "\x1b[36m"
+)
+
+// isTerminal reports whether w is a terminal that takes colors.
+func isTerminal(w io.Writer) bool {
+	if _, ok := os.LookupEnv("NO_COLOR"); ok {
+		return false
+	}
+
+	f, ok := w.(*os.File)
+	if !ok {
+		return false
+	}
+	info, err := f.Stat()
+	return err == nil && info.Mode()&os.ModeCharDevice != 0
+}
+
+// isStandardLibraryFrame reports whether a frame is of a standard library package, whose import paths,
+// unlike those of modules, have no dot in their first element.
+func isStandardLibraryFrame(f Frame) bool {
+	path := f.Function
+	if i := strings.LastIndexByte(path, '/'); i >= 0 {
+		first, _, _ := strings.Cut(path, "/")
+		return !strings.Contains(first, ".")
+	}
+
+	pkg, _, _ := strings.Cut(path, ".")
+	return pkg != "main"
+}
+
+func (d *Detector) reportDev(ev LeakEvent) {
+	w := log.Writer()
+	text := ev.devText(isTerminal(w))
+
+	jsonWriteMu.Lock()
+	defer jsonWriteMu.Unlock()
+	_, _ = io.WriteString(w, text)
+}
+
+// devText renders the event for WithDevOutput.
+func (ev LeakEvent) devText(colored bool) string {
+	paint := func(color, s string) string {
+		if !colored {
+			return s
+		}
+		return color + s + ansiReset
+	}
+
+	color := ansiYellow
+	switch ev.Severity {
+	case SeverityCritical, SeverityError:
+		color = ansiRed
+	case SeverityInfo:
+		color = ansiCyan
+	}
+
+	age := ev.Age.Round(time.Millisecond)
+	var what string
+	switch {
+	case ev.Type == EventClosedLate:
+		color, what = ansiGreen, fmt.Sprintf("%s closed late after %s", ev.Kind, age)
+	case ev.Type == EventOutstanding:
+		what = fmt.Sprintf("%s still open after %s", ev.Kind, age)
+	case ev.Type == EventForcedRollback:
+		color, what = ansiRed, fmt.Sprintf("%s rolled back after %s", ev.Kind, age)
+	case ev.Type == EventLongTx:
+		what = fmt.Sprintf("%s held open for %s", ev.Kind, age)
+	case ev.Type == EventStalled:
+		what = fmt.Sprintf("%s stalled, Next not called for %s", ev.Kind, ev.Idle.Round(time.Millisecond))
+	case ev.Type == EventCloseFailed:
+		color, what = ansiRed, fmt.Sprintf("%s failed to close, %s", ev.Kind, ev.Error)
+	case ev.exhausted():
+		what = fmt.Sprintf("%s exhausted but not closed, open for %s", ev.Kind, age)
+	case ev.Type == EventLeak && ev.Idle > 0:
+		what = fmt.Sprintf("%s leaked, idle for %s", ev.Kind, ev.Idle.Round(time.Millisecond))
+	case ev.Occurrence > 1:
+		what = fmt.Sprintf("%s still open after %s, warning #%d", ev.Kind, age, ev.Occurrence)
+	default:
+		what = fmt.Sprintf("%s leaked, open for %s", ev.Kind, age)
+	}
+
+	summary := []string{paint(ansiBold, namePrefix(ev.Name)+"sqleak") + " " + paint(color, what)}
+	if ev.Severity != "" {
+		summary = append(summary, string(ev.Severity))
+	}
+	if ev.LeakID != 0 {
+		summary = append(summary, fmt.Sprintf("leak #%d", ev.LeakID))
+	}
+	if ev.Query != "" {
+		summary = append(summary, fmt.Sprintf("%q", shortenQuery(ev.Query)))
+	}
+
+	var b strings.Builder
+	b.WriteString(strings.Join(summary, paint(ansiDim, " · ")) + "\n")
+
+	hidden := 0
+	for _, f := range ev.Frames {
+		if isInfrastructureFrame(f) || isStandardLibraryFrame(f) {
+			hidden++
+			continue
+		}
+		fmt.Fprintf(&b, "    %s %s\n", f.Function, paint(ansiDim, fmt.Sprintf("%s:%d", f.File, f.Line)))
+	}
+	if hidden > 0 {
+		b.WriteString(paint(ansiDim, fmt.Sprintf("    %d runtime and library frames hidden", hidden)) + "\n")
+	}
+
+	if ev.Type != EventClosedLate && !ev.OpenedAt.IsZero() {
+		opened := fmt.Sprintf("    opened %s ago", age)
+		if ev.Goroutine != 0 {
+			opened += fmt.Sprintf(" by goroutine %d", ev.Goroutine)
+		}
+		b.WriteString(paint(ansiDim, opened) + "\n")
+	}
+
+	return b.String()
+}
@@ -0,0 +1,61 @@
+//go:build !sqleak_noop
+
+package sqleak
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestDevText(t *testing.T) {
+	ev := LeakEvent{
+		Type:      EventLeak,
+		LeakID:    2,
+		Kind:      KindRows,
+		Query:     "SELECT id\n  FROM users",
+		Severity:  SeverityWarning,
+		OpenedAt:  time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC),
+		Goroutine: 7,
+		Timeout:   time.Second,
+		Age:       1500 * time.Millisecond,
+		Frames: []Frame{
+			{Function: "database/sql.(*DB).QueryContext", File: "/go/src/database/sql/sql.go", Line: 1},
+			{Function: "github.com/org/app/store.(*Store).List", File: "/app/store/store.go", Line: 42},
+			{Function: "net/http.HandlerFunc.ServeHTTP", File: "/go/src/net/http/server.go", Line: 2},
+			{Function: "main.main", File: "/app/main.go", Line: 10},
+			{Function: "runtime.main", File: "/go/src/runtime/proc.go", Line: 3},
+		},
+	}
+
+	want := `sqleak Rows leaked, open for 1.5s · warning · leak #2 · "SELECT id FROM users"
+    github.com/org/app/store.(*Store).List /app/store/store.go:42
+    main.main /app/main.go:10
+    3 runtime and library frames hidden
+    opened 1.5s ago by goroutine 7
+`
+	if got := ev.devText(false); got != want {
+		t.Errorf("got:\n%s\nwant:\n%s", got, want)
+	}
+
+	ev.Severity = SeverityCritical
+	if got := ev.devText(true); !strings.Contains(got, ansiRed+"Rows leaked, open for 1.5s"+ansiReset) {
+		t.Errorf("expected a red summary, got %q", got)
+	}
+}
+
+func TestDevOutput(t *testing.T) {
+	mc, fc, logOutput := newTestConn(t, WithTimeout(time.Second), WithDevOutput())
+
+	rows, _ := mc.QueryContext(context.Background(), "SELECT 1", nil)
+	fc.Advance(time.Second)
+	_ = rows.Close()
+
+	out := logOutput.String()
+	if !strings.HasPrefix(out, `sqleak Rows leaked, open for 1s · warning · leak #1 · "SELECT 1"`+"\n") ||
+		!strings.Contains(out, "\nsqleak Rows closed late after 1s · warning · leak #1") ||
+		strings.Contains(out, "\x1b[") {
+		t.Errorf("unexpected dev output:\n%s", out)
+	}
+}
@@ -2,6 +2,7 @@ package sqleak
 
 import (
 	"database/sql/driver"
+	"fmt"
 	"time"
 )
 
@@ -11,39 +12,54 @@ var (
 )
 
 type monitoredDriver struct {
-	driver  driver.Driver
-	timeout time.Duration
+	driver driver.Driver
+	*Detector
 }
 
 func newMonitoredDriver(d driver.Driver, timeout time.Duration) *monitoredDriver {
 	if _, ok := d.(driver.DriverContext); ok {
 		return &monitoredDriver{
-			driver:  d,
-			timeout: timeout,
+			driver:   d,
+			Detector: newDetector(timeout, fmt.Sprintf("%T", d)),
 		}
 	}
 
 	// Only implements driver.Driver
 	return &monitoredDriver{
-		driver:  struct{ driver.Driver }{d},
-		timeout: timeout,
+		driver:   struct{ driver.Driver }{d},
+		Detector: newDetector(timeout, fmt.Sprintf("%T", d)),
 	}
 }
 
 func (d *monitoredDriver) Open(name string) (driver.Conn, error) {
+	if noop {
+		return d.driver.Open(name)
+	}
+
+	start := d.connectStarted()
 	conn, err := d.driver.Open(name)
+	d.connectDone(start)
 	if err != nil {
 		return nil, err
 	}
 
-	return newMonitoredConn(conn, d.timeout), nil
+	mc := newMonitoredConn(conn, d.Detector)
+	mc.checkedOut()
+	mc.database = parseDSN(name)
+
+	return mc, nil
 }
 
 func (d *monitoredDriver) OpenConnector(name string) (driver.Connector, error) {
-	connector, err := d.driver.(driver.DriverContext).OpenConnector(name)
+	dc, ok := d.driver.(driver.DriverContext)
+	if !ok {
+		return dsnConnector{dsn: name, driver: d}, nil
+	}
+
+	connector, err := dc.OpenConnector(name)
 	if err != nil {
 		return nil, err
 	}
 
-	return newMonitoredConnector(connector, d), nil
+	return newMonitoredConnector(connector, d, parseDSN(name)), nil
 }
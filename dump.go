@@ -0,0 +1,84 @@
+package sqleak
+
+import (
+	"runtime"
+	"sync"
+	"time"
+)
+
+// fullDumpInterval rate-limits the goroutine dumps of WithFullDumpOnLeak.
+const fullDumpInterval = time.Minute
+
+// WithFullDumpOnLeak adds the stacks of all goroutines to leak reports, showing the whole program state at the
+// moment of the leak. Dumps are rate-limited to one per minute, as each briefly stops the world and can be large.
+// Leaks not logged due to WithDeduplication don't take a dump.
+func WithFullDumpOnLeak() Option {
+	return func(ld *monitoredDriver) {
+		ld.fullDump = &dumpLimiter{interval: fullDumpInterval}
+	}
+}
+
+type dumpLimiter struct {
+	interval time.Duration
+
+	mu   sync.Mutex
+	last time.Time
+}
+
+// allow reports whether a dump may be taken at now, and if so counts it.
+func (l *dumpLimiter) allow(now time.Time) bool {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	if !l.last.IsZero() && now.Sub(l.last) < l.interval {
+		return false
+	}
+	l.last = now
+
+	return true
+}
+
+const (
+	initialDumpBufferSize = 64 * 1024
+	maxDumpBufferSize     = 64 * 1024 * 1024
+)
+
+// goroutineDump returns the stacks of all goroutines, growing the buffer until they fit.
+func goroutineDump() []byte {
+	buf := make([]byte, initialDumpBufferSize)
+	for {
+		n := runtime.Stack(buf, true)
+		if n < len(buf) || len(buf) >= maxDumpBufferSize {
+			return buf[:n]
+		}
+		buf = make([]byte, 2*len(buf))
+	}
+}
+
+// dumpSection renders GoroutineDump below a report, empty without one.
+func (ev LeakEvent) dumpSection() string {
+	if ev.GoroutineDump == "" {
+		return ""
+	}
+
+	return "\nall goroutines at the time of the leak:\n" + ev.GoroutineDump
+}
+
+// attachGoroutines adds the details of WithFullDumpOnLeak and WithOpenerStack to a leak event,
+// sharing a single goroutine dump between both.
+func (m *monitor) attachGoroutines(ev *LeakEvent) {
+	d := m.detector
+	full := d.fullDump != nil && !ev.quiet && d.fullDump.allow(d.clock.Now())
+	opener := d.openerStack && m.goroutine != 0
+	if !full && !opener {
+		return
+	}
+
+	dump := goroutineDump()
+	if full {
+		ev.GoroutineDump = string(dump)
+	}
+	if opener {
+		attachOpenerStack(ev, dump, m.goroutine)
+	}
+}
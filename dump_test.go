@@ -0,0 +1,47 @@
+//go:build !sqleak_noop
+
+package sqleak
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestFullDumpOnLeak(t *testing.T) {
+	var events []LeakEvent
+	mc, fc, logOutput := newTestConn(t,
+		WithTimeout(time.Second),
+		WithFullDumpOnLeak(),
+		WithOnLeak(func(ev LeakEvent) {
+			if ev.Type == EventLeak {
+				events = append(events, ev)
+			}
+		}),
+	)
+
+	leak := func() {
+		rows, _ := mc.QueryContext(context.Background(), "SELECT 1", nil)
+		defer rows.Close()
+		fc.Advance(time.Second)
+	}
+
+	leak()
+	leak() // within the minute
+	fc.Advance(fullDumpInterval)
+	leak()
+
+	if len(events) != 3 {
+		t.Fatalf("got %d events, want 3", len(events))
+	}
+	for i, want := range []bool{true, false, true} {
+		if got := strings.Contains(events[i].GoroutineDump, "TestFullDumpOnLeak"); got != want {
+			t.Errorf("event %d: expected dump %v, got:\n%s", i, want, events[i].GoroutineDump)
+		}
+	}
+
+	if n := strings.Count(logOutput.String(), "\nall goroutines at the time of the leak:\ngoroutine "); n != 2 {
+		t.Errorf("expected 2 dumps in log, got %d:\n%s", n, logOutput.String())
+	}
+}
@@ -0,0 +1,8 @@
+//go:build !unix
+
+package sqleak
+
+import "os"
+
+// defaultDumpSignals are the signals of DumpOnSignal if none are given, there is no SIGUSR1 outside of unix.
+var defaultDumpSignals []os.Signal
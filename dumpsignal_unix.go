@@ -0,0 +1,11 @@
+//go:build unix
+
+package sqleak
+
+import (
+	"os"
+	"syscall"
+)
+
+// defaultDumpSignals are the signals of DumpOnSignal if none are given.
+var defaultDumpSignals = []os.Signal{syscall.SIGUSR1}
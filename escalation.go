@@ -0,0 +1,84 @@
+package sqleak
+
+import "time"
+
+// WithSeverityEscalation reports a leaked resource again as it stays open, with rising severity: the first report at
+// the timeout has SeverityWarning, another one at errorAfter times the timeout SeverityError and a last one at
+// criticalAfter times the timeout SeverityCritical, e.g. WithSeverityEscalation(3, 10). The severity then depends on
+// how long the resource is open, not on the estimated cost. Multipliers not above those before them are ignored.
+// Reports of WithRepeatInterval in between carry the severity reached so far.
+func WithSeverityEscalation(errorAfter, criticalAfter float64) Option {
+	if errorAfter <= 1 {
+		errorAfter = 0
+	}
+	if criticalAfter <= max(1, errorAfter) {
+		criticalAfter = 0
+	}
+
+	return func(ld *monitoredDriver) {
+		ld.escalation = &escalation{errorAfter: errorAfter, criticalAfter: criticalAfter}
+	}
+}
+
+type escalation struct {
+	errorAfter, criticalAfter float64 // multiples of the timeout, 0 if ignored
+}
+
+// levels returns the ages at which a resource with the given timeout escalates, in ascending order.
+func (e *escalation) levels(timeout time.Duration) []time.Duration {
+	var ages []time.Duration
+	for _, f := range []float64{e.errorAfter, e.criticalAfter} {
+		if f != 0 {
+			ages = append(ages, time.Duration(f*float64(timeout)))
+		}
+	}
+
+	return ages
+}
+
+// severity returns the severity of a resource with the given timeout that is open for age.
+func (e *escalation) severity(age, timeout time.Duration) Severity {
+	switch {
+	case e.criticalAfter != 0 && age >= time.Duration(e.criticalAfter*float64(timeout)):
+		return SeverityCritical
+	case e.errorAfter != 0 && age >= time.Duration(e.errorAfter*float64(timeout)):
+		return SeverityError
+	}
+
+	return SeverityWarning
+}
+
+// warningAge returns the age of a resource with the given timeout at which its n-th warning is due: the first one at
+// the timeout, then one at every escalation level and every repeat interval, whichever comes next.
+func (d *Detector) warningAge(timeout time.Duration, n int32) (time.Duration, bool) {
+	if n == 1 {
+		return timeout, true
+	}
+
+	var levels []time.Duration
+	if d.escalation != nil {
+		levels = d.escalation.levels(timeout)
+	}
+
+	repeat := timeout + d.repeatInterval
+	for w := int32(2); ; w++ {
+		var age time.Duration
+		switch {
+		case d.repeatInterval <= 0 && len(levels) == 0:
+			return 0, false
+		case d.repeatInterval <= 0 || len(levels) > 0 && levels[0] <= repeat:
+			age = levels[0]
+			levels = levels[1:]
+			if age == repeat {
+				repeat += d.repeatInterval
+			}
+		default:
+			age = repeat
+			repeat += d.repeatInterval
+		}
+
+		if w == n {
+			return age, true
+		}
+	}
+}
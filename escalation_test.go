@@ -0,0 +1,80 @@
+//go:build !sqleak_noop
+
+package sqleak
+
+import (
+	"context"
+	"slices"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestSeverityEscalation(t *testing.T) {
+	var events []LeakEvent
+	mc, fc, logOutput := newTestConn(t,
+		WithTimeout(time.Second),
+		WithSeverityEscalation(3, 10),
+		WithOnLeak(func(ev LeakEvent) { events = append(events, ev) }),
+	)
+
+	rows, _ := mc.QueryContext(context.Background(), "SELECT 1", nil)
+	defer rows.Close()
+	for range 20 {
+		fc.Advance(time.Second)
+	}
+
+	want := []struct {
+		age      time.Duration
+		severity Severity
+	}{
+		{time.Second, SeverityWarning},
+		{3 * time.Second, SeverityError},
+		{10 * time.Second, SeverityCritical},
+	}
+	if len(events) != len(want) {
+		t.Fatalf("got %d events, want %d:\n%s", len(events), len(want), logOutput.String())
+	}
+	for i, w := range want {
+		if ev := events[i]; ev.Age != w.age || ev.Severity != w.severity || ev.Occurrence != i+1 {
+			t.Errorf("event %d: got %s %s warning #%d, want %s %s", i, ev.Age, ev.Severity, ev.Occurrence, w.age, w.severity)
+		}
+	}
+
+	if want := "warning #3, open for 10s, escalated to critical, opened "; !strings.Contains(logOutput.String(), want) {
+		t.Errorf("expected %q in log, got:\n%s", want, logOutput.String())
+	}
+}
+
+func TestWarningAge(t *testing.T) {
+	for _, c := range []struct {
+		name string
+		opts []Option
+		want []time.Duration
+	}{
+		{"default", nil, []time.Duration{2}},
+		{"repeat", []Option{WithRepeatInterval(3)}, []time.Duration{2, 5, 8, 11}},
+		{"escalation", []Option{WithSeverityEscalation(3, 10)}, []time.Duration{2, 6, 20}},
+		{"escalation and repeat", []Option{WithSeverityEscalation(3, 10), WithRepeatInterval(4)}, []time.Duration{2, 6, 10, 14, 18, 20, 22}},
+		{"ignored levels", []Option{WithSeverityEscalation(0.5, 10)}, []time.Duration{2, 20}},
+	} {
+		d := newDetector(2, "test")
+		ld := &monitoredDriver{Detector: d}
+		for _, opt := range c.opts {
+			opt(ld)
+		}
+
+		var got []time.Duration
+		for n := int32(1); n <= int32(len(c.want)); n++ {
+			if age, ok := d.warningAge(d.timeout, n); ok {
+				got = append(got, age)
+			}
+		}
+		if _, ok := d.warningAge(d.timeout, int32(len(c.want)+1)); ok && d.repeatInterval == 0 {
+			t.Errorf("%s: expected no warning after %v", c.name, got)
+		}
+		if !slices.Equal(got, c.want) {
+			t.Errorf("%s: got warnings at %v, want %v", c.name, got, c.want)
+		}
+	}
+}
@@ -0,0 +1,89 @@
+package sqleak
+
+import (
+	"database/sql"
+	"sync"
+)
+
+// defaultEventsBuffer is the channel buffer used by Events.
+const defaultEventsBuffer = 64
+
+// Subscribe returns a channel receiving every leak event detected from now on, and a function to cancel the subscription.
+// Events are delivered without blocking the detecting timer goroutine: if the buffer is full, the event is dropped
+// for this subscriber and counted in Stats().DroppedEvents. The channel is closed when the subscription is
+// cancelled or the sql.DB is closed. A negative buffer is treated as 0.
+func (d *Detector) Subscribe(buffer int) (<-chan LeakEvent, func()) {
+	sub := &subscription{ch: make(chan LeakEvent, max(buffer, 0))}
+
+	d.subscribers.mu.Lock()
+	if d.subscribers.closed {
+		close(sub.ch)
+	} else {
+		d.subscribers.subs = append(d.subscribers.subs, sub)
+	}
+	d.subscribers.mu.Unlock()
+
+	return sub.ch, func() { d.subscribers.remove(sub) }
+}
+
+// Events subscribes to the leak events of db, which must have been opened with Open.
+// The channel is closed when db is closed or cancel is called, which a consumer that stops reading before db is
+// closed must do, see Detector.Subscribe for the delivery semantics.
+// It returns a nil channel if db is not instrumented by sqleak.
+func Events(db *sql.DB) (events <-chan LeakEvent, cancel func()) {
+	d := DetectorOf(db)
+	if d == nil {
+		return nil, func() {}
+	}
+
+	return d.Subscribe(defaultEventsBuffer)
+}
+
+type subscription struct {
+	ch chan LeakEvent
+}
+
+type subscribers struct {
+	mu     sync.RWMutex
+	subs   []*subscription
+	closed bool
+}
+
+func (s *subscribers) publish(ev LeakEvent) (dropped int) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	for _, sub := range s.subs {
+		select {
+		case sub.ch <- ev:
+		default:
+			dropped++
+		}
+	}
+
+	return dropped
+}
+
+func (s *subscribers) remove(sub *subscription) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for i, other := range s.subs {
+		if other == sub {
+			s.subs = append(s.subs[:i], s.subs[i+1:]...)
+			close(sub.ch)
+			return
+		}
+	}
+}
+
+func (s *subscribers) close() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.closed = true
+	for _, sub := range s.subs {
+		close(sub.ch)
+	}
+	s.subs = nil
+}
@@ -0,0 +1,90 @@
+package sqleak
+
+import (
+	"fmt"
+	"strings"
+)
+
+type dbFamily int
+
+const (
+	familyUnknown dbFamily = iota
+	familyPostgres
+	familyMySQL
+	familySQLite
+)
+
+// familyOf guesses the database behind a driver from its registered name or type.
+func familyOf(driverName string) dbFamily {
+	name := strings.ToLower(driverName)
+	switch {
+	case strings.Contains(name, "pgx"), strings.Contains(name, "stdlib."), strings.Contains(name, "postgres"), strings.Contains(name, "pq."):
+		return familyPostgres
+	case strings.Contains(name, "mysql"):
+		return familyMySQL
+	case strings.Contains(name, "sqlite"):
+		return familySQLite
+	default:
+		return familyUnknown
+	}
+}
+
+type explanation struct {
+	what string
+	cost string
+	// driverCost maps a database family to additional, database specific costs.
+	driverCost map[dbFamily]string
+	fix        string
+}
+
+var explanations = map[Kind]explanation{
+	KindRows: {
+		what: "A *sql.Rows was neither closed nor fully iterated.",
+		cost: "Until then it pins its pool connection: no other query can use it, and once all connections are pinned every further query blocks waiting for one (see sql.DBStats.WaitCount).",
+		driverCost: map[dbFamily]string{
+			familyPostgres: "The Postgres backend also keeps the portal and any unsent result rows; inside a transaction it keeps the snapshot alive as well.",
+			familyMySQL:    "MySQL cannot run any other statement on the connection until the remaining result set has been read or discarded.",
+			familySQLite:   "The open read statement keeps a shared lock on the database file, which can make writers fail with SQLITE_BUSY.",
+		},
+		fix: "Add `defer rows.Close()` directly after checking the error returned by Query, and check rows.Err() after the loop. Helpers that return *sql.Rows hand the responsibility to close them to every caller.",
+	},
+	KindStmt: {
+		what: "A *sql.Stmt was prepared but not closed.",
+		cost: "It does not pin a connection, but keeps the statement prepared on every connection it was used on, until the connection is closed.",
+		driverCost: map[dbFamily]string{
+			familyPostgres: "Each Postgres backend keeps the prepared statement and its cached plan in memory.",
+			familyMySQL:    "Prepared statements count towards max_prepared_stmt_count; once it is exhausted, every Prepare on the server fails.",
+			familySQLite:   "The compiled statement stays allocated and can keep schema changes from taking effect.",
+		},
+		fix: "Add `defer stmt.Close()` directly after checking the error returned by Prepare. If the statement is only used once, call Query/Exec on the *sql.DB instead.",
+	},
+	KindTx: {
+		what: "A *sql.Tx was begun but neither committed nor rolled back.",
+		cost: "It pins its pool connection for the whole transaction and keeps holding every lock it acquired.",
+		driverCost: map[dbFamily]string{
+			familyPostgres: "The Postgres session stays 'idle in transaction', holding row locks and preventing VACUUM from cleaning up dead rows.",
+			familyMySQL:    "InnoDB keeps its row locks and undo history, so the history list length grows and other writers time out waiting for locks.",
+			familySQLite:   "The database lock is held, so other connections cannot write (SQLITE_BUSY).",
+		},
+		fix: "Add `defer tx.Rollback()` directly after checking the error returned by Begin; it is a no-op once Commit succeeded. Make sure every return path after Begin ends in Commit or Rollback.",
+	},
+}
+
+// Explain composes a human-oriented explanation of a leak event:
+// what resource leaked, what it costs while open on the event's driver, and how such leaks are usually fixed.
+func Explain(ev LeakEvent) string {
+	e, ok := explanations[ev.Kind]
+	if !ok {
+		return fmt.Sprintf("A %s was not closed within %s.", ev.Kind, ev.Timeout)
+	}
+
+	var b strings.Builder
+	fmt.Fprintf(&b, "What: %s It was still open %s after it was opened.\n", e.what, ev.Timeout)
+	fmt.Fprintf(&b, "Cost: %s", e.cost)
+	if driverCost, ok := e.driverCost[familyOf(ev.Driver)]; ok {
+		fmt.Fprintf(&b, " %s", driverCost)
+	}
+	fmt.Fprintf(&b, "\nFix: %s", e.fix)
+
+	return b.String()
+}
@@ -0,0 +1,47 @@
+package sqleak
+
+import "log"
+
+// FailMode decides what happens to the application when a leak is detected, see WithFailMode.
+type FailMode string
+
+const (
+	// FailLog only reports leaks, the default.
+	FailLog FailMode = "log"
+	// FailPanic panics with a *LeakPanic on the first report of every leak, after it was reported.
+	FailPanic FailMode = "panic"
+	// FailFatal logs the first report of a leak with its stack and exits the process with status 1, like log.Fatal.
+	FailFatal FailMode = "fatal"
+)
+
+// WithFailMode makes detected leaks panic or exit the process, e.g. WithFailMode(sqleak.FailPanic) in development
+// and CI so leaks are fixed before they are merged, while production stays at FailLog. The panic or exit happens on
+// the goroutine reporting the leak: a timer goroutine, or the caller of CheckDeadlines with WithServerless.
+func WithFailMode(mode FailMode) Option {
+	return func(ld *monitoredDriver) {
+		ld.failMode = mode
+	}
+}
+
+// LeakPanic is the panic value of FailPanic.
+type LeakPanic struct {
+	Event LeakEvent
+}
+
+// Error renders the leak with its stack, as printed by the runtime for unrecovered panics.
+func (p *LeakPanic) Error() string {
+	return p.Event.Text()
+}
+
+// logFatal is log.Fatal, replaced in tests.
+var logFatal = log.Fatal
+
+// fail applies the fail mode to the first report of a leak.
+func (d *Detector) fail(ev LeakEvent) {
+	switch d.failMode {
+	case FailPanic:
+		panic(&LeakPanic{Event: ev})
+	case FailFatal:
+		logFatal(ev.Text())
+	}
+}
@@ -0,0 +1,53 @@
+//go:build !sqleak_noop
+
+package sqleak
+
+import (
+	"context"
+	"log"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestFailMode(t *testing.T) {
+	t.Run("panic", func(t *testing.T) {
+		mc, fc, _ := newTestConn(t, WithTimeout(time.Second), WithFailMode(FailPanic))
+		rows, _ := mc.QueryContext(context.Background(), "SELECT 1", nil)
+		defer rows.Close()
+
+		var recovered any
+		func() {
+			defer func() { recovered = recover() }()
+			fc.Advance(time.Second)
+		}()
+
+		p, ok := recovered.(*LeakPanic)
+		if !ok || p.Event.Kind != KindRows || p.Event.LeakID != 1 {
+			t.Fatalf("expected a LeakPanic for the Rows, got %v", recovered)
+		}
+		if !strings.Contains(p.Error(), "likely resource leak detected: Rows not closed within 1s") || !strings.Contains(p.Error(), "goroutine ") {
+			t.Errorf("expected the panic to describe the leak with its stack, got:\n%s", p.Error())
+		}
+	})
+
+	t.Run("fatal", func(t *testing.T) {
+		var fatal []any
+		logFatal = func(v ...any) { fatal = v }
+		t.Cleanup(func() { logFatal = log.Fatal })
+
+		mc, fc, _ := newTestConn(t, WithTimeout(time.Second), WithRepeatInterval(time.Second), WithFailMode(FailFatal))
+		rows, _ := mc.QueryContext(context.Background(), "SELECT 1", nil)
+		defer rows.Close()
+		fc.Advance(time.Second)
+
+		if len(fatal) != 1 || !strings.Contains(fatal[0].(string), "Rows not closed within 1s") {
+			t.Fatalf("expected log.Fatal with the leak, got %v", fatal)
+		}
+		fatal = nil
+		fc.Advance(time.Second)
+		if fatal != nil {
+			t.Errorf("expected repeated warnings not to fail again, got %v", fatal)
+		}
+	})
+}
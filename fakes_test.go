@@ -0,0 +1,161 @@
+package sqleak
+
+import (
+	"context"
+	"database/sql/driver"
+	"io"
+	"sort"
+	"sync"
+	"time"
+)
+
+// fakeClock is a manually advanced clock. Timers fire synchronously in Advance.
+type fakeClock struct {
+	mu     sync.Mutex
+	now    time.Time
+	timers []*fakeTimer
+}
+
+type fakeTimer struct {
+	c    *fakeClock
+	when time.Time
+	f    func()
+}
+
+func newFakeClock() *fakeClock {
+	return &fakeClock{now: time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC)}
+}
+
+func withClock(c clock) Option {
+	return func(ld *monitoredDriver) {
+		ld.clock = c
+	}
+}
+
+func (c *fakeClock) Now() time.Time {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.now
+}
+
+func (c *fakeClock) AfterFunc(d time.Duration, f func()) timer {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	t := &fakeTimer{c: c, when: c.now.Add(d), f: f}
+	c.timers = append(c.timers, t)
+	return t
+}
+
+func (t *fakeTimer) Stop() bool {
+	t.c.mu.Lock()
+	defer t.c.mu.Unlock()
+
+	for i, other := range t.c.timers {
+		if other == t {
+			t.c.timers = append(t.c.timers[:i], t.c.timers[i+1:]...)
+			return true
+		}
+	}
+	return false
+}
+
+// Advance moves the clock forward and runs all timers that became due, in deadline order.
+func (c *fakeClock) Advance(d time.Duration) {
+	c.mu.Lock()
+	c.now = c.now.Add(d)
+
+	var due, pending []*fakeTimer
+	for _, t := range c.timers {
+		if !t.when.After(c.now) {
+			due = append(due, t)
+		} else {
+			pending = append(pending, t)
+		}
+	}
+	c.timers = pending
+	c.mu.Unlock()
+
+	sort.SliceStable(due, func(i, j int) bool { return due[i].when.Before(due[j].when) })
+	for _, t := range due {
+		t.f()
+	}
+}
+
+// Freeze moves the clock forward without running timers, like a process suspended by a serverless platform.
+func (c *fakeClock) Freeze(d time.Duration) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.now = c.now.Add(d)
+}
+
+func (c *fakeClock) Pending() int {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return len(c.timers)
+}
+
+// fakeDriver is a minimal in-memory driver, so the harness measures sqleak and nothing else.
+type fakeDriver struct{}
+
+type fakeConn struct{}
+
+type fakeStmt struct{}
+
+type fakeRows struct{ n int }
+
+type fakeTx struct{}
+
+func (fakeDriver) Open(string) (driver.Conn, error) { return fakeConn{}, nil }
+
+func (fakeConn) Prepare(string) (driver.Stmt, error) { return fakeStmt{}, nil }
+func (fakeConn) Close() error                        { return nil }
+func (fakeConn) Begin() (driver.Tx, error)           { return fakeTx{}, nil }
+func (fakeConn) QueryContext(context.Context, string, []driver.NamedValue) (driver.Rows, error) {
+	return &fakeRows{}, nil
+}
+
+func (fakeStmt) Close() error                               { return nil }
+func (fakeStmt) NumInput() int                              { return -1 }
+func (fakeStmt) Exec([]driver.Value) (driver.Result, error) { return driver.RowsAffected(0), nil }
+func (fakeStmt) Query([]driver.Value) (driver.Rows, error)  { return &fakeRows{}, nil }
+
+func (*fakeRows) Columns() []string { return []string{"n"} }
+func (*fakeRows) Close() error      { return nil }
+func (r *fakeRows) Next(dest []driver.Value) error {
+	if r.n > 0 {
+		return io.EOF
+	}
+	r.n++
+	dest[0] = int64(r.n)
+	return nil
+}
+
+func (fakeTx) Commit() error   { return nil }
+func (fakeTx) Rollback() error { return nil }
+
+// countingConnector counts the connections opened through a driver.
+type countingConnector struct {
+	driver driver.Driver
+	opens  int
+}
+
+func (c *countingConnector) Connect(context.Context) (driver.Conn, error) {
+	c.opens++
+	return c.driver.Open("")
+}
+
+func (c *countingConnector) Driver() driver.Driver { return c.driver }
+
+// legacyConn only implements the pre-context Queryer and Execer interfaces.
+type legacyConn struct{}
+
+func (legacyConn) Prepare(string) (driver.Stmt, error) { return fakeStmt{}, nil }
+func (legacyConn) Close() error                        { return nil }
+func (legacyConn) Begin() (driver.Tx, error)           { return fakeTx{}, nil }
+func (legacyConn) Query(string, []driver.Value) (driver.Rows, error) {
+	return &fakeRows{}, nil
+}
+func (legacyConn) Exec(string, []driver.Value) (driver.Result, error) {
+	return driver.RowsAffected(0), nil
+}
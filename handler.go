@@ -0,0 +1,133 @@
+package sqleak
+
+import (
+	"encoding/json"
+	"html/template"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+)
+
+// registry holds the started Detectors of the process, for Handler.
+var registry struct {
+	mu        sync.Mutex
+	detectors []*Detector
+}
+
+func register(d *Detector) {
+	registry.mu.Lock()
+	defer registry.mu.Unlock()
+
+	registry.detectors = append(registry.detectors, d)
+}
+
+func unregister(d *Detector) {
+	registry.mu.Lock()
+	defer registry.mu.Unlock()
+
+	for i, other := range registry.detectors {
+		if other == d {
+			registry.detectors = append(registry.detectors[:i], registry.detectors[i+1:]...)
+			return
+		}
+	}
+}
+
+func registeredDetectors() []*Detector {
+	registry.mu.Lock()
+	defer registry.mu.Unlock()
+
+	return append([]*Detector(nil), registry.detectors...)
+}
+
+// Handler serves a live snapshot of the open resources of every Detector in the process, e.g. mounted with
+// http.Handle("/debug/sqleak", sqleak.Handler()). It responds with JSON, or with an HTML page for browsers
+// and for ?format=html. Each resource is listed like an EventOutstanding with its kind, age, query and stack,
+// next to the Detector's effective configuration. Detectors appear once started and disappear once their sql.DB
+// is closed.
+func Handler() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		serveSnapshot(w, r, registeredDetectors())
+	})
+}
+
+// Handler serves the live snapshot of Handler for this Detector only.
+func (d *Detector) Handler() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		serveSnapshot(w, r, []*Detector{d})
+	})
+}
+
+type detectorSnapshot struct {
+	Name   string      `json:"name,omitempty"`
+	Driver string      `json:"driver"`
+	Config Config      `json:"config"`
+	Open   []LeakEvent `json:"open"`
+}
+
+type snapshot struct {
+	Time      time.Time          `json:"time"`
+	Detectors []detectorSnapshot `json:"detectors"`
+}
+
+func serveSnapshot(w http.ResponseWriter, r *http.Request, detectors []*Detector) {
+	s := snapshot{Time: time.Now(), Detectors: []detectorSnapshot{}}
+	for _, d := range detectors {
+		open := d.Outstanding()
+		if open == nil {
+			open = []LeakEvent{}
+		}
+		s.Detectors = append(s.Detectors, detectorSnapshot{Name: d.name, Driver: d.driverName, Config: d.Config(), Open: open})
+	}
+
+	if r.URL.Query().Get("format") == "html" || r.URL.Query().Get("format") == "" && strings.Contains(r.Header.Get("Accept"), "text/html") {
+		w.Header().Set("Content-Type", "text/html; charset=utf-8")
+		if err := snapshotTemplate.Execute(w, s); err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+		}
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	_ = enc.Encode(s)
+}
+
+var snapshotTemplate = template.Must(template.New("snapshot").Funcs(template.FuncMap{
+	"round": func(d time.Duration) time.Duration { return d.Round(time.Millisecond) },
+}).Parse(`<!DOCTYPE html>
+<html>
+<head>
+<title>sqleak</title>
+<style>
+body { font-family: sans-serif; }
+table { border-collapse: collapse; }
+th, td { border-bottom: 1px solid #ddd; padding: 4px 8px; text-align: left; vertical-align: top; }
+pre { margin: 0; }
+.leaked { color: #b00; }
+</style>
+</head>
+<body>
+<p>Snapshot of {{.Time.Format "2006-01-02 15:04:05 MST"}}</p>
+{{range .Detectors}}
+<h2>{{with .Name}}{{.}} ({{end}}{{.Driver}}{{with .Name}}){{end}}: {{len .Open}} open</h2>
+{{if .Open}}
+<table>
+<tr><th>Resource</th><th>Open for</th><th>Opened</th><th>Query</th><th>Stack</th></tr>
+{{range .Open}}
+<tr{{if .LeakID}} class="leaked"{{end}}>
+<td>{{.Kind}} #{{.ResourceID}}{{if .LeakID}}, leak #{{.LeakID}}{{end}}</td>
+<td>{{round .Age}}</td>
+<td>{{.OpenedAt.Format "15:04:05.000"}}{{if .Goroutine}} by goroutine {{.Goroutine}}{{end}}</td>
+<td><pre>{{.Query}}</pre></td>
+<td>{{if .Stack}}<details><summary>{{if .Frames}}{{(index .Frames 0).Function}}{{else}}stack{{end}}</summary><pre>{{.Stack}}</pre></details>{{end}}</td>
+</tr>
+{{end}}
+</table>
+{{end}}
+{{end}}
+</body>
+</html>
+`))
@@ -0,0 +1,59 @@
+//go:build !sqleak_noop
+
+package sqleak
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"slices"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestHandler(t *testing.T) {
+	mc, fc, _ := newTestConn(t, WithTimeout(time.Second), WithName("orders"))
+	rows, _ := mc.QueryContext(context.Background(), "SELECT <1>", nil)
+	defer rows.Close()
+	fc.Advance(2 * time.Second)
+
+	rec := httptest.NewRecorder()
+	mc.detector.Handler().ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/debug/sqleak", nil))
+
+	var s struct {
+		Detectors []struct {
+			Name   string
+			Config map[string]any
+			Open   []LeakEvent
+		}
+	}
+	if err := json.Unmarshal(rec.Body.Bytes(), &s); err != nil {
+		t.Fatalf("%v: %s", err, rec.Body)
+	}
+	if len(s.Detectors) != 1 || s.Detectors[0].Name != "orders" || s.Detectors[0].Config["timeout"] != "1s" {
+		t.Fatalf("unexpected snapshot %s", rec.Body)
+	}
+	open := s.Detectors[0].Open
+	if len(open) != 1 || open[0].Kind != KindRows || open[0].Query != "SELECT <1>" || open[0].Age != 2*time.Second || open[0].LeakID != 1 {
+		t.Errorf("unexpected open resources %+v", open)
+	}
+
+	rec = httptest.NewRecorder()
+	req := httptest.NewRequest(http.MethodGet, "/debug/sqleak", nil)
+	req.Header.Set("Accept", "text/html,application/xhtml+xml")
+	mc.detector.Handler().ServeHTTP(rec, req)
+	if body := rec.Body.String(); rec.Header().Get("Content-Type") != "text/html; charset=utf-8" ||
+		!strings.Contains(body, "orders (sqleak.fakeDriver): 1 open") || !strings.Contains(body, "Rows #1, leak #1") || !strings.Contains(body, "SELECT &lt;1&gt;") {
+		t.Errorf("unexpected HTML snapshot:\n%s", body)
+	}
+
+	if !slices.Contains(registeredDetectors(), mc.detector) {
+		t.Error("expected the detector to be registered for Handler")
+	}
+	mc.detector.stop()
+	if slices.Contains(registeredDetectors(), mc.detector) {
+		t.Error("expected the detector to be unregistered once stopped")
+	}
+}
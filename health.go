@@ -0,0 +1,91 @@
+package sqleak
+
+import (
+	"fmt"
+	"net/http"
+	"strings"
+)
+
+// HealthThresholds are the numbers of leaked resources still open above which Health reports the Detector as
+// unhealthy. Zero fields are not checked.
+type HealthThresholds struct {
+	// Total bounds the leaked resources of all kinds together.
+	Total int
+	// Rows, Stmt and Tx bound the leaked resources of one kind.
+	Rows, Stmt, Tx int
+}
+
+// WithHealthThresholds makes Health fail once more leaked resources than the thresholds allow are still open.
+// A resource counts as leaked once it has been reported as such, and stops counting when it is closed.
+func WithHealthThresholds(thresholds HealthThresholds) Option {
+	return func(ld *monitoredDriver) {
+		ld.health = thresholds
+	}
+}
+
+// HealthError is returned by Health when a threshold is exceeded.
+type HealthError struct {
+	// Name is the name set by WithName, if any.
+	Name string
+	// Leaked counts the leaked resources still open, by kind.
+	Leaked map[Kind]int
+	// Exceeded describes the exceeded thresholds.
+	Exceeded []string
+}
+
+func (e *HealthError) Error() string {
+	return fmt.Sprintf("sqleak: %sleaked resources still open: %s", namePrefix(e.Name), strings.Join(e.Exceeded, ", "))
+}
+
+// Health returns a *HealthError if more leaked resources are still open than allowed by WithHealthThresholds,
+// nil otherwise and without thresholds. Wire it into readiness probes to take instances with runaway leaks out of
+// rotation until the leaked resources are closed, or use HealthHandler.
+func (d *Detector) Health() error {
+	if noop {
+		return nil
+	}
+	t := d.health
+	if t == (HealthThresholds{}) {
+		return nil
+	}
+
+	leaked := make(map[Kind]int)
+	total := 0
+	for _, m := range d.openMonitors() {
+		if m.state.Load() == stateLeaked {
+			leaked[m.kind]++
+			total++
+		}
+	}
+
+	var exceeded []string
+	if t.Total > 0 && total > t.Total {
+		exceeded = append(exceeded, fmt.Sprintf("%d in total, more than %d", total, t.Total))
+	}
+	for _, c := range []struct {
+		kind  Kind
+		limit int
+	}{{KindRows, t.Rows}, {KindStmt, t.Stmt}, {KindTx, t.Tx}} {
+		if c.limit > 0 && leaked[c.kind] > c.limit {
+			exceeded = append(exceeded, fmt.Sprintf("%d %s, more than %d", leaked[c.kind], c.kind, c.limit))
+		}
+	}
+	if exceeded == nil {
+		return nil
+	}
+
+	return &HealthError{Name: d.name, Leaked: leaked, Exceeded: exceeded}
+}
+
+// HealthHandler responds to readiness probes with 200 OK while Health succeeds, and with 503 Service Unavailable and
+// the error otherwise.
+func (d *Detector) HealthHandler() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if err := d.Health(); err != nil {
+			http.Error(w, err.Error(), http.StatusServiceUnavailable)
+			return
+		}
+
+		_, _ = fmt.Fprintln(w, "ok")
+	})
+}
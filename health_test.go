@@ -0,0 +1,52 @@
+//go:build !sqleak_noop
+
+package sqleak
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestHealth(t *testing.T) {
+	mc, fc, _ := newTestConn(t, WithTimeout(time.Second), WithHealthThresholds(HealthThresholds{Rows: 1}))
+	d := mc.detector
+
+	first, _ := mc.QueryContext(context.Background(), "SELECT 1", nil)
+	second, _ := mc.QueryContext(context.Background(), "SELECT 2", nil)
+	if err := d.Health(); err != nil {
+		t.Errorf("expected resources that aren't leaked yet to be healthy, got %v", err)
+	}
+
+	fc.Advance(time.Second)
+	err := d.Health()
+	var he *HealthError
+	if !errors.As(err, &he) || he.Leaked[KindRows] != 2 {
+		t.Fatalf("expected a HealthError for 2 leaked Rows, got %v", err)
+	}
+	if want := "sqleak: leaked resources still open: 2 Rows, more than 1"; err.Error() != want {
+		t.Errorf("got error %q, want %q", err, want)
+	}
+
+	rec := httptest.NewRecorder()
+	d.HealthHandler().ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/ready", nil))
+	if rec.Code != http.StatusServiceUnavailable || !strings.Contains(rec.Body.String(), "2 Rows") {
+		t.Errorf("got %d %q, want 503 with the error", rec.Code, rec.Body.String())
+	}
+
+	first.Close()
+	if err := d.Health(); err != nil {
+		t.Errorf("expected closing a leaked resource to restore health, got %v", err)
+	}
+	second.Close()
+
+	rec = httptest.NewRecorder()
+	d.HealthHandler().ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/ready", nil))
+	if rec.Code != http.StatusOK {
+		t.Errorf("got %d, want 200", rec.Code)
+	}
+}
@@ -0,0 +1,169 @@
+package sqleak
+
+import (
+	"log"
+	"sync"
+	"time"
+)
+
+// holdWarnRatio is the share of the pool's connection time above which a saturation warning is logged.
+const holdWarnRatio = 0.8
+
+// WithConnHoldLimit enables tracking of the cumulative connection time held by open Rows and Tx.
+// At the end of every interval a warning is logged if the held time approaches maxOpenConns × interval,
+// i.e. the pool is saturated by long holds even if no single resource exceeds the leak timeout.
+// maxOpenConns should match the value passed to sql.DB.SetMaxOpenConns.
+// Tracking is off by default, values below 1 for either keep it off.
+func WithConnHoldLimit(maxOpenConns int, interval time.Duration) Option {
+	return func(ld *monitoredDriver) {
+		if maxOpenConns <= 0 || interval <= 0 {
+			return
+		}
+		ld.hold = &holdTracker{
+			maxOpenConns: maxOpenConns,
+			interval:     interval,
+		}
+	}
+}
+
+// HoldStats describes how much connection time monitored resources held.
+type HoldStats struct {
+	Interval time.Duration
+	// Capacity is MaxOpenConns × Interval, the connection time the pool can hand out per interval.
+	Capacity time.Duration
+	// Held is the connection time held by Rows and Tx during the last completed interval.
+	Held time.Duration
+	// Current is the connection time held so far during the interval in progress.
+	Current time.Duration
+}
+
+// Utilization returns Held as a fraction of Capacity.
+func (s HoldStats) Utilization() float64 {
+	if s.Capacity <= 0 {
+		return 0
+	}
+
+	return float64(s.Held) / float64(s.Capacity)
+}
+
+// HoldStats returns the connection hold time statistics, or the zero value if WithConnHoldLimit is not set.
+func (d *Detector) HoldStats() HoldStats {
+	if noop {
+		return HoldStats{}
+	}
+	if d.hold == nil {
+		return HoldStats{}
+	}
+
+	return d.hold.stats(d.clock.Now())
+}
+
+// holdTracker accounts held connection time in O(1) per open and close.
+// Resources still open at the end of an interval are carried over as if opened at the start of the next one.
+type holdTracker struct {
+	maxOpenConns int
+	interval     time.Duration
+
+	overhead  *overhead
+	logPrefix string
+
+	mu            sync.Mutex
+	clock         clock
+	timer         timer
+	stopped       bool
+	intervalStart time.Time
+	released      time.Duration // held during this interval by resources closed since
+	open          int
+	openOffset    time.Duration // sum of (effective open time - intervalStart) over open resources
+	last          time.Duration // held during the last completed interval
+}
+
+func (h *holdTracker) capacity() time.Duration {
+	return time.Duration(h.maxOpenConns) * h.interval
+}
+
+func (h *holdTracker) start(c clock) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	h.clock = c
+	h.intervalStart = c.Now()
+	h.timer = c.AfterFunc(h.interval, h.rollover)
+}
+
+func (h *holdTracker) stop() {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	h.stopped = true
+	if h.timer != nil {
+		h.timer.Stop()
+	}
+}
+
+// effectiveStart clamps the open time of a resource to the current interval.
+func (h *holdTracker) effectiveStart(openedAt time.Time) time.Time {
+	if openedAt.Before(h.intervalStart) {
+		return h.intervalStart
+	}
+
+	return openedAt
+}
+
+func (h *holdTracker) acquire(openedAt time.Time) {
+	h.overhead.lock(&h.mu)
+	defer h.mu.Unlock()
+
+	h.open++
+	h.openOffset += h.effectiveStart(openedAt).Sub(h.intervalStart)
+}
+
+func (h *holdTracker) release(openedAt, closedAt time.Time) {
+	h.overhead.lock(&h.mu)
+	defer h.mu.Unlock()
+
+	start := h.effectiveStart(openedAt)
+	h.released += closedAt.Sub(start)
+	h.open--
+	h.openOffset -= start.Sub(h.intervalStart)
+}
+
+func (h *holdTracker) heldLocked(now time.Time) time.Duration {
+	return h.released + time.Duration(h.open)*now.Sub(h.intervalStart) - h.openOffset
+}
+
+func (h *holdTracker) stats(now time.Time) HoldStats {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	return HoldStats{
+		Interval: h.interval,
+		Capacity: h.capacity(),
+		Held:     h.last,
+		Current:  h.heldLocked(now),
+	}
+}
+
+func (h *holdTracker) rollover() {
+	h.mu.Lock()
+	if h.stopped {
+		h.mu.Unlock()
+		return
+	}
+
+	now := h.clock.Now()
+	held := h.heldLocked(now)
+	open := h.open
+
+	h.last = held
+	h.intervalStart = now
+	h.released = 0
+	h.openOffset = 0
+	h.timer = h.clock.AfterFunc(h.interval, h.rollover)
+	h.mu.Unlock()
+
+	if capacity := h.capacity(); capacity > 0 && float64(held) >= holdWarnRatio*float64(capacity) {
+		log.Printf("%sconnection pool likely saturated by long-held resources: Rows and Tx held %s of %s connection time (MaxOpenConns=%d) within the last %s, %d still open",
+			h.logPrefix, held, capacity, h.maxOpenConns, h.interval, open)
+	}
+}
@@ -0,0 +1,69 @@
+package sqleak
+
+import "time"
+
+// Hooks observe the lifecycle of every resource opened through a Detector, leaked or not, e.g. to build
+// dashboards of in-flight Rows and transactions. Each hook is optional. Hooks are called synchronously by the
+// goroutine opening or closing the resource, or detecting the leak, and must not block.
+type Hooks struct {
+	// OnOpen is called for every opened resource, including those not monitored due to sampling.
+	OnOpen func(Resource)
+	// OnClose is called once a resource is closed, with how long it was open.
+	OnClose func(r Resource, openFor time.Duration)
+	// OnLeak is called for every leak event, like a callback of WithOnLeak.
+	OnLeak func(LeakEvent)
+}
+
+// Resource describes a resource passed to Hooks.
+type Resource struct {
+	Kind Kind
+	// ResourceID is the per-kind ID of the resource, see LeakEvent.ResourceID.
+	ResourceID uint64
+	// LeakID is the ID of the resource's leak, 0 unless it was reported as leaked.
+	LeakID uint64
+	// Name is the Detector's name set by WithName.
+	Name string
+	// Query is the query that opened Rows or prepared a Stmt, anonymized with WithQueryAnonymizer.
+	Query string
+	// Database is the name of the database the resource was opened on, if known.
+	Database string
+	OpenedAt time.Time
+	// RowsFetched is the number of rows read from Rows so far, the total once they are closed. It's 0 for Stmt and Tx.
+	RowsFetched int64
+	// Attrs are the attributes of the context the resource was opened with, see WithContextAttrs and Annotate.
+	Attrs map[string]string
+}
+
+// WithHooks adds hooks observing the opening, closing and leaking of every resource. It may be passed several times.
+func WithHooks(hooks Hooks) Option {
+	return func(ld *monitoredDriver) {
+		if hooks.OnOpen != nil {
+			ld.onOpen = append(ld.onOpen, hooks.OnOpen)
+		}
+		if hooks.OnClose != nil {
+			ld.onClose = append(ld.onClose, hooks.OnClose)
+		}
+		if hooks.OnLeak != nil {
+			ld.onLeak = append(ld.onLeak, hooks.OnLeak)
+		}
+	}
+}
+
+func (m *monitor) resource() Resource {
+	r := Resource{
+		Kind:       m.kind,
+		ResourceID: m.id,
+		LeakID:     m.leakID.Load(),
+		Name:       m.detector.name,
+		Query:      m.detector.anonymizeQuery(m.query),
+		OpenedAt:   m.openedAt,
+
+		RowsFetched: m.fetched.Load(),
+		Attrs:       m.attrs,
+	}
+	if m.database != nil {
+		r.Database = m.database.name
+	}
+
+	return r
+}
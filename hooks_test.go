@@ -0,0 +1,59 @@
+//go:build !sqleak_noop
+
+package sqleak
+
+import (
+	"context"
+	"database/sql/driver"
+	"testing"
+	"time"
+)
+
+func TestHooks(t *testing.T) {
+	var (
+		opened, closed []Resource
+		durations      []time.Duration
+		leaks          []LeakEvent
+	)
+	mc, fc, _ := newTestConn(t, WithTimeout(time.Second), WithHooks(Hooks{
+		OnOpen: func(r Resource) { opened = append(opened, r) },
+		OnClose: func(r Resource, openFor time.Duration) {
+			closed = append(closed, r)
+			durations = append(durations, openFor)
+		},
+		OnLeak: func(ev LeakEvent) { leaks = append(leaks, ev) },
+	}))
+	mc.database = parseDSN("file:orders.db")
+	ctx := context.Background()
+
+	tx, _ := mc.BeginTx(ctx, driver.TxOptions{})
+	rows, _ := mc.QueryContext(ctx, "SELECT 1", nil)
+	if len(opened) != 2 || opened[0].Kind != KindTx || opened[1].Kind != KindRows || opened[1].Query != "SELECT 1" || opened[1].Database != "orders.db" {
+		t.Fatalf("unexpected opens %+v", opened)
+	}
+
+	_ = rows.Next(make([]driver.Value, 1))
+	fc.Advance(500 * time.Millisecond)
+	_ = rows.Close()
+	fc.Advance(time.Second)
+	_ = tx.Commit()
+
+	if len(closed) != 2 || closed[0].Kind != KindRows || closed[1].Kind != KindTx {
+		t.Fatalf("unexpected closes %+v", closed)
+	}
+	if closed[0].RowsFetched != 1 || closed[1].RowsFetched != 0 {
+		t.Errorf("expected the rows fetched from the Rows, got %d and %d", closed[0].RowsFetched, closed[1].RowsFetched)
+	}
+	if durations[0] != 500*time.Millisecond || durations[1] != 1500*time.Millisecond {
+		t.Errorf("unexpected durations %v", durations)
+	}
+	if closed[0].LeakID != 0 || closed[1].LeakID != 1 {
+		t.Errorf("expected only the Tx to have leaked, got leak IDs %d and %d", closed[0].LeakID, closed[1].LeakID)
+	}
+	if len(leaks) != 2 || leaks[0].Type != EventLeak || leaks[1].Type != EventClosedLate {
+		t.Errorf("expected the Tx leak and its late close, got %+v", leaks)
+	}
+	if cfg := mc.detector.Config(); cfg.OnOpenHooks != 1 || cfg.OnCloseHooks != 1 || cfg.OnLeakCallbacks != 1 {
+		t.Errorf("unexpected hook counts in %+v", cfg)
+	}
+}
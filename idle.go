@@ -0,0 +1,59 @@
+package sqleak
+
+import "time"
+
+// WithIdleTimeout makes the leak timeout of the given kinds of resources, or of all kinds if none are given, count
+// from their last activity instead of from opening: a call to Next on Rows, an execution of a Stmt and any statement
+// run on the connection of a Tx, including those of its Stmts. A streaming query that keeps producing rows is never
+// reported, however long it runs, while Rows abandoned halfway through are reported once they were idle for the
+// timeout. Leak reports of such resources have their time since the last activity in LeakEvent.Idle.
+func WithIdleTimeout(kinds ...Kind) Option {
+	return func(ld *monitoredDriver) {
+		if len(kinds) == 0 {
+			kinds = []Kind{KindRows, KindStmt, KindTx}
+		}
+		for _, kind := range kinds {
+			ld.idleTimeout[kindIndex(kind)] = true
+		}
+	}
+}
+
+// idleTimed reports whether the timeout of the resource counts from its last activity, with WithIdleTimeout.
+func (m *monitor) idleTimed() bool {
+	return m.detector.idleTimeout[kindIndex(m.kind)]
+}
+
+// touch records activity on the resource, with WithIdleTimeout.
+func (m *monitor) touch() {
+	if m.idleTimed() {
+		m.active.Store(m.detector.clock.Now().UnixNano())
+	}
+}
+
+// touchTx records activity on the Tx in progress on the connection, if any.
+func (mc *monitoredConn) touchTx() {
+	if m := mc.tx.Load(); m != nil {
+		m.touch()
+	}
+}
+
+// idle returns the time since the last activity on the resource.
+func (m *monitor) idle() time.Duration {
+	return m.detector.clock.Now().Sub(time.Unix(0, m.active.Load()))
+}
+
+// expire reports the resource as leaked once its timeout elapsed, with WithIdleTimeout since its last activity,
+// checking again once it could have.
+func (m *monitor) expire() {
+	if m.idleTimed() {
+		if m.state.Load() == stateClosed {
+			return
+		}
+		if idle := m.idle(); idle < m.timeout {
+			m.detector.clock.AfterFunc(m.timeout-idle, m.expire)
+			return
+		}
+	}
+
+	m.fire(1)
+}
@@ -0,0 +1,51 @@
+//go:build !sqleak_noop
+
+package sqleak
+
+import (
+	"context"
+	"database/sql/driver"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestIdleTimeout(t *testing.T) {
+	var events []LeakEvent
+	mc, fc, logOutput := newTestConn(t,
+		WithTimeout(time.Second),
+		WithIdleTimeout(KindRows, KindTx),
+		WithOnLeak(func(ev LeakEvent) { events = append(events, ev) }),
+	)
+	ctx := context.Background()
+
+	streamed, _ := mc.QueryContext(ctx, "SELECT 1", nil)
+	defer streamed.Close()
+	abandoned, _ := mc.QueryContext(ctx, "SELECT 2", nil)
+	defer abandoned.Close()
+	tx, _ := mc.BeginTx(ctx, driver.TxOptions{})
+	defer tx.Rollback()
+	stmt, _ := mc.PrepareContext(ctx, "SELECT 3")
+	defer stmt.Close()
+
+	dest := make([]driver.Value, 1)
+	for range 4 {
+		fc.Advance(600 * time.Millisecond)
+		_ = streamed.Next(dest)
+		_, _ = mc.ExecContext(ctx, "UPDATE t SET x = 1", nil)
+		_, _ = stmt.(driver.StmtExecContext).ExecContext(ctx, nil)
+	}
+
+	if len(events) != 2 {
+		t.Fatalf("got %d events, want the abandoned Rows and the Stmt", len(events))
+	}
+	if ev := events[0]; ev.Query != "SELECT 2" || ev.Idle != 1200*time.Millisecond {
+		t.Errorf("unexpected event %+v", ev)
+	}
+	if ev := events[1]; ev.Kind != KindStmt || ev.Idle != 0 {
+		t.Errorf("expected the Stmt to time out after opening, got %+v", ev)
+	}
+	if want := "Rows not closed and idle for 1.2s, open for 1.2s"; !strings.Contains(logOutput.String(), want) {
+		t.Errorf("expected %q in log, got:\n%s", want, logOutput.String())
+	}
+}
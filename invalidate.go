@@ -0,0 +1,35 @@
+package sqleak
+
+import "database/sql/driver"
+
+var _ driver.Validator = (*monitoredConn)(nil)
+
+// WithInvalidateConn marks the connection of a leaked resource as bad once the leak is detected, so database/sql
+// discards it instead of reusing a connection in an unknown state once the resource is closed, auto-closed or
+// rolled back. Until the connection is returned to the pool, queries and executions on it fail with driver.ErrBadConn.
+func WithInvalidateConn() Option {
+	return func(ld *monitoredDriver) {
+		ld.invalidateConn = true
+	}
+}
+
+// invalidateConn marks the connection of the resource as bad, with WithInvalidateConn.
+func (m *monitor) invalidateConn() {
+	if m.conn != nil {
+		m.conn.invalid.Store(true)
+	}
+}
+
+// IsValid is called by database/sql before returning the connection to the pool.
+func (mc *monitoredConn) IsValid() bool {
+	mc.returned()
+	mc.checkManualTx()
+	if mc.invalid.Load() {
+		return false
+	}
+	if validator, ok := mc.Conn.(driver.Validator); ok {
+		return validator.IsValid()
+	}
+
+	return true
+}
@@ -0,0 +1,45 @@
+//go:build !sqleak_noop
+
+package sqleak
+
+import (
+	"database/sql"
+	"io"
+	"log"
+	"os"
+	"testing"
+	"time"
+)
+
+func TestInvalidateConn(t *testing.T) {
+	log.SetOutput(io.Discard)
+	t.Cleanup(func() { log.SetOutput(os.Stderr) })
+
+	for _, invalidate := range []bool{false, true} {
+		fc := newFakeClock()
+		opts := []Option{withClock(fc), WithTimeout(time.Second)}
+		if invalidate {
+			opts = append(opts, WithInvalidateConn())
+		}
+		d := WrapDriver(fakeDriver{}, opts...)
+		connector := &countingConnector{driver: d}
+		db := sql.OpenDB(connector)
+		db.SetMaxOpenConns(1)
+
+		rows, err := db.Query("SELECT 1")
+		if err != nil {
+			t.Fatal(err)
+		}
+		fc.Advance(time.Second)
+		rows.Close()
+
+		if err := db.QueryRow("SELECT 1").Scan(new(int)); err != nil {
+			t.Fatal(err)
+		}
+		if want := map[bool]int{false: 1, true: 2}[invalidate]; connector.opens != want {
+			t.Errorf("with invalidation %v: opened %d connections, want %d", invalidate, connector.opens, want)
+		}
+		db.Close()
+		d.(*monitoredDriver).stop()
+	}
+}
@@ -0,0 +1,99 @@
+//go:build linux
+
+package sqleak
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"net"
+	"strconv"
+	"strings"
+	"syscall"
+)
+
+// journalSocket is where the systemd journal receives entries in its native protocol.
+var journalSocket = "/run/systemd/journal/socket"
+
+// JournalSink is a Sink sending every report to the systemd journal, at the priority of its severity like
+// SyslogSink. The message includes the stack, the event details are attached as SQLEAK_* fields and the
+// opening function as CODE_FUNC, CODE_FILE and CODE_LINE, e.g. for journalctl SQLEAK_KIND=Rows.
+type JournalSink struct {
+	identifier string
+	conn       *net.UnixConn
+}
+
+// NewJournalSink connects to the journal, failing if it isn't running. Entries are tagged with the
+// SYSLOG_IDENTIFIER identifier.
+func NewJournalSink(identifier string) (*JournalSink, error) {
+	conn, err := net.DialUnix("unixgram", nil, &net.UnixAddr{Name: journalSocket, Net: "unixgram"})
+	if err != nil {
+		return nil, fmt.Errorf("sqleak: connecting to the journal: %w", err)
+	}
+
+	return &JournalSink{identifier: identifier, conn: conn}, nil
+}
+
+// WriteEvent sends ev as a journal entry. Entries too large for a datagram are sent without the stack.
+func (s *JournalSink) WriteEvent(ev LeakEvent) error {
+	_, err := s.conn.Write(s.entry(ev, ev.Text()))
+	if errors.Is(err, syscall.EMSGSIZE) {
+		_, err = s.conn.Write(s.entry(ev, ev.singleLine()))
+	}
+	if err != nil {
+		return fmt.Errorf("sqleak: writing to the journal: %w", err)
+	}
+
+	return nil
+}
+
+// Close closes the connection to the journal.
+func (s *JournalSink) Close() error {
+	return s.conn.Close()
+}
+
+func (s *JournalSink) entry(ev LeakEvent, message string) []byte {
+	var b bytes.Buffer
+	field := func(key, value string) {
+		if value == "" {
+			return
+		}
+		if !strings.Contains(value, "\n") {
+			b.WriteString(key + "=" + value + "\n")
+			return
+		}
+
+		// Multi-line values are length-prefixed instead.
+		b.WriteString(key + "\n")
+		_ = binary.Write(&b, binary.LittleEndian, uint64(len(value)))
+		b.WriteString(value + "\n")
+	}
+	id := func(id uint64) string {
+		if id == 0 {
+			return ""
+		}
+		return strconv.FormatUint(id, 10)
+	}
+
+	field("MESSAGE", message)
+	field("PRIORITY", strconv.Itoa(ev.priority()))
+	field("SYSLOG_IDENTIFIER", s.identifier)
+	if len(ev.Frames) > 0 {
+		f := ev.Frames[0]
+		field("CODE_FUNC", f.Function)
+		field("CODE_FILE", f.File)
+		field("CODE_LINE", strconv.Itoa(f.Line))
+	}
+	field("SQLEAK_TYPE", string(ev.Type))
+	field("SQLEAK_NAME", ev.Name)
+	field("SQLEAK_KIND", string(ev.Kind))
+	field("SQLEAK_SEVERITY", string(ev.Severity))
+	field("SQLEAK_LEAK_ID", id(ev.LeakID))
+	field("SQLEAK_RESOURCE_ID", id(ev.ResourceID))
+	field("SQLEAK_QUERY", ev.Query)
+	field("SQLEAK_QUERY_FINGERPRINT", ev.QueryFingerprint)
+	field("SQLEAK_DATABASE", ev.Database)
+
+	return b.Bytes()
+}
@@ -0,0 +1,57 @@
+//go:build linux && !sqleak_noop
+
+package sqleak
+
+import (
+	"encoding/binary"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestJournalSink(t *testing.T) {
+	path, receive := listenUnixgram(t)
+	defer func(socket string) { journalSocket = socket }(journalSocket)
+	journalSocket = path
+
+	sink, err := NewJournalSink("sqleak-test")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer sink.Close()
+
+	stack := "goroutine 1 [running]:\nmain.run()\n\tmain.go:7 +0x1d\n"
+	ev := LeakEvent{
+		Type:     EventLeak,
+		LeakID:   3,
+		Kind:     KindRows,
+		Query:    "SELECT 1",
+		Severity: SeverityError,
+		Timeout:  time.Second,
+		Stack:    stack,
+		Frames:   []Frame{{Function: "main.run", File: "main.go", Line: 7}},
+	}
+	if err = sink.WriteEvent(ev); err != nil {
+		t.Fatal(err)
+	}
+
+	entry := receive()
+	for _, want := range []string{
+		"PRIORITY=3\n", "SYSLOG_IDENTIFIER=sqleak-test\n", "CODE_FUNC=main.run\n", "CODE_LINE=7\n",
+		"SQLEAK_KIND=Rows\n", "SQLEAK_LEAK_ID=3\n", "SQLEAK_QUERY=SELECT 1\n",
+	} {
+		if !strings.Contains(entry, want) {
+			t.Errorf("expected %q in entry:\n%s", want, entry)
+		}
+	}
+
+	// The multi-line message is length-prefixed.
+	_, rest, ok := strings.Cut(entry, "MESSAGE\n")
+	if !ok || len(rest) < 8 {
+		t.Fatalf("expected a binary MESSAGE field in entry:\n%s", entry)
+	}
+	n := binary.LittleEndian.Uint64([]byte(rest[:8]))
+	if message := rest[8:][:n]; message != ev.Text() || !strings.HasSuffix(message, stack) {
+		t.Errorf("unexpected message %q", message)
+	}
+}
@@ -0,0 +1,340 @@
+package sqleak
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log"
+	"sync"
+	"time"
+)
+
+// jsonWriteMu serializes JSON lines and other reports written directly to the standard logger's writer.
+var jsonWriteMu sync.Mutex
+
+// WithJSONOutput writes every leak report as a single line JSON object instead of a multi-line log message,
+// with the stack as an array of frames. Lines are written to the standard logger's writer, without its prefix.
+func WithJSONOutput() Option {
+	return func(ld *monitoredDriver) {
+		ld.jsonOutput = true
+	}
+}
+
+// LeakEventSchemaVersion is the version of the JSON encoding of LeakEvent.
+//
+// The schema only evolves additively: new fields may be added and the version incremented,
+// but existing fields never change their name, type or meaning. Consumers should decode with
+// DecodeLeakEvent or ReadLeakEvents, which accept events of every schema version.
+const LeakEventSchemaVersion = 24
+
+// leakEventJSON is the serialized form of LeakEvent.
+type leakEventJSON struct {
+	SchemaVersion    int       `json:"schema_version"`
+	Message          string    `json:"msg,omitempty"`
+	Type             EventType `json:"type"`
+	Name             string    `json:"name,omitempty"`
+	LeakID           uint64    `json:"leak_id"`
+	Kind             Kind      `json:"kind"`
+	ResourceID       uint64    `json:"resource_id,omitempty"`
+	Query            string    `json:"query,omitempty"`
+	QueryFingerprint string    `json:"query_fingerprint,omitempty"`
+	Args             []string  `json:"args,omitempty"`
+	Columns          []Column  `json:"columns,omitempty"`
+	RowsFetched      int64     `json:"rows_fetched,omitempty"`
+	Executions       int64     `json:"executions,omitempty"`
+	Cost             CostClass `json:"cost_class,omitempty"`
+	Severity         Severity  `json:"severity,omitempty"`
+	OpenedAt         time.Time `json:"opened_at"`
+	Goroutine        uint64    `json:"goroutine,omitempty"`
+	TraceID          string    `json:"trace_id,omitempty"`
+	SpanID           string    `json:"span_id,omitempty"`
+	Timeout          string    `json:"timeout"`
+	Age              string    `json:"age"`
+	Idle             string    `json:"idle,omitempty"`
+	Error            string    `json:"error,omitempty"`
+	Occurrence       int       `json:"occurrence"`
+	Frames           []Frame   `json:"frames"`
+	Truncated        bool      `json:"stack_truncated,omitempty"`
+	CloseFrames      []Frame   `json:"close_frames,omitempty"`
+	CloseGoroutine   uint64    `json:"close_goroutine,omitempty"`
+	OpenerFrames     []Frame   `json:"opener_frames,omitempty"`
+	OpenerState      string    `json:"opener_state,omitempty"`
+	GoroutineDump    string    `json:"goroutine_dump,omitempty"`
+	Driver           string    `json:"driver,omitempty"`
+	Database         string    `json:"database,omitempty"`
+	DSN              string    `json:"dsn,omitempty"`
+	Pool             *poolJSON `json:"pool,omitempty"`
+	Owner            string    `json:"owner,omitempty"`
+	SiteCount        int       `json:"site_count,omitempty"`
+	Explanation      string    `json:"explanation,omitempty"`
+
+	Attrs map[string]string `json:"attrs,omitempty"`
+}
+
+func (ev LeakEvent) toJSON() leakEventJSON {
+	frames := ev.Frames
+	if frames == nil {
+		frames = []Frame{}
+	}
+	var idle string
+	if ev.Idle != 0 {
+		idle = ev.Idle.String()
+	}
+
+	return leakEventJSON{
+		SchemaVersion:    LeakEventSchemaVersion,
+		Type:             ev.Type,
+		Name:             ev.Name,
+		LeakID:           ev.LeakID,
+		Kind:             ev.Kind,
+		ResourceID:       ev.ResourceID,
+		Query:            ev.Query,
+		QueryFingerprint: ev.QueryFingerprint,
+		Args:             ev.Args,
+		Columns:          ev.Columns,
+		RowsFetched:      ev.RowsFetched,
+		Executions:       ev.Executions,
+		Cost:             ev.Cost,
+		Severity:         ev.Severity,
+		OpenedAt:         ev.OpenedAt,
+		Goroutine:        ev.Goroutine,
+		TraceID:          ev.TraceID,
+		SpanID:           ev.SpanID,
+		Timeout:          ev.Timeout.String(),
+		Age:              ev.Age.String(),
+		Idle:             idle,
+		Error:            ev.Error,
+		Occurrence:       ev.Occurrence,
+		Frames:           frames,
+		Truncated:        ev.StackTruncated,
+		CloseFrames:      ev.CloseFrames,
+		CloseGoroutine:   ev.CloseGoroutine,
+		OpenerFrames:     ev.OpenerFrames,
+		OpenerState:      ev.OpenerState,
+		GoroutineDump:    ev.GoroutineDump,
+		Driver:           ev.Driver,
+		Database:         ev.Database,
+		DSN:              ev.DSN,
+		Pool:             ev.Pool.toJSON(),
+		Owner:            ev.Owner,
+		SiteCount:        ev.SiteCount,
+		Attrs:            ev.Attrs,
+	}
+}
+
+// MarshalJSON encodes the event with the timeout as a Go duration string and the stack as frames.
+func (ev LeakEvent) MarshalJSON() ([]byte, error) {
+	return json.Marshal(ev.toJSON())
+}
+
+// UnmarshalJSON decodes an event of any schema version, see DecodeLeakEvent.
+func (ev *LeakEvent) UnmarshalJSON(data []byte) error {
+	decoded, err := DecodeLeakEvent(data)
+	if err != nil {
+		return err
+	}
+
+	*ev = decoded
+	return nil
+}
+
+// leakEventDecodeJSON mirrors leakEventJSON with lenient field types for decoding.
+type leakEventDecodeJSON struct {
+	SchemaVersion    int             `json:"schema_version"`
+	Type             EventType       `json:"type"`
+	Name             string          `json:"name"`
+	LeakID           uint64          `json:"leak_id"`
+	Kind             Kind            `json:"kind"`
+	ResourceID       uint64          `json:"resource_id"`
+	Query            string          `json:"query"`
+	QueryFingerprint string          `json:"query_fingerprint"`
+	Args             []string        `json:"args"`
+	Columns          []Column        `json:"columns"`
+	RowsFetched      int64           `json:"rows_fetched"`
+	Executions       int64           `json:"executions"`
+	Cost             CostClass       `json:"cost_class"`
+	Severity         Severity        `json:"severity"`
+	OpenedAt         time.Time       `json:"opened_at"`
+	Goroutine        uint64          `json:"goroutine"`
+	TraceID          string          `json:"trace_id"`
+	SpanID           string          `json:"span_id"`
+	Timeout          json.RawMessage `json:"timeout"`
+	Age              json.RawMessage `json:"age"`
+	Idle             json.RawMessage `json:"idle"`
+	Error            string          `json:"error"`
+	Occurrence       int             `json:"occurrence"`
+	Frames           []Frame         `json:"frames"`
+	Truncated        bool            `json:"stack_truncated"`
+	CloseFrames      []Frame         `json:"close_frames"`
+	CloseGoroutine   uint64          `json:"close_goroutine"`
+	OpenerFrames     []Frame         `json:"opener_frames"`
+	OpenerState      string          `json:"opener_state"`
+	GoroutineDump    string          `json:"goroutine_dump"`
+	Driver           string          `json:"driver"`
+	Database         string          `json:"database"`
+	DSN              string          `json:"dsn"`
+	Pool             *poolDecodeJSON `json:"pool"`
+	Owner            string          `json:"owner"`
+	SiteCount        int             `json:"site_count"`
+
+	Attrs map[string]string `json:"attrs"`
+}
+
+// DecodeLeakEvent decodes a JSON encoded LeakEvent of any schema version.
+// Unknown fields, e.g. from a newer version of sqleak, are ignored and missing fields keep their zero value.
+// Timeout is accepted both as a Go duration string and as nanoseconds.
+// Stack is not part of the encoding and stays empty, Frames carries the stack instead.
+func DecodeLeakEvent(data []byte) (LeakEvent, error) {
+	var v leakEventDecodeJSON
+	if err := json.Unmarshal(data, &v); err != nil {
+		return LeakEvent{}, err
+	}
+
+	timeout, err := decodeDuration(v.Timeout)
+	if err != nil {
+		return LeakEvent{}, fmt.Errorf("sqleak: invalid timeout: %w", err)
+	}
+	age, err := decodeDuration(v.Age)
+	if err != nil {
+		return LeakEvent{}, fmt.Errorf("sqleak: invalid age: %w", err)
+	}
+	idle, err := decodeDuration(v.Idle)
+	if err != nil {
+		return LeakEvent{}, fmt.Errorf("sqleak: invalid idle: %w", err)
+	}
+
+	pool, err := v.Pool.decode()
+	if err != nil {
+		return LeakEvent{}, err
+	}
+
+	if v.Type == "" {
+		v.Type = EventLeak // schema versions before 3 only had leak reports
+	}
+
+	return LeakEvent{
+		SchemaVersion:    v.SchemaVersion,
+		Type:             v.Type,
+		Name:             v.Name,
+		LeakID:           v.LeakID,
+		Kind:             v.Kind,
+		ResourceID:       v.ResourceID,
+		Query:            v.Query,
+		QueryFingerprint: v.QueryFingerprint,
+		Args:             v.Args,
+		Columns:          v.Columns,
+		RowsFetched:      v.RowsFetched,
+		Executions:       v.Executions,
+		Cost:             v.Cost,
+		Severity:         v.Severity,
+		OpenedAt:         v.OpenedAt,
+		Goroutine:        v.Goroutine,
+		TraceID:          v.TraceID,
+		SpanID:           v.SpanID,
+		Timeout:          timeout,
+		Age:              age,
+		Idle:             idle,
+		Error:            v.Error,
+		Occurrence:       v.Occurrence,
+		Frames:           v.Frames,
+		StackTruncated:   v.Truncated,
+		CloseFrames:      v.CloseFrames,
+		CloseGoroutine:   v.CloseGoroutine,
+		OpenerFrames:     v.OpenerFrames,
+		OpenerState:      v.OpenerState,
+		GoroutineDump:    v.GoroutineDump,
+		Driver:           v.Driver,
+		Database:         v.Database,
+		DSN:              v.DSN,
+		Pool:             pool,
+		Owner:            v.Owner,
+		SiteCount:        v.SiteCount,
+		Attrs:            v.Attrs,
+	}, nil
+}
+
+func decodeDuration(raw json.RawMessage) (time.Duration, error) {
+	if len(raw) == 0 || string(raw) == "null" {
+		return 0, nil
+	}
+
+	var s string
+	if err := json.Unmarshal(raw, &s); err == nil {
+		return time.ParseDuration(s)
+	}
+
+	var ns int64
+	if err := json.Unmarshal(raw, &ns); err != nil {
+		return 0, err
+	}
+
+	return time.Duration(ns), nil
+}
+
+// ReadLeakEvents decodes a stream of JSON lines as written by WithJSONOutput and calls fn for every leak event.
+// Lines that are not JSON objects with a "kind" field, such as regular log messages interleaved in the same stream,
+// are skipped. A line that looks like a leak event but fails to decode, e.g. because of a truncated write, is
+// returned as an error with its line number. Decoding stops at the first such error or the first error returned by
+// fn or the reader.
+func ReadLeakEvents(r io.Reader, fn func(LeakEvent) error) error {
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 0, 64*1024), 16*1024*1024)
+
+	for n := 1; scanner.Scan(); n++ {
+		line := bytes.TrimSpace(scanner.Bytes())
+		if len(line) == 0 || line[0] != '{' {
+			continue
+		}
+
+		var probe struct {
+			Kind Kind `json:"kind"`
+		}
+		if err := json.Unmarshal(line, &probe); err != nil {
+			if !bytes.Contains(line, []byte(`"kind":`)) {
+				continue
+			}
+		} else if probe.Kind == "" {
+			continue
+		}
+
+		ev, err := DecodeLeakEvent(line)
+		if err != nil {
+			return fmt.Errorf("sqleak: line %d: %w", n, err)
+		}
+		if err = fn(ev); err != nil {
+			return err
+		}
+	}
+
+	return scanner.Err()
+}
+
+// jsonLine encodes the event as a line of WithJSONOutput, with the explanation of WithVerbose if explain is set.
+func (ev LeakEvent) jsonLine(explain bool) ([]byte, error) {
+	v := ev.toJSON()
+	v.Message = ev.message()
+	if explain {
+		v.Explanation = Explain(ev)
+	}
+
+	b, err := json.Marshal(v)
+	if err != nil {
+		return nil, err
+	}
+
+	return append(b, '\n'), nil
+}
+
+func (d *Detector) reportJSON(ev LeakEvent) {
+	b, err := ev.jsonLine(d.verbose)
+	if err != nil {
+		log.Printf("sqleak: failed to encode leak report: %v", err)
+		return
+	}
+
+	jsonWriteMu.Lock()
+	defer jsonWriteMu.Unlock()
+	_, _ = log.Writer().Write(b)
+}
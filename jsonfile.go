@@ -0,0 +1,130 @@
+package sqleak
+
+import (
+	"fmt"
+	"os"
+	"sync"
+)
+
+// JSONFileSink is a Sink appending leak events as JSON lines to a dedicated file, encoded like WithJSONOutput and
+// readable with ReadLeakEvents. It is safe for concurrent use by several Detectors.
+type JSONFileSink struct {
+	path string
+
+	mu      sync.Mutex
+	file    *os.File
+	size    int64
+	maxSize int64
+	rotate  func(path string) error
+	closed  bool
+}
+
+// OpenJSONFile opens the file at path for appending leak events, creating it if needed.
+func OpenJSONFile(path string) (*JSONFileSink, error) {
+	s := &JSONFileSink{path: path}
+	if err := s.open(); err != nil {
+		return nil, err
+	}
+
+	return s, nil
+}
+
+func (s *JSONFileSink) open() error {
+	f, err := os.OpenFile(s.path, os.O_WRONLY|os.O_APPEND|os.O_CREATE, 0o644)
+	if err != nil {
+		return fmt.Errorf("sqleak: opening leak log: %w", err)
+	}
+
+	info, err := f.Stat()
+	if err != nil {
+		_ = f.Close()
+		return fmt.Errorf("sqleak: opening leak log: %w", err)
+	}
+
+	s.file, s.size = f, info.Size()
+	return nil
+}
+
+// RotateAt calls rotate with the path of the file once a write made it exceed maxSize bytes, after closing it,
+// and continues in a new file at the same path. rotate typically renames or compresses the full file.
+// A rotate error is returned from that write, the sink then appends to whatever file is at path.
+func (s *JSONFileSink) RotateAt(maxSize int64, rotate func(path string) error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.maxSize, s.rotate = maxSize, rotate
+}
+
+// Reopen closes the file and opens the one at its path, e.g. after an external tool like logrotate moved it away.
+func (s *JSONFileSink) Reopen() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.closed {
+		return fmt.Errorf("sqleak: reopening leak log: %w", os.ErrClosed)
+	}
+
+	if s.file != nil {
+		_ = s.file.Close()
+		s.file = nil
+	}
+
+	return s.open()
+}
+
+// WriteEvent appends ev as a single line.
+func (s *JSONFileSink) WriteEvent(ev LeakEvent) error {
+	b, err := ev.jsonLine(false)
+	if err != nil {
+		return err
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.closed {
+		return fmt.Errorf("sqleak: writing leak log: %w", os.ErrClosed)
+	}
+	if s.file == nil {
+		if err = s.open(); err != nil {
+			return err
+		}
+	}
+
+	n, err := s.file.Write(b)
+	s.size += int64(n)
+	if err != nil {
+		return fmt.Errorf("sqleak: writing leak log: %w", err)
+	}
+
+	if s.rotate == nil || s.size <= s.maxSize {
+		return nil
+	}
+
+	_ = s.file.Close()
+	s.file = nil
+	rotateErr := s.rotate(s.path)
+	if err = s.open(); err != nil {
+		return err
+	}
+	if rotateErr != nil {
+		return fmt.Errorf("sqleak: rotating leak log: %w", rotateErr)
+	}
+
+	return nil
+}
+
+// Close closes the file, events written afterwards fail.
+func (s *JSONFileSink) Close() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.closed = true
+	if s.file == nil {
+		return nil
+	}
+
+	err := s.file.Close()
+	s.file = nil
+	return err
+}
@@ -0,0 +1,111 @@
+//go:build !sqleak_noop
+
+package sqleak
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func readJSONFile(t *testing.T, path string) []LeakEvent {
+	t.Helper()
+
+	f, err := os.Open(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+
+	var events []LeakEvent
+	if err = ReadLeakEvents(f, func(ev LeakEvent) error {
+		events = append(events, ev)
+		return nil
+	}); err != nil {
+		t.Fatal(err)
+	}
+
+	return events
+}
+
+func TestJSONFileSink(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "leaks.jsonl")
+	sink, err := OpenJSONFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer sink.Close()
+
+	var rotated []string
+	sink.RotateAt(1, func(path string) error {
+		rotated = append(rotated, path+".1")
+		return os.Rename(path, path+".1")
+	})
+
+	mc, fc, logOutput := newTestConn(t, WithTimeout(time.Second), WithSink(sink), WithSingleLineLog())
+
+	rows, _ := mc.QueryContext(context.Background(), "SELECT 1", nil)
+	fc.Advance(time.Second)
+	_ = rows.Close()
+
+	// Every line exceeds the limit and is rotated away right after, the late close replacing the leak.
+	if len(rotated) != 2 {
+		t.Fatalf("expected 2 rotations, got %v", rotated)
+	}
+	events := readJSONFile(t, path+".1")
+	if len(events) != 1 || events[0].Type != EventClosedLate {
+		t.Fatalf("expected the late close in the rotated file, got %+v", events)
+	}
+
+	sink.RotateAt(0, nil)
+	rows, _ = mc.QueryContext(context.Background(), "SELECT 2", nil)
+	defer rows.Close()
+	fc.Advance(time.Second)
+
+	events = readJSONFile(t, path)
+	if len(events) != 1 || events[0].Query != "SELECT 2" || len(events[0].Frames) == 0 {
+		t.Fatalf("expected the second leak with its stack, got %+v", events)
+	}
+
+	if out := logOutput.String(); strings.Contains(out, "goroutine ") && strings.Contains(out, "[running]") {
+		t.Errorf("expected no stacks in the log, got:\n%s", out)
+	}
+
+	if err = sink.Close(); err != nil {
+		t.Fatal(err)
+	}
+	if err = sink.WriteEvent(events[0]); !errors.Is(err, os.ErrClosed) {
+		t.Errorf("expected a closed error after Close, got %v", err)
+	}
+}
+
+func TestJSONFileSinkReopen(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "leaks.jsonl")
+	sink, err := OpenJSONFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer sink.Close()
+
+	ev := LeakEvent{Type: EventLeak, Kind: KindRows, Timeout: time.Second}
+	if err = sink.WriteEvent(ev); err != nil {
+		t.Fatal(err)
+	}
+	if err = os.Rename(path, path+".old"); err != nil {
+		t.Fatal(err)
+	}
+	if err = sink.Reopen(); err != nil {
+		t.Fatal(err)
+	}
+	if err = sink.WriteEvent(ev); err != nil {
+		t.Fatal(err)
+	}
+
+	if n, m := len(readJSONFile(t, path+".old")), len(readJSONFile(t, path)); n != 1 || m != 1 {
+		t.Errorf("expected one event per file, got %d and %d", n, m)
+	}
+}
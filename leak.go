@@ -0,0 +1,477 @@
+package sqleak
+
+import (
+	"fmt"
+	"log"
+	"strings"
+	"sync/atomic"
+	"time"
+	"unicode/utf8"
+)
+
+// Kind identifies the type of a monitored resource.
+type Kind string
+
+const (
+	KindRows Kind = "Rows"
+	KindStmt Kind = "Stmt"
+	KindTx   Kind = "Tx"
+)
+
+// EventType distinguishes the reports emitted about a leaked resource.
+type EventType string
+
+const (
+	// EventLeak reports a resource that was not closed within the timeout.
+	EventLeak EventType = "leak"
+	// EventClosedLate follows up on an EventLeak once the resource is eventually closed.
+	// Its Age is the total time the resource was held, and its LeakID matches the original report.
+	EventClosedLate EventType = "closed_late"
+	// EventOutstanding reports a resource that was still open when Detector.ReportOutstanding was called.
+	// Its LeakID is only set if the resource was reported by an EventLeak before.
+	EventOutstanding EventType = "outstanding"
+	// EventForcedRollback reports a Tx rolled back at the deadline of WithAutoRollback. Its Age is the time the Tx
+	// was open, and its LeakID matches the EventLeak reported before, if any.
+	EventForcedRollback EventType = "forced_rollback"
+	// EventLongTx reports a Tx still open at the threshold of WithLongTxThreshold. Its Age is the time the Tx
+	// has been open, and its LeakID matches the EventLeak reported before, if any.
+	EventLongTx EventType = "long_tx"
+	// EventStalled reports Rows on which Next was not called for the idle period of WithStallTimeout.
+	// Its Idle is the time since the last call to Next, or since the Rows were opened if Next was never called.
+	EventStalled EventType = "stalled"
+	// EventCloseFailed reports a resource whose Close, Commit or Rollback failed, with CloseErrorReport.
+	// Its Error is the failed operation and its error, e.g. "Rollback: driver: bad connection".
+	EventCloseFailed EventType = "close_failed"
+)
+
+// LeakEvent describes a resource that was not closed within the configured timeout.
+type LeakEvent struct {
+	Type EventType
+	// Name is the name of the wrapped driver set by WithName, if any.
+	Name string
+	// LeakID identifies the leak, it links the follow-up reports of a resource to its first EventLeak.
+	LeakID uint64
+	Kind   Kind
+	// ResourceID numbers the resources of a Kind in the order they were opened, starting at 1 per Detector.
+	// Unlike LeakID it's assigned to every resource, sampled or not.
+	ResourceID uint64
+	// Query is the SQL text that produced the resource, empty for Tx.
+	// It is anonymized if WithQueryAnonymizer is set.
+	Query string
+	// QueryFingerprint fingerprints the statement shape of Query, see NormalizeQuery, for grouping leaks by statement
+	// and as a metrics label. It's computed before anonymization, but from the normalized query without literals.
+	QueryFingerprint string
+	// Args are the bound parameters of the query that produced Rows as rendered by the WithCaptureArgs redactor.
+	Args []string
+	// Columns describes the result set of Rows if WithColumnMetadata is set.
+	Columns []Column
+	// RowsFetched is the number of rows read from Rows so far.
+	RowsFetched int64
+	// Executions is the number of times a Stmt was executed so far, 0 for Rows and Tx.
+	Executions int64
+	// Cost estimates the server-side cost of leaked Rows from the driver and the fetch progress,
+	// Severity ranks the event accordingly, or by how long the resource is open with WithSeverityEscalation.
+	Cost     CostClass
+	Severity Severity
+	// OpenedAt is the wall-clock time the resource was opened, and Goroutine the ID of the goroutine opening it,
+	// for correlation with request logs and traces. Goroutine is 0 for resources that were not sampled.
+	OpenedAt  time.Time
+	Goroutine uint64
+	// TraceID and SpanID identify the trace of the context the resource was opened with, see WithTraceExtractor.
+	TraceID, SpanID string
+	Timeout         time.Duration
+	// Attrs are the attributes of the context the resource was opened with, see WithContextAttrs.
+	Attrs map[string]string
+	// Age is how long the resource had been open when the event was reported.
+	Age time.Duration
+	// Idle is the time since Next was last called on the Rows of an EventStalled, or the time since the last activity
+	// on a resource reported as EventLeak with WithIdleTimeout, zero for other events.
+	Idle time.Duration
+	// Error is the failed operation and its error of an EventCloseFailed, empty for other events.
+	Error string
+	// Occurrence counts the reports for this resource, starting at 1. It only exceeds 1 with WithRepeatInterval
+	// or WithSeverityEscalation.
+	Occurrence int
+	// Stack is the stack trace of the goroutine that opened the resource.
+	// It is empty with WithCallerOnly, and for resources that were not sampled.
+	Stack string
+	// StackTruncated is set if the stack didn't fit the stack buffer, see WithStackBufferSize.
+	StackTruncated bool
+	// Frames is Stack parsed into individual frames, innermost call first. With WithCallerOnly it holds the caller only.
+	Frames []Frame
+	// CloseStack is the stack of the goroutine that closed the resource of an EventClosedLate, with WithCloseStacks.
+	// CloseFrames is CloseStack parsed, and CloseGoroutine the ID of the closing goroutine.
+	CloseStack     string
+	CloseFrames    []Frame
+	CloseGoroutine uint64
+	// OpenerStack is the stack of the goroutine that opened the resource at the time of an EventLeak,
+	// with WithOpenerStack. OpenerFrames is OpenerStack parsed, and OpenerState the goroutine's status,
+	// e.g. "chan receive", or "exited" if it no longer exists.
+	OpenerStack  string
+	OpenerFrames []Frame
+	OpenerState  string
+	// GoroutineDump holds the stacks of all goroutines at the time of an EventLeak, with WithFullDumpOnLeak.
+	// It's only set on the first leak reported within each minute.
+	GoroutineDump string
+	// Driver is the name the wrapped driver was opened with, or its type if it was wrapped directly.
+	Driver string
+	// Database is the name of the database the resource was opened on, as parsed from the data source name,
+	// and DSN the data source name with passwords and other credentials scrubbed. Both are empty if unknown.
+	Database string
+	DSN      string
+	// Pool holds the stats of the connection pool when the leak was reported, with WithPoolStats.
+	Pool *PoolStats
+	// Owner is the owner of the opening call site as determined by WithOwnerResolver, if set.
+	Owner string
+	// SiteCount is the number of leaks of the call site so far if WithDeduplication is set, including this one.
+	SiteCount int
+	// SchemaVersion is the schema version an event was decoded from, see LeakEventSchemaVersion.
+	// It is zero for events that were not decoded from JSON.
+	SchemaVersion int
+
+	quiet     bool // not logged, see WithDeduplication
+	escalated bool // Severity set by WithSeverityEscalation
+}
+
+// WithOwnerResolver sets a function mapping stack frames to owners, e.g. teams from a CODEOWNERS-style mapping.
+// It is called for the application frames of a leak's stack, innermost first, and the first non-empty result
+// is reported as LeakEvent.Owner. Frames of the runtime, database/sql and sqleak itself are skipped.
+func WithOwnerResolver(resolve func(Frame) string) Option {
+	return func(ld *monitoredDriver) {
+		ld.ownerResolver = resolve
+	}
+}
+
+func (d *Detector) resolveOwner(frames []Frame) string {
+	if d.ownerResolver == nil {
+		return ""
+	}
+
+	for _, f := range frames {
+		if isInfrastructureFrame(f) {
+			continue
+		}
+		if owner := d.ownerResolver(f); owner != "" {
+			return owner
+		}
+	}
+
+	return ""
+}
+
+// LeakInfo is the information passed to OnLeak callbacks.
+type LeakInfo = LeakEvent
+
+// WithOnLeak registers a callback that is invoked for every reported event, in addition to the log output: not only
+// leaks but also late closes, repeated warnings and the other EventTypes, so callbacks interested in leaks only
+// check for LeakInfo.Type == EventLeak and LeakInfo.Occurrence == 1. Callbacks run synchronously on the goroutine
+// reporting the event, e.g. a timer's, the one closing a resource late or the caller of ReportOutstanding, and should
+// return quickly.
+func WithOnLeak(f func(LeakInfo)) Option {
+	return func(ld *monitoredDriver) {
+		ld.onLeak = append(ld.onLeak, f)
+	}
+}
+
+// WithRepeatInterval keeps reporting a leaked resource every interval after the timeout elapsed, until it is closed.
+// Each repeated report carries an increasing LeakEvent.Occurrence.
+func WithRepeatInterval(interval time.Duration) Option {
+	return func(ld *monitoredDriver) {
+		ld.repeatInterval = interval
+	}
+}
+
+// WithVerbose appends a human-oriented explanation of the cost and likely fix to every leak report, see Explain.
+func WithVerbose() Option {
+	return func(ld *monitoredDriver) {
+		ld.verbose = true
+	}
+}
+
+func (d *Detector) report(ev LeakEvent) {
+	if d.budget != nil && ev.Type == EventLeak && ev.Occurrence == 1 {
+		d.budget.record(d.clock.Now())
+	}
+
+	for _, f := range d.onLeak {
+		f(ev)
+	}
+	if dropped := d.subscribers.publish(ev); dropped > 0 {
+		d.droppedEvents.Add(int64(dropped))
+	}
+
+	if ev.quiet {
+		return
+	}
+
+	for _, sink := range d.sinks {
+		if err := sink.WriteEvent(ev); err != nil {
+			log.Printf("sqleak: failed to write leak report to sink: %v", err)
+		}
+	}
+
+	if d.jsonOutput {
+		d.reportJSON(ev)
+		return
+	}
+	if d.devOutput {
+		d.reportDev(ev)
+		return
+	}
+
+	if ev.Type == EventLeak && ev.Occurrence == 1 && ev.SiteCount > 1 {
+		log.Printf("%s: %s", ev.logMessage(), ev.summary())
+		return
+	}
+
+	if ev.Type == EventClosedLate {
+		if ev.CloseStack != "" && !d.singleLineLog {
+			log.Printf("%s: %s:\n%s", ev.logMessage(), ev.headline(), ev.CloseStack)
+			return
+		}
+		log.Printf("%s: %s", ev.logMessage(), ev.headline())
+		return
+	}
+
+	if d.withoutStacks {
+		msg := fmt.Sprintf("%s: %s", ev.logMessage(), ev.headline())
+		if ev.Type == EventLeak {
+			msg += "; " + d.countQueryLeak(ev)
+		}
+		log.Print(msg + ev.openerLine() + ev.dumpSection())
+		return
+	}
+
+	if d.callerOnly || d.singleLineLog {
+		msg := ev.singleLine() + ev.openerLine() + ev.dumpSection()
+		if d.verbose && ev.Type == EventLeak {
+			msg += "\n" + Explain(ev)
+		}
+		log.Print(msg)
+		return
+	}
+
+	if ev.Stack == "" { // not sampled
+		log.Printf("%s: %s%s", ev.logMessage(), ev.headline(), ev.dumpSection())
+		return
+	}
+
+	if d.verbose && ev.Type == EventLeak {
+		log.Printf("%s: %s:\n%s%s%s\n%s", ev.logMessage(), ev.headline(), ev.Stack, ev.openerSection(), ev.dumpSection(), Explain(ev))
+		return
+	}
+
+	log.Printf("%s: %s:\n%s%s%s", ev.logMessage(), ev.headline(), ev.Stack, ev.openerSection(), ev.dumpSection())
+}
+
+type queryLeakKey struct {
+	kind  Kind
+	query string
+}
+
+// countQueryLeak counts the first report of a leak per kind and query and describes the count so far.
+func (d *Detector) countQueryLeak(ev LeakEvent) string {
+	v, _ := d.queryLeaks.LoadOrStore(queryLeakKey{ev.Kind, ev.Query}, new(atomic.Int64))
+	count := v.(*atomic.Int64)
+
+	n := count.Load()
+	if ev.Occurrence == 1 {
+		n = count.Add(1)
+	}
+
+	if ev.Query == "" {
+		return fmt.Sprintf("%d %s leaked", n, ev.Kind)
+	}
+	return fmt.Sprintf("%d %s leaked from this query", n, ev.Kind)
+}
+
+// maxHeadlineQueryLen bounds the length of queries in headlines, the full query is part of the event.
+const maxHeadlineQueryLen = 200
+
+// shortenQuery collapses the whitespace of a query onto a single line and truncates it to maxHeadlineQueryLen.
+func shortenQuery(query string) string {
+	query = strings.Join(strings.Fields(query), " ")
+	if len(query) <= maxHeadlineQueryLen {
+		return query
+	}
+
+	cut := maxHeadlineQueryLen
+	for cut > 0 && !utf8.RuneStart(query[cut]) {
+		cut--
+	}
+	return query[:cut] + "..."
+}
+
+// Text renders the event as in the default log output, including the stack if one was captured.
+func (ev LeakEvent) Text() string {
+	if ev.Stack == "" {
+		return fmt.Sprintf("%s: %s%s%s", ev.logMessage(), ev.headline(), ev.openerLine(), ev.dumpSection())
+	}
+
+	return fmt.Sprintf("%s: %s:\n%s%s%s", ev.logMessage(), ev.headline(), ev.Stack, ev.openerSection(), ev.dumpSection())
+}
+
+// singleLine renders the event on a single line, naming the function that opened the resource instead of its stack.
+func (ev LeakEvent) singleLine() string {
+	line := fmt.Sprintf("%s: %s", ev.logMessage(), ev.headline())
+	if len(ev.Frames) > 0 {
+		f := ev.Frames[0]
+		line += fmt.Sprintf(" at %s (%s:%d)", f.Function, f.File, f.Line)
+	}
+
+	return line
+}
+
+// logMessage is message for text output, prefixed with the name set by WithName.
+func (ev LeakEvent) logMessage() string {
+	return namePrefix(ev.Name) + ev.message()
+}
+
+func (ev LeakEvent) message() string {
+	switch ev.Type {
+	case EventClosedLate:
+		return "leaked resource closed late"
+	case EventOutstanding:
+		return "resource still open"
+	case EventForcedRollback:
+		return "leaked transaction rolled back"
+	case EventLongTx:
+		return "long-held transaction"
+	case EventStalled:
+		return "stalled Rows iteration"
+	case EventCloseFailed:
+		return "failed to close resource"
+	}
+	if ev.exhausted() {
+		return "exhausted Rows not closed"
+	}
+
+	return "likely resource leak detected"
+}
+
+func describeExecutions(n int64) string {
+	switch n {
+	case 0:
+		return "never executed"
+	case 1:
+		return "executed once"
+	}
+
+	return fmt.Sprintf("executed %d times", n)
+}
+
+// exhausted reports whether the event is about Rows that were read to the end, but not closed. It's the lesser
+// kind of leak, only the pool connection stays pinned. As database/sql closes Rows once Next returns false,
+// it mostly happens with Rows used through the driver directly.
+func (ev LeakEvent) exhausted() bool {
+	return ev.Type == EventLeak && ev.Kind == KindRows && ev.Cost == CostClientBuffered
+}
+
+// openedAtFormat formats LeakEvent.OpenedAt in headlines, with milliseconds and the zone offset for correlation.
+const openedAtFormat = "2006-01-02T15:04:05.000Z07:00"
+
+// describeDatabase names the database of the event, with its scrubbed data source name if that says more.
+func (ev LeakEvent) describeDatabase() string {
+	switch {
+	case ev.DSN == "":
+		return ""
+	case ev.Database == "" || ev.Database == ev.DSN:
+		return "db " + ev.DSN
+	}
+
+	return fmt.Sprintf("db %s (%s)", ev.Database, ev.DSN)
+}
+
+// headline summarizes the event on a single line.
+func (ev LeakEvent) headline() string {
+	var details []string
+	if ev.LeakID != 0 {
+		details = append(details, fmt.Sprintf("leak #%d", ev.LeakID))
+	}
+	if ev.ResourceID != 0 {
+		details = append(details, fmt.Sprintf("resource #%d", ev.ResourceID))
+	}
+
+	if ev.Type == EventClosedLate {
+		if closer := ev.describeCloser(); closer != "" {
+			details = append(details, closer)
+		}
+		return fmt.Sprintf("%s closed %s after opening, %s after the timeout (%s)",
+			ev.Kind, ev.Age.Round(time.Millisecond), (ev.Age - ev.Timeout).Round(time.Millisecond), strings.Join(details, ", "))
+	}
+
+	if ev.Query != "" {
+		details = append(details, fmt.Sprintf("query %q", shortenQuery(ev.Query)))
+	}
+	if len(ev.Args) > 0 {
+		details = append(details, fmt.Sprintf("args [%s]", strings.Join(ev.Args, ", ")))
+	}
+	if len(ev.Columns) > 0 {
+		details = append(details, describeColumns(ev.Columns))
+	}
+	fetched := fmt.Sprintf("%d rows fetched", ev.RowsFetched)
+	if ev.RowsFetched == 1 {
+		fetched = "1 row fetched"
+	}
+	if desc := ev.Cost.describe(); desc != "" {
+		details = append(details, desc+", "+fetched)
+	} else if ev.Kind == KindRows {
+		details = append(details, fetched)
+	}
+	if ev.Kind == KindStmt {
+		details = append(details, describeExecutions(ev.Executions))
+	}
+	if ev.Type == EventLeak && ev.Occurrence > 1 {
+		warning := fmt.Sprintf("warning #%d, open for %s", ev.Occurrence, ev.Age.Round(time.Millisecond))
+		if ev.escalated && ev.Severity != SeverityWarning {
+			warning += ", escalated to " + string(ev.Severity)
+		}
+		details = append(details, warning)
+	}
+	if !ev.OpenedAt.IsZero() {
+		opened := "opened " + ev.OpenedAt.Format(openedAtFormat)
+		if ev.Goroutine != 0 {
+			opened += fmt.Sprintf(" by goroutine %d", ev.Goroutine)
+		}
+		details = append(details, opened)
+	}
+	if ev.TraceID != "" {
+		details = append(details, fmt.Sprintf("trace %s span %s", ev.TraceID, ev.SpanID))
+	}
+	if len(ev.Attrs) > 0 {
+		details = append(details, describeAttrs(ev.Attrs))
+	}
+	if db := ev.describeDatabase(); db != "" {
+		details = append(details, db)
+	}
+	if ev.Pool != nil {
+		details = append(details, ev.Pool.describe())
+	}
+	if ev.Owner != "" {
+		details = append(details, "owner: "+ev.Owner)
+	}
+
+	what := fmt.Sprintf("%s not closed within %s after opening", ev.Kind, ev.Timeout)
+	if ev.exhausted() {
+		what = fmt.Sprintf("%s exhausted but not explicitly closed within %s after opening", ev.Kind, ev.Timeout)
+	} else if ev.Type == EventLeak && ev.Idle > 0 {
+		what = fmt.Sprintf("%s not closed and idle for %s, open for %s", ev.Kind, ev.Idle.Round(time.Millisecond), ev.Age.Round(time.Millisecond))
+	}
+	switch ev.Type {
+	case EventOutstanding:
+		what = fmt.Sprintf("%s open for %s", ev.Kind, ev.Age.Round(time.Millisecond))
+	case EventForcedRollback:
+		what = fmt.Sprintf("%s rolled back by sqleak after %s", ev.Kind, ev.Age.Round(time.Millisecond))
+	case EventLongTx:
+		what = fmt.Sprintf("%s held open for %s, holding its locks and delaying vacuum until it ends", ev.Kind, ev.Age.Round(time.Millisecond))
+	case EventStalled:
+		what = fmt.Sprintf("%s stalled: Next not called for %s, open for %s", ev.Kind, ev.Idle.Round(time.Millisecond), ev.Age.Round(time.Millisecond))
+	case EventCloseFailed:
+		what = fmt.Sprintf("%s failed to close after %s, %s", ev.Kind, ev.Age.Round(time.Millisecond), ev.Error)
+	}
+	if len(details) == 0 {
+		return what
+	}
+
+	return fmt.Sprintf("%s (%s)", what, strings.Join(details, ", "))
+}
@@ -0,0 +1,104 @@
+package sqleak
+
+import (
+	"sync/atomic"
+	"time"
+)
+
+// lifetimeBucketCount is the number of bounded buckets of lifetime histograms, growing by a factor of 4 from a
+// millisecond to above an hour like promsqleak.DefaultBuckets.
+const lifetimeBucketCount = 12
+
+var lifetimeBounds = func() (bounds [lifetimeBucketCount]time.Duration) {
+	bound := time.Millisecond
+	for i := range bounds {
+		bounds[i] = bound
+		bound *= 4
+	}
+	return bounds
+}()
+
+// Histogram is the distribution of the lifetimes of closed resources, from opening to closing.
+type Histogram struct {
+	// Bounds are the inclusive upper bounds of the buckets. Counts has one more entry than Bounds:
+	// Counts[i] counts the lifetimes up to Bounds[i] and above the previous bound, the last one those above all bounds.
+	Bounds []time.Duration
+	Counts []int64
+	// Count and Sum are the number and total of all lifetimes, Max is the longest one.
+	Count int64
+	Sum   time.Duration
+	Max   time.Duration
+}
+
+// Quantile estimates the q-quantile of the lifetimes, e.g. 0.99 for the p99, as the upper bound of the bucket it
+// falls into, or Max for the last bucket. It is 0 without lifetimes. Set leak timeouts comfortably above the p99.
+func (h Histogram) Quantile(q float64) time.Duration {
+	if h.Count == 0 {
+		return 0
+	}
+
+	rank := int64(q * float64(h.Count))
+	if rank >= h.Count {
+		rank = h.Count - 1
+	}
+	var seen int64
+	for i, n := range h.Counts {
+		seen += n
+		if seen > rank {
+			if i < len(h.Bounds) && h.Bounds[i] < h.Max {
+				return h.Bounds[i]
+			}
+			return h.Max
+		}
+	}
+
+	return h.Max
+}
+
+// Mean returns the mean lifetime, 0 without lifetimes.
+func (h Histogram) Mean() time.Duration {
+	if h.Count == 0 {
+		return 0
+	}
+
+	return h.Sum / time.Duration(h.Count)
+}
+
+// lifetimes accumulates a Histogram.
+type lifetimes struct {
+	counts [lifetimeBucketCount + 1]atomic.Int64
+	count  atomic.Int64
+	sum    atomic.Int64
+	max    atomic.Int64
+}
+
+func (l *lifetimes) observe(d time.Duration) {
+	i := 0
+	for i < lifetimeBucketCount && d > lifetimeBounds[i] {
+		i++
+	}
+	l.counts[i].Add(1)
+	l.count.Add(1)
+	l.sum.Add(int64(d))
+	for {
+		prev := l.max.Load()
+		if int64(d) <= prev || l.max.CompareAndSwap(prev, int64(d)) {
+			return
+		}
+	}
+}
+
+func (l *lifetimes) histogram() Histogram {
+	h := Histogram{
+		Bounds: append([]time.Duration(nil), lifetimeBounds[:]...),
+		Counts: make([]int64, len(l.counts)),
+		Count:  l.count.Load(),
+		Sum:    time.Duration(l.sum.Load()),
+		Max:    time.Duration(l.max.Load()),
+	}
+	for i := range l.counts {
+		h.Counts[i] = l.counts[i].Load()
+	}
+
+	return h
+}
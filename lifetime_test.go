@@ -0,0 +1,38 @@
+//go:build !sqleak_noop
+
+package sqleak
+
+import (
+	"context"
+	"testing"
+	"time"
+)
+
+func TestLifetimeHistogram(t *testing.T) {
+	mc, fc, _ := newTestConn(t, WithTimeout(time.Hour))
+
+	for _, d := range []time.Duration{time.Millisecond, 3 * time.Millisecond, 3 * time.Millisecond, 2 * time.Second} {
+		rows, _ := mc.QueryContext(context.Background(), "SELECT 1", nil)
+		fc.Advance(d)
+		rows.Close()
+	}
+
+	h := mc.detector.Stats().Rows.Lifetime
+	if h.Count != 4 || h.Sum != 2007*time.Millisecond || h.Max != 2*time.Second || len(h.Counts) != len(h.Bounds)+1 {
+		t.Fatalf("unexpected histogram %+v", h)
+	}
+	if h.Counts[0] != 1 || h.Counts[1] != 2 || h.Counts[6] != 1 {
+		t.Errorf("unexpected bucket counts %v for bounds %v", h.Counts, h.Bounds)
+	}
+	for _, c := range []struct {
+		q    float64
+		want time.Duration
+	}{{0, time.Millisecond}, {0.5, 4 * time.Millisecond}, {0.99, 2 * time.Second}} {
+		if got := h.Quantile(c.q); got != c.want {
+			t.Errorf("Quantile(%g) = %s, want %s", c.q, got, c.want)
+		}
+	}
+	if got := (Histogram{}).Quantile(0.99); got != 0 {
+		t.Errorf("expected no quantile without lifetimes, got %s", got)
+	}
+}
@@ -0,0 +1,33 @@
+package sqleak
+
+import "time"
+
+// WithLongTxThreshold reports transactions still open threshold after they began as an EventLongTx, independently
+// of the leak timeout. A transaction open for minutes holds its locks and keeps the database from cleaning up old
+// row versions (vacuum, undo logs) even if it's eventually committed, so it's worth a report of its own before,
+// or instead of, being reported as a leak. Like WithAutoRollback, the threshold applies regardless of sampling.
+func WithLongTxThreshold(threshold time.Duration) Option {
+	return func(ld *monitoredDriver) {
+		ld.longTx = threshold
+	}
+}
+
+// reportLongHeld reports the Tx at the WithLongTxThreshold unless it ended already. The report runs unlocked, so
+// hooks and sinks don't block ending the Tx.
+func (mt *monitoredTx) reportLongHeld() {
+	mt.mu.Lock()
+	if mt.done {
+		mt.mu.Unlock()
+		return
+	}
+
+	m := mt.monitor
+	ev := m.leakEvent()
+	ev.Type = EventLongTx
+	ev.Age = m.detector.clock.Now().Sub(m.openedAt)
+	ev.quiet = false
+	mt.mu.Unlock()
+
+	m.detector.attachPoolStats(&ev)
+	m.detector.report(ev)
+}
@@ -0,0 +1,61 @@
+//go:build !sqleak_noop
+
+package sqleak
+
+import (
+	"context"
+	"database/sql/driver"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestLongTxThreshold(t *testing.T) {
+	var events []LeakEvent
+	mc, fc, logOutput := newTestConn(t,
+		WithTimeout(time.Hour),
+		WithLongTxThreshold(time.Minute),
+		WithOnLeak(func(ev LeakEvent) { events = append(events, ev) }),
+	)
+	ctx := context.Background()
+
+	committed, _ := mc.BeginTx(ctx, driver.TxOptions{})
+	fc.Advance(30 * time.Second)
+	if err := committed.Commit(); err != nil {
+		t.Fatal(err)
+	}
+
+	tx, _ := mc.BeginTx(ctx, driver.TxOptions{})
+	defer tx.Rollback()
+	fc.Advance(time.Minute)
+
+	if len(events) != 1 {
+		t.Fatalf("got %d events, want the long-held Tx only", len(events))
+	}
+	if ev := events[0]; ev.Type != EventLongTx || ev.Kind != KindTx || ev.ResourceID != 2 || ev.LeakID != 0 || ev.Age != time.Minute {
+		t.Errorf("unexpected event %+v", ev)
+	}
+	if want := "long-held transaction: Tx held open for 1m0s, holding its locks and delaying vacuum until it ends (resource #2"; !strings.Contains(logOutput.String(), want) {
+		t.Errorf("expected %q in log, got:\n%s", want, logOutput.String())
+	}
+}
+
+func TestLongTxReportUnlocked(t *testing.T) {
+	var tx driver.Tx
+	committed := make(chan error, 1)
+	mc, fc, _ := newTestConn(t,
+		WithTimeout(time.Hour),
+		WithLongTxThreshold(time.Minute),
+		WithOnLeak(func(ev LeakEvent) {
+			if ev.Type == EventLongTx {
+				committed <- tx.Commit() // deadlocks if reported while holding the Tx
+			}
+		}),
+	)
+
+	tx, _ = mc.BeginTx(context.Background(), driver.TxOptions{})
+	fc.Advance(time.Minute)
+	if err := <-committed; err != nil {
+		t.Errorf("expected the Tx to commit from the hook, got %v", err)
+	}
+}
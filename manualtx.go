@@ -0,0 +1,132 @@
+package sqleak
+
+import (
+	"fmt"
+	"log"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// WithManualTxTracking tracks transactions begun and ended by plain SQL statements like BEGIN and COMMIT
+// instead of DB.BeginTx, and warns when a connection is returned to the pool while such a transaction is open:
+// the transaction then spans whatever the next user of the connection runs on it. The warning names the statement
+// and the application function that began the transaction; Stats().ManualTxLeaks counts the warnings.
+// Only the first statement of a query is inspected.
+func WithManualTxTracking() Option {
+	return func(ld *monitoredDriver) {
+		ld.manualTx = &manualTxTracking{}
+	}
+}
+
+type manualTxTracking struct {
+	leaks atomic.Int64
+}
+
+// manualTxState is the transaction begun by SQL statements on a single connection.
+type manualTxState struct {
+	mu     sync.Mutex
+	open   bool
+	warned bool
+	since  time.Time
+	query  string
+	caller Frame
+}
+
+// trackManualTx follows the transaction state of the connection through the statement query, with
+// WithManualTxTracking.
+func (mc *monitoredConn) trackManualTx(query string) {
+	s := mc.manualTx
+	if s == nil {
+		return
+	}
+
+	begin, end := manualTxStatement(query)
+	if !begin && !end {
+		return
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if end {
+		s.open = false
+		return
+	}
+	if s.open {
+		return // databases ignore, or reject, a BEGIN within a transaction
+	}
+	s.open, s.warned = true, false
+	s.since, s.query = mc.detector.clock.Now(), query
+	s.caller, _ = applicationCaller()
+}
+
+// checkManualTx warns about returning the connection to the pool with a transaction open, with
+// WithManualTxTracking.
+func (mc *monitoredConn) checkManualTx() {
+	s := mc.manualTx
+	if s == nil {
+		return
+	}
+	d := mc.detector
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if !s.open || s.warned {
+		return
+	}
+	s.warned = true
+	d.manualTx.leaks.Add(1)
+
+	msg := fmt.Sprintf("%sconnection returned to the pool with a transaction still open, begun %s ago by %q",
+		d.logPrefix(), d.clock.Now().Sub(s.since).Round(time.Millisecond), shortenQuery(d.anonymizeQuery(s.query)))
+	if s.caller.Function != "" {
+		msg += fmt.Sprintf(" in %s (%s:%d)", s.caller.Function, s.caller.File, s.caller.Line)
+	}
+	log.Print(msg + "; the next user of the connection runs inside it, use DB.BeginTx or a sql.Conn")
+}
+
+// manualTxStatement reports whether the first statement of query begins or ends a transaction, e.g.
+// BEGIN, START TRANSACTION, COMMIT, END or ROLLBACK, but not ROLLBACK TO SAVEPOINT.
+func manualTxStatement(query string) (begin, end bool) {
+	first, rest := nextKeyword(query)
+	switch first {
+	case "BEGIN":
+		return true, false
+	case "START":
+		second, _ := nextKeyword(rest)
+		return second == "TRANSACTION", false
+	case "COMMIT", "END", "ABORT":
+		return false, true
+	case "ROLLBACK":
+		second, _ := nextKeyword(rest)
+		return false, second != "TO"
+	}
+
+	return false, false
+}
+
+// nextKeyword returns the upper-cased word at the start of query, skipping whitespace and comments, and the rest.
+func nextKeyword(query string) (string, string) {
+	for {
+		query = strings.TrimLeft(query, " \t\r\n;")
+		switch {
+		case strings.HasPrefix(query, "--"):
+			_, query, _ = strings.Cut(query, "\n")
+			continue
+		case strings.HasPrefix(query, "/*"):
+			_, query, _ = strings.Cut(query[2:], "*/")
+			continue
+		}
+		break
+	}
+
+	i := 0
+	for i < len(query) && (query[i] >= 'a' && query[i] <= 'z' || query[i] >= 'A' && query[i] <= 'Z') {
+		i++
+	}
+
+	return strings.ToUpper(query[:i]), query[i:]
+}
@@ -0,0 +1,58 @@
+//go:build !sqleak_noop
+
+package sqleak
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestManualTxStatement(t *testing.T) {
+	for _, c := range []struct {
+		query      string
+		begin, end bool
+	}{
+		{"BEGIN", true, false},
+		{"  begin immediate transaction", true, false},
+		{"/* app */ START TRANSACTION READ ONLY", true, false},
+		{"-- txn\nBEGIN;", true, false},
+		{"COMMIT", false, true},
+		{"end", false, true},
+		{"ROLLBACK", false, true},
+		{"ROLLBACK TO SAVEPOINT sp1", false, false},
+		{"START SLAVE", false, false},
+		{"SELECT 'BEGIN'", false, false},
+		{"BEGINNING", false, false},
+	} {
+		if begin, end := manualTxStatement(c.query); begin != c.begin || end != c.end {
+			t.Errorf("manualTxStatement(%q) = %t, %t, want %t, %t", c.query, begin, end, c.begin, c.end)
+		}
+	}
+}
+
+func TestManualTxTracking(t *testing.T) {
+	mc, fc, logOutput := newTestConn(t, WithTimeout(time.Hour), WithManualTxTracking())
+	ctx := context.Background()
+
+	_, _ = mc.ExecContext(ctx, "BEGIN", nil)
+	_, _ = mc.ExecContext(ctx, "COMMIT", nil)
+	_ = mc.IsValid()
+	if out := logOutput.String(); out != "" {
+		t.Fatalf("expected no warning, got:\n%s", out)
+	}
+
+	_, _ = mc.ExecContext(ctx, "BEGIN", nil)
+	fc.Advance(2 * time.Second)
+	_ = mc.IsValid()
+	_ = mc.IsValid()
+
+	out := logOutput.String()
+	if want := `connection returned to the pool with a transaction still open, begun 2s ago by "BEGIN" in `; !strings.Contains(out, want) {
+		t.Errorf("expected %q in log, got:\n%s", want, out)
+	}
+	if n := mc.detector.Stats().ManualTxLeaks; n != 1 {
+		t.Errorf("got %d manual transaction leaks, want 1:\n%s", n, out)
+	}
+}
@@ -0,0 +1,26 @@
+package sqleak
+
+import "net/http"
+
+// Labels added by Middleware, named after the OpenTelemetry semantic conventions.
+const (
+	LabelHTTPMethod    = "http.request.method"
+	LabelHTTPRoute     = "http.route"
+	LabelURLPath       = "url.path"
+	LabelClientAddress = "client.address"
+)
+
+// Middleware annotates the context of every request with its method, path, route pattern and remote address, see
+// Annotate, so the leak reports of resources opened with the request's context name the endpoint responsible.
+// The route is the pattern of the http.ServeMux route matched when the middleware is called, so wrap the handlers
+// registered on the mux rather than the mux itself to have it; it's left out otherwise.
+func Middleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		labels := []string{LabelHTTPMethod, r.Method, LabelURLPath, r.URL.Path, LabelClientAddress, r.RemoteAddr}
+		if r.Pattern != "" {
+			labels = append(labels, LabelHTTPRoute, r.Pattern)
+		}
+
+		next.ServeHTTP(w, r.WithContext(Annotate(r.Context(), labels...)))
+	})
+}
@@ -0,0 +1,46 @@
+//go:build !sqleak_noop
+
+package sqleak
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestMiddleware(t *testing.T) {
+	var events []LeakEvent
+	mc, fc, logOutput := newTestConn(t,
+		WithTimeout(time.Second),
+		WithOnLeak(func(ev LeakEvent) { events = append(events, ev) }),
+	)
+
+	mux := http.NewServeMux()
+	mux.Handle("GET /orders/{id}", Middleware(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if _, err := mc.QueryContext(r.Context(), "SELECT * FROM orders WHERE id = ?", nil); err != nil {
+			t.Error(err)
+		}
+	})))
+	req := httptest.NewRequest(http.MethodGet, "/orders/42", nil)
+	mux.ServeHTTP(httptest.NewRecorder(), req)
+	fc.Advance(time.Second)
+
+	if len(events) != 1 {
+		t.Fatalf("got %d events, want 1", len(events))
+	}
+	for key, want := range map[string]string{
+		LabelHTTPMethod:    "GET",
+		LabelHTTPRoute:     "GET /orders/{id}",
+		LabelURLPath:       "/orders/42",
+		LabelClientAddress: req.RemoteAddr,
+	} {
+		if got := events[0].Attrs[key]; got != want {
+			t.Errorf("%s = %q, want %q", key, got, want)
+		}
+	}
+	if want := "http.request.method=GET http.route=GET /orders/{id} url.path=/orders/42"; !strings.Contains(logOutput.String(), want) {
+		t.Errorf("expected %q in log, got:\n%s", want, logOutput.String())
+	}
+}
@@ -0,0 +1,72 @@
+package sqleak
+
+import (
+	"fmt"
+	"log"
+	"sync/atomic"
+)
+
+// WithMisuseDetection reports Rows, Stmt and Tx closed twice, and Rows and Stmt used after they were closed, with
+// the stack of the offending call and the stack of the call that closed the resource first. database/sql guards
+// against most of these itself; below it, e.g. with drivers or wrappers used directly, they otherwise surface as
+// cryptic driver errors. The stack of every close is captured. Resources closed by WithAutoClose or
+// WithAutoRollback are not reported, they fail with ErrAutoClosed or ErrForcedRollback instead.
+// Stats().Misuses counts the reports.
+func WithMisuseDetection() Option {
+	return func(ld *monitoredDriver) {
+		ld.misuse = &misuseDetector{}
+	}
+}
+
+type misuseDetector struct {
+	count atomic.Int64
+}
+
+// closing reports closing the resource again with WithMisuseDetection, or records the stack closing it
+// with WithCloseStacks. op names the method, e.g. "Close" or "Commit".
+func (m *monitor) closing(op string) {
+	if !m.detector.recordsCloseStacks() || m.ignored {
+		return
+	}
+	if m.state.Load() == stateClosed {
+		if m.detector.misuse != nil {
+			m.reportMisuse(op)
+		}
+		return
+	}
+
+	stack := m.detector.currentStack()
+	m.closedBy.Store(&stack)
+}
+
+// using reports calling op on the resource after it was closed, with WithMisuseDetection.
+func (m *monitor) using(op string) {
+	if m.detector.misuse == nil || m.ignored || m.state.Load() != stateClosed {
+		return
+	}
+
+	m.reportMisuse(op)
+}
+
+func (m *monitor) reportMisuse(op string) {
+	d := m.detector
+	if m.reclaimed.Load() {
+		return
+	}
+	d.misuse.count.Add(1)
+
+	resource := fmt.Sprintf("resource #%d", m.id)
+	if m.query != "" {
+		resource += fmt.Sprintf(", query %q", shortenQuery(d.anonymizeQuery(m.query)))
+	}
+
+	stack, closedBy := string(d.currentStack()), "unknown\n"
+	if p := m.closedBy.Load(); p != nil {
+		closedBy = string(*p)
+	}
+	if !d.fullStacks {
+		stack, closedBy = trimStack(stack), trimStack(closedBy)
+	}
+
+	log.Printf("%s%s.%s called on closed %s (%s):\n%s\nit was closed by:\n%s", d.logPrefix(), m.kind, op, m.kind, resource, stack, closedBy)
+}
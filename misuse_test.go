@@ -0,0 +1,50 @@
+//go:build !sqleak_noop
+
+package sqleak
+
+import (
+	"context"
+	"database/sql/driver"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestMisuseDetection(t *testing.T) {
+	mc, _, logOutput := newTestConn(t, WithTimeout(time.Hour), WithMisuseDetection())
+	ctx := context.Background()
+
+	rows, _ := mc.QueryContext(ctx, "SELECT 1", nil)
+	rows.Close()
+	rows.Close()
+	_ = rows.Next(make([]driver.Value, 1))
+
+	stmt, _ := mc.Prepare("SELECT 2")
+	stmt.Close()
+	_, _ = stmt.(driver.StmtExecContext).ExecContext(ctx, nil)
+
+	tx, _ := mc.BeginTx(ctx, driver.TxOptions{})
+	_ = tx.Commit()
+	_ = tx.Rollback()
+
+	// Used properly.
+	rows, _ = mc.QueryContext(ctx, "SELECT 3", nil)
+	_ = rows.Next(make([]driver.Value, 1))
+	rows.Close()
+
+	out := logOutput.String()
+	for _, want := range []string{
+		"Rows.Close called on closed Rows (resource #1, query \"SELECT 1\"):\ngoroutine ",
+		"Rows.Next called on closed Rows (resource #1, query \"SELECT 1\"):\ngoroutine ",
+		"Stmt.Exec called on closed Stmt (resource #1, query \"SELECT 2\"):\ngoroutine ",
+		"Tx.Rollback called on closed Tx (resource #1):\ngoroutine ",
+		"\nit was closed by:\ngoroutine ",
+	} {
+		if !strings.Contains(out, want) {
+			t.Errorf("expected %q in log, got:\n%s", want, out)
+		}
+	}
+	if n := mc.detector.Stats().Misuses; n != 4 {
+		t.Errorf("got %d misuses, want 4:\n%s", n, out)
+	}
+}
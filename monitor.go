@@ -0,0 +1,402 @@
+package sqleak
+
+import (
+	"bytes"
+	"context"
+	"database/sql/driver"
+	"runtime"
+	rtrace "runtime/trace"
+	"strings"
+	"sync/atomic"
+	"time"
+)
+
+// Monitor states, a monitor only moves forward through them.
+const (
+	stateOpen   int32 = iota
+	stateLeaked       // reported as leaked and still open
+	stateClosed
+)
+
+type monitor struct {
+	detector  *Detector
+	timeout   time.Duration
+	stack     []byte
+	callers   []uintptr    // instead of stack with WithCallerOnly
+	columns   []Column     // of Rows with WithColumnMetadata
+	database  *database    // of the connection, if known
+	args      []string     // redacted query parameters of Rows with WithCaptureArgs
+	trace     *Trace       // of the opening context, with WithTraceExtractor
+	task      *rtrace.Task // with WithRuntimeTrace, while an execution trace is running
+	taskCtx   context.Context
+	fetched   atomic.Int64 // rows read so far
+	execs     atomic.Int64 // executions of a Stmt so far
+	drained   atomic.Bool  // whether all result sets were read to the end
+	siteCount atomic.Int64 // leaks of the call site so far, with WithDeduplication
+	quiet     atomic.Bool  // whether the leak is not logged, with WithDeduplication or WithSuppressions
+	silenced  atomic.Bool  // whether the leak matched WithSuppressions, exempting it from the fail mode
+	state     atomic.Int32
+	kind      Kind
+	id        uint64 // resource ID, see LeakEvent.ResourceID
+	query     string
+	openedAt  time.Time
+	goroutine uint64               // ID of the opening goroutine, only set if sampled
+	holdsConn bool                 // whether the resource pins a pool connection while open
+	site      uint64               // call site hash, only set if sampling is enabled
+	sampled   bool                 // whether leak detection is armed and, unless WithoutStacks, the stack captured
+	warnings  atomic.Int32         // number of leak reports so far
+	leakID    atomic.Uint64        // assigned before the first leak report, see fire
+	reclaim   func() (bool, error) // closes the resource for WithAutoClose, false if it was closed already
+	reclaimed atomic.Bool          // whether reclaim closed it
+	conn      *monitoredConn       // to invalidate, only with WithInvalidateConn
+
+	closedBy atomic.Pointer[[]byte] // stack of the first close, with WithCloseStacks or WithMisuseDetection
+	shape    string                 // query fingerprint, with WithAdaptiveTimeout
+	active   atomic.Int64           // UnixNano of the last activity, with WithIdleTimeout
+	ignored  bool                   // opened with a context from Ignore
+	attrs    map[string]string      // of the opening context, with WithContextAttrs
+	canceled atomic.Bool            // whether the opening context is done, see watchCancel
+	unwatch  func() bool            // stops watching the opening context
+}
+
+func (m *monitor) markClosed() {
+	prev := m.state.Swap(stateClosed)
+	if prev == stateClosed || m.ignored {
+		return
+	}
+
+	m.detector.open.Delete(m)
+	m.unwatchCancel()
+	m.endTask()
+	if m.detector.pprofProfiles {
+		m.removeFromProfile()
+	}
+
+	closedAt := m.detector.clock.Now()
+	counters := &m.detector.kinds[kindIndex(m.kind)]
+	counters.open.Add(-1)
+	counters.observeAge(closedAt.Sub(m.openedAt))
+	counters.lifetime.observe(closedAt.Sub(m.openedAt))
+	// Lifetimes of leaked resources would raise the timeout of their query until its leaks are no longer reported.
+	if m.shape != "" && prev != stateLeaked && !m.reclaimed.Load() {
+		m.detector.adaptive.observe(m.shape, closedAt.Sub(m.openedAt))
+	}
+	if m.holdsConn && m.detector.hold != nil {
+		m.detector.hold.release(m.openedAt, closedAt)
+	}
+
+	if len(m.detector.onClose) > 0 {
+		r := m.resource()
+		for _, f := range m.detector.onClose {
+			f(r, closedAt.Sub(m.openedAt))
+		}
+	}
+
+	if prev == stateLeaked {
+		m.detector.countLeakedOpen(-1)
+		if m.reclaimed.Load() {
+			return // logged by scheduleReclaim
+		}
+
+		ev := m.leakEvent()
+		ev.Type = EventClosedLate
+		ev.Age = closedAt.Sub(m.openedAt)
+		m.attachCloseStack(&ev)
+		m.detector.report(ev)
+	}
+}
+
+// capturedStack returns the stack captured at open, trimmed unless WithFullStacks, and its parsed frames.
+func (m *monitor) capturedStack() (string, []Frame) {
+	if m.callers != nil {
+		return "", trimFrames(callerFrames(m.callers))
+	}
+
+	stack, frames := string(m.stack), parseStack(string(m.stack))
+	if !m.detector.fullStacks {
+		stack, frames = trimStack(stack), trimFrames(frames)
+	}
+
+	return stack, frames
+}
+
+func (m *monitor) leakEvent() LeakEvent {
+	stack, frames := m.capturedStack()
+	owner := m.detector.resolveOwner(frames)
+	truncated := bytes.HasSuffix(m.stack, []byte(truncatedMarker))
+	if keep := m.detector.stackFilter; keep != nil {
+		stack, frames = filterStack(stack, keep), filterFrames(frames, keep)
+		if truncated && !strings.HasSuffix(stack, truncatedMarker) {
+			stack += truncatedMarker
+		}
+	}
+	if m.callers != nil && len(frames) > 1 {
+		frames = frames[:1]
+	}
+
+	age := m.detector.clock.Now().Sub(m.openedAt)
+	cost, severity := classify(m.kind, familyOf(m.detector.driverName), m.drained.Load(), m.kind == KindRows && !m.holdsConn)
+	escalation := m.detector.escalation
+	if escalation != nil {
+		severity = escalation.severity(age, m.timeout)
+	}
+
+	var dbName, dsn string
+	if m.database != nil {
+		dbName, dsn = m.database.name, m.database.dsn
+	}
+	var traceID, spanID string
+	if m.trace != nil {
+		traceID, spanID = m.trace.TraceID, m.trace.SpanID
+	}
+
+	return LeakEvent{
+		Type:             EventLeak,
+		Name:             m.detector.name,
+		LeakID:           m.leakID.Load(),
+		Kind:             m.kind,
+		ResourceID:       m.id,
+		Query:            m.detector.anonymizeQuery(m.query),
+		QueryFingerprint: m.detector.queryFingerprint(m.query),
+		Args:             m.args,
+		Columns:          m.columns,
+		RowsFetched:      m.fetched.Load(),
+		Executions:       m.execs.Load(),
+		Cost:             cost,
+		Severity:         severity,
+		OpenedAt:         m.openedAt,
+		Goroutine:        m.goroutine,
+		TraceID:          traceID,
+		Attrs:            m.attrs,
+		SpanID:           spanID,
+		Timeout:          m.timeout,
+		Age:              age,
+		Occurrence:       int(m.warnings.Load()),
+		Stack:            stack,
+		StackTruncated:   truncated,
+		Frames:           frames,
+		Driver:           m.detector.driverName,
+		Database:         dbName,
+		DSN:              dsn,
+		Owner:            owner,
+		SiteCount:        int(m.siteCount.Load()),
+		quiet:            m.quiet.Load(),
+		escalated:        escalation != nil,
+	}
+}
+
+// fire reports the n-th warning for the resource, the first one when the timeout elapsed and, while the resource stays
+// open, more with WithRepeatInterval and WithSeverityEscalation, see Detector.warningAge. Each warning is claimed once, so a late timer and CheckDeadlines never
+// report the same warning twice.
+func (m *monitor) fire(n int32) {
+	if !m.warnings.CompareAndSwap(n-1, n) {
+		return
+	}
+
+	var site string // dedupKey of the first report
+	if n == 1 {
+		if m.state.Load() != stateOpen || m.canceled.Load() {
+			return // closed in time, or being closed by database/sql, don't use up a leak ID
+		}
+		// The ID is set before the state transition, so a concurrent markClosed observing stateLeaked also sees it.
+		m.leakID.Store(m.detector.leakIDs.Add(1))
+		site = m.dedupKey()
+		if dd := m.detector.dedup; dd != nil {
+			count, log := dd.observe(site, m.detector.clock.Now())
+			m.siteCount.Store(count)
+			m.quiet.Store(!log)
+		}
+		if !m.state.CompareAndSwap(stateOpen, stateLeaked) {
+			return
+		}
+		m.detector.countLeakedOpen(1)
+		counters := &m.detector.kinds[kindIndex(m.kind)]
+		counters.leaked.Add(1)
+		counters.observeAge(m.detector.clock.Now().Sub(m.openedAt))
+		if m.suppressed() {
+			m.silenced.Store(true)
+			m.quiet.Store(true)
+		}
+		m.invalidateConn()
+	} else if m.state.Load() == stateClosed {
+		return
+	}
+
+	ev := m.leakEvent()
+	if m.idleTimed() {
+		ev.Idle = m.idle()
+	}
+	m.detector.attachPoolStats(&ev)
+	if n == 1 {
+		m.detector.sources.record(site, ev, m.detector.clock.Now())
+	}
+	m.attachGoroutines(&ev)
+	m.detector.report(ev)
+	m.logTask(ev)
+	if m.trace != nil && m.trace.OnLeak != nil {
+		m.trace.OnLeak(ev)
+	}
+	if n == 1 {
+		m.scheduleReclaim()
+	}
+
+	// The next warning is scheduled relative to this one, so a frozen process doesn't catch up on all missed warnings.
+	if next, ok := m.detector.warningAge(m.timeout, n+1); ok {
+		this, _ := m.detector.warningAge(m.timeout, n)
+		m.detector.clock.AfterFunc(next-this, func() { m.fire(n + 1) })
+	}
+
+	if n == 1 && !m.silenced.Load() {
+		m.detector.fail(ev)
+	}
+}
+
+// due returns the next warning of the resource if it is due at now. Like the timers, it counts from the last
+// activity with WithIdleTimeout.
+func (m *monitor) due(now time.Time) (int32, bool) {
+	if !m.sampled || m.state.Load() == stateClosed {
+		return 0, false
+	}
+
+	n := m.warnings.Load() + 1
+	age, ok := m.detector.warningAge(m.timeout, n)
+	if !ok {
+		return 0, false
+	}
+
+	since := m.openedAt
+	if m.idleTimed() {
+		since = time.Unix(0, m.active.Load())
+	}
+
+	return n, !now.Before(since.Add(age))
+}
+
+func newMonitor(ctx context.Context, mc *monitoredConn, kind Kind, query string, args []driver.NamedValue, columns []Column, holdsConn bool) *monitor {
+	d := mc.detector
+	mon := &monitor{
+		detector:  d,
+		database:  mc.database,
+		timeout:   d.timeout,
+		kind:      kind,
+		id:        d.nextResourceID(kind),
+		query:     query,
+		columns:   columns,
+		openedAt:  d.clock.Now(),
+		holdsConn: holdsConn,
+		sampled:   true,
+	}
+	if d.disabled || ignored(ctx) || kind != KindTx && d.shouldMonitor != nil && !d.shouldMonitor(query) {
+		mon.ignored, mon.sampled = true, false
+		return mon
+	}
+	d.overhead.opens.Add(1)
+	d.kinds[kindIndex(kind)].open.Add(1)
+	if d.invalidateConn {
+		mon.conn = mc
+	}
+	mon.attrs = d.contextAttrs(ctx)
+	if d.adaptive != nil && query != "" {
+		mon.shape = d.queryFingerprint(query)
+		mon.timeout = d.adaptive.timeout(mon.shape, d.timeout)
+	}
+	if timeout, ok := contextTimeout(ctx); ok {
+		mon.timeout = timeout
+	}
+
+	if holdsConn && d.hold != nil {
+		d.hold.acquire(mon.openedAt)
+	}
+	d.open.Store(mon, struct{}{})
+	if d.runtimeTrace {
+		mon.startTask(ctx)
+	}
+	if d.pprofProfiles {
+		mon.addToProfile()
+	}
+
+	if len(d.onOpen) > 0 {
+		r := mon.resource()
+		for _, f := range d.onOpen {
+			f(r)
+		}
+	}
+
+	if d.sampleContext != nil && !d.sampleContext(ctx) {
+		mon.sampled = false
+	} else if s := d.sampler; s != nil {
+		if s.perCallSite() {
+			mon.site = callSite(2)
+		}
+		mon.sampled = s.sample(mon.site, mon.openedAt)
+	}
+
+	if !mon.sampled {
+		return mon
+	}
+
+	if d.withoutStacks {
+		mon.goroutine = currentGoroutineID()
+	} else {
+		mon.captureStack()
+	}
+	mon.args = d.captureArgs(args)
+	if d.extractTrace != nil {
+		if t := d.extractTrace(ctx); t.TraceID != "" || t.OnLeak != nil {
+			mon.trace = &t
+		}
+	}
+
+	mon.watchCancel(ctx)
+	start := time.Now()
+	mon.touch()
+	d.clock.AfterFunc(mon.timeout, mon.expire)
+
+	d.overhead.timerSchedules.Add(1)
+	d.overhead.timerNanos.Add(int64(time.Since(start)))
+
+	return mon
+}
+
+// captureStack records the stack of the goroutine opening the resource, or only its callers with WithCallerOnly.
+func (m *monitor) captureStack() {
+	start := time.Now()
+
+	if m.detector.callerOnly {
+		var pcs [maxCallerDepth]uintptr
+		n := runtime.Callers(3, pcs[:])
+		m.callers = append([]uintptr(nil), pcs[:n]...)
+		m.goroutine = currentGoroutineID()
+	} else {
+		m.stack = m.detector.currentStack()
+		m.goroutine = goroutineID(m.stack)
+	}
+
+	m.detector.overhead.stackCaptures.Add(1)
+	m.detector.overhead.stackCaptureNanos.Add(int64(time.Since(start)))
+}
+
+// currentStack captures the stack of the calling goroutine.
+func (d *Detector) currentStack() []byte {
+	buf := d.stackBuffers.Get().(*[]byte)
+	defer d.stackBuffers.Put(buf)
+
+	// Copy the stack out of the pooled buffer, it is usually much smaller than the buffer and is kept for the timeout.
+	n := runtime.Stack(*buf, false)
+	if n == len(*buf) {
+		return truncateStack((*buf)[:n])
+	}
+
+	return append([]byte(nil), (*buf)[:n]...)
+}
+
+// openMonitors returns the monitors of all currently open resources.
+func (d *Detector) openMonitors() []*monitor {
+	var monitors []*monitor
+	d.open.Range(func(key, _ any) bool {
+		monitors = append(monitors, key.(*monitor))
+		return true
+	})
+
+	return monitors
+}
@@ -0,0 +1,8 @@
+//go:build sqleak_noop
+
+package sqleak
+
+// noop strips the instrumentation from builds with the sqleak_noop build tag: the API stays the same, but drivers and
+// connectors return the driver's connections unwrapped, so no resource is monitored and none costs an allocation.
+// Detectors are neither started nor registered, their methods return zero values, Config returns only Disabled.
+const noop = true
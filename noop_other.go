@@ -0,0 +1,6 @@
+//go:build !sqleak_noop
+
+package sqleak
+
+// noop is false unless built with the sqleak_noop build tag.
+const noop = false
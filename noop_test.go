@@ -0,0 +1,43 @@
+//go:build sqleak_noop
+
+package sqleak
+
+import (
+	"context"
+	"testing"
+	"time"
+)
+
+func TestNoop(t *testing.T) {
+	d := WrapDriver(fakeDriver{}, WithTimeout(time.Second), WithConnHoldLimit(1, time.Second))
+	defer d.(*monitoredDriver).stop()
+
+	conn, err := d.Open("")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, ok := conn.(fakeConn); !ok {
+		t.Errorf("expected the driver's connection unwrapped, got %T", conn)
+	}
+
+	detector := DetectorFromDriver(d)
+	if c := detector.Config(); !c.Disabled || c.Timeout != 0 || c.HoldMaxOpenConns != 0 {
+		t.Errorf("expected only Disabled in the Config, got %+v", c)
+	}
+	if s := detector.Stats(); s.Name != "" || s.Rows.Open != 0 {
+		t.Errorf("expected zero Stats, got %+v", s)
+	}
+	if len(registeredDetectors()) != 0 {
+		t.Error("expected the Detector not to be registered")
+	}
+
+	connector := WrapConnector(&countingConnector{driver: fakeDriver{}})
+	defer connector.(*monitoredConnector).Close()
+
+	if conn, err = connector.Connect(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	if _, ok := conn.(fakeConn); !ok {
+		t.Errorf("expected the connector's connection unwrapped, got %T", conn)
+	}
+}
@@ -0,0 +1,82 @@
+package sqleak
+
+import (
+	"bytes"
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// WithOpenerStack adds the current stack of the goroutine that opened a leaked resource to its leak reports,
+// found by the goroutine's ID in a dump of all goroutines when the leak is reported. It tells whether the goroutine
+// is stuck, still iterating or has exited. The dump briefly stops the world, at a cost growing with the number of
+// goroutines, but happens only when a leak is reported.
+func WithOpenerStack() Option {
+	return func(ld *monitoredDriver) {
+		ld.openerStack = true
+	}
+}
+
+// openerExited is LeakEvent.OpenerState of an opening goroutine that has exited.
+const openerExited = "exited"
+
+// findGoroutine returns the stack of the goroutine with the given ID in a dump of all goroutines.
+func findGoroutine(dump []byte, id uint64) (string, bool) {
+	header := []byte("goroutine " + strconv.FormatUint(id, 10) + " [")
+	for _, stack := range bytes.Split(dump, []byte("\n\n")) {
+		if bytes.HasPrefix(stack, header) {
+			return string(stack) + "\n", true
+		}
+	}
+
+	return "", false
+}
+
+// goroutineState returns the status of a goroutine from the "goroutine N [status]:" header of its stack.
+func goroutineState(stack string) string {
+	_, rest, _ := strings.Cut(stack, "[")
+	state, _, _ := strings.Cut(rest, "]")
+	return state
+}
+
+// attachOpenerStack sets the current stack and state of the goroutine with the given ID, which opened the resource.
+func attachOpenerStack(ev *LeakEvent, dump []byte, goroutine uint64) {
+	stack, ok := findGoroutine(dump, goroutine)
+	if !ok {
+		ev.OpenerState = openerExited
+		return
+	}
+
+	ev.OpenerStack = stack
+	ev.OpenerFrames = parseStack(stack)
+	ev.OpenerState = goroutineState(stack)
+}
+
+// openerLine describes the opening goroutine on a single line, empty without WithOpenerStack.
+func (ev LeakEvent) openerLine() string {
+	switch ev.OpenerState {
+	case "":
+		return ""
+	case openerExited:
+		return "; opening goroutine has exited"
+	}
+
+	line := fmt.Sprintf("; opening goroutine now [%s]", ev.OpenerState)
+	if frames := trimFrames(ev.OpenerFrames); len(frames) > 0 {
+		line += fmt.Sprintf(" at %s (%s:%d)", frames[0].Function, frames[0].File, frames[0].Line)
+	}
+
+	return line
+}
+
+// openerSection describes the opening goroutine below the stack of a report, empty without WithOpenerStack.
+func (ev LeakEvent) openerSection() string {
+	switch ev.OpenerState {
+	case "":
+		return ""
+	case openerExited:
+		return "\nthe opening goroutine has exited\n"
+	}
+
+	return "\nthe opening goroutine now:\n" + ev.OpenerStack
+}
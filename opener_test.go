@@ -0,0 +1,66 @@
+//go:build !sqleak_noop
+
+package sqleak
+
+import (
+	"context"
+	"database/sql/driver"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestOpenerStack(t *testing.T) {
+	var events []LeakEvent
+	mc, fc, logOutput := newTestConn(t,
+		WithTimeout(time.Second),
+		WithOpenerStack(),
+		WithOnLeak(func(ev LeakEvent) { events = append(events, ev) }),
+	)
+
+	open := func() driver.Rows {
+		rows, err := mc.QueryContext(context.Background(), "SELECT 1", nil)
+		if err != nil {
+			t.Error(err)
+		}
+		return rows
+	}
+
+	// One goroutine exits after opening, the other one blocks.
+	opened := make(chan driver.Rows)
+	go func() { opened <- open() }()
+	exited := <-opened
+	defer exited.Close()
+
+	release := make(chan struct{})
+	blocked := make(chan struct{})
+	go func() {
+		defer close(blocked)
+		opened <- open()
+		<-release
+	}()
+	stuck := <-opened
+	defer stuck.Close()
+
+	time.Sleep(10 * time.Millisecond) // let the goroutine block on release
+	fc.Advance(time.Second)
+	close(release)
+	<-blocked
+
+	if len(events) != 2 {
+		t.Fatalf("got %d events, want 2", len(events))
+	}
+	if events[0].OpenerState != openerExited || events[0].OpenerStack != "" {
+		t.Errorf("expected the first opener to have exited, got %q", events[0].OpenerState)
+	}
+	if ev := events[1]; ev.OpenerState != "chan receive" || !strings.Contains(ev.OpenerStack, "TestOpenerStack.func") || len(ev.OpenerFrames) == 0 {
+		t.Errorf("unexpected opener of the second Rows: %q\n%s", ev.OpenerState, ev.OpenerStack)
+	}
+
+	out := logOutput.String()
+	for _, want := range []string{"\nthe opening goroutine has exited\n", "\nthe opening goroutine now:\ngoroutine "} {
+		if !strings.Contains(out, want) {
+			t.Errorf("expected %q in log, got:\n%s", want, out)
+		}
+	}
+}
@@ -0,0 +1,75 @@
+// Package otelsqleak ties sqleak leak reports to OpenTelemetry traces:
+//
+//	db, err := sqleak.Open("postgres", dsn, sqleak.WithTimeout(time.Minute), sqleak.WithTraceExtractor(otelsqleak.Extract))
+//
+// Leaks of resources opened with a context carrying a span, e.g. one started by otelsql or an HTTP middleware,
+// report the span's trace and span ID, and are recorded as events on the span if it is still open.
+// sqleak.WithContextSampling(otelsqleak.Sampled) monitors only resources opened within sampled traces.
+package otelsqleak
+
+import (
+	"context"
+
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
+
+	"github.com/saiko-tech/sqleak"
+)
+
+// LeakEventName is the name of the span events recording leaks.
+const LeakEventName = "sqleak.leak"
+
+// Extract returns the trace of the span in ctx for sqleak.WithTraceExtractor, adding a LeakEventName event
+// to the span for every leak report while the span is recording.
+func Extract(ctx context.Context) sqleak.Trace {
+	t := ExtractIDs(ctx)
+	if t.TraceID == "" {
+		return t
+	}
+
+	span := trace.SpanFromContext(ctx)
+	t.OnLeak = func(ev sqleak.LeakEvent) {
+		if span.IsRecording() {
+			span.AddEvent(LeakEventName, trace.WithAttributes(attributes(ev)...))
+		}
+	}
+
+	return t
+}
+
+// ExtractIDs returns the trace and span ID of the span in ctx for sqleak.WithTraceExtractor, without span events.
+func ExtractIDs(ctx context.Context) sqleak.Trace {
+	sc := trace.SpanContextFromContext(ctx)
+	if !sc.IsValid() {
+		return sqleak.Trace{}
+	}
+
+	return sqleak.Trace{TraceID: sc.TraceID().String(), SpanID: sc.SpanID().String()}
+}
+
+// Sampled reports whether ctx carries the span of a sampled trace, for sqleak.WithContextSampling.
+func Sampled(ctx context.Context) bool {
+	return trace.SpanContextFromContext(ctx).IsSampled()
+}
+
+func attributes(ev sqleak.LeakEvent) []attribute.KeyValue {
+	attrs := []attribute.KeyValue{
+		attribute.String("sqleak.kind", string(ev.Kind)),
+		attribute.Int64("sqleak.leak_id", int64(ev.LeakID)),
+		attribute.Int64("sqleak.resource_id", int64(ev.ResourceID)),
+		attribute.String("sqleak.severity", string(ev.Severity)),
+		attribute.String("sqleak.timeout", ev.Timeout.String()),
+		attribute.String("sqleak.open_for", ev.Age.String()),
+		attribute.Int("sqleak.warning", ev.Occurrence),
+	}
+	if ev.QueryFingerprint != "" {
+		attrs = append(attrs, attribute.String("sqleak.query_fingerprint", ev.QueryFingerprint))
+	}
+	if len(ev.Frames) > 0 {
+		f := ev.Frames[0]
+		attrs = append(attrs, attribute.String("code.function", f.Function), attribute.String("code.filepath", f.File),
+			attribute.Int("code.lineno", f.Line))
+	}
+
+	return attrs
+}
@@ -0,0 +1,64 @@
+package otelsqleak
+
+import (
+	"context"
+	"testing"
+
+	"go.opentelemetry.io/otel/trace"
+	"go.opentelemetry.io/otel/trace/noop"
+
+	"github.com/saiko-tech/sqleak"
+)
+
+// recordingSpan records the names of its events while not ended.
+type recordingSpan struct {
+	noop.Span
+	sc     trace.SpanContext
+	ended  bool
+	events []string
+}
+
+func (s *recordingSpan) SpanContext() trace.SpanContext { return s.sc }
+func (s *recordingSpan) IsRecording() bool              { return !s.ended }
+func (s *recordingSpan) AddEvent(name string, _ ...trace.EventOption) {
+	s.events = append(s.events, name)
+}
+
+func TestExtract(t *testing.T) {
+	if tr := Extract(context.Background()); tr.TraceID != "" || tr.OnLeak != nil {
+		t.Errorf("expected no trace without a span, got %+v", tr)
+	}
+
+	span := &recordingSpan{sc: trace.NewSpanContext(trace.SpanContextConfig{
+		TraceID: trace.TraceID{0x4b, 0xf9, 0x2f, 0x35, 0x77, 0xb3, 0x4d, 0xa6, 0xa3, 0xce, 0x92, 0x9d, 0x0e, 0x0e, 0x47, 0x36},
+		SpanID:  trace.SpanID{0x00, 0xf0, 0x67, 0xaa, 0x0b, 0xa9, 0x02, 0xb7},
+	})}
+	ctx := trace.ContextWithSpan(context.Background(), span)
+
+	tr := Extract(ctx)
+	if tr.TraceID != "4bf92f3577b34da6a3ce929d0e0e4736" || tr.SpanID != "00f067aa0ba902b7" || tr.OnLeak == nil {
+		t.Fatalf("unexpected trace %+v", tr)
+	}
+
+	tr.OnLeak(sqleak.LeakEvent{Type: sqleak.EventLeak, Kind: sqleak.KindRows})
+	span.ended = true
+	tr.OnLeak(sqleak.LeakEvent{Type: sqleak.EventLeak, Kind: sqleak.KindRows, Occurrence: 2})
+	if len(span.events) != 1 || span.events[0] != LeakEventName {
+		t.Errorf("expected one event on the recording span, got %v", span.events)
+	}
+
+	if ids := ExtractIDs(ctx); ids.TraceID != tr.TraceID || ids.OnLeak != nil {
+		t.Errorf("unexpected IDs %+v", ids)
+	}
+}
+
+func TestSampled(t *testing.T) {
+	config := trace.SpanContextConfig{TraceID: trace.TraceID{1}, SpanID: trace.SpanID{1}}
+	unsampled := trace.ContextWithSpanContext(context.Background(), trace.NewSpanContext(config))
+	config.TraceFlags = trace.FlagsSampled
+	sampled := trace.ContextWithSpanContext(context.Background(), trace.NewSpanContext(config))
+
+	if Sampled(context.Background()) || Sampled(unsampled) || !Sampled(sampled) {
+		t.Error("expected only the context of the sampled span to be sampled")
+	}
+}
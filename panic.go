@@ -0,0 +1,79 @@
+package sqleak
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"text/tabwriter"
+	"time"
+)
+
+// panicOutput is where DumpOnPanic writes, the standard error like the runtime's panic message.
+var panicOutput io.Writer = os.Stderr
+
+// DumpOnPanic prints a table of the open resources of every Detector in the process to standard error if the
+// goroutine is panicking, and then continues panicking with the same value. Defer it at the top of main and of
+// long-lived goroutines, so crash post-mortems show whether database resources were leaking:
+//
+//	func main() {
+//		defer sqleak.DumpOnPanic()
+//		...
+//	}
+//
+// It has to be deferred directly, as recover only stops panics in deferred calls.
+func DumpOnPanic() {
+	r := recover()
+	if r == nil {
+		return
+	}
+
+	writeOpenResources(panicOutput, registeredDetectors())
+	panic(r)
+}
+
+// writeOpenResources writes a table of the open resources of the detectors, oldest first per Detector.
+func writeOpenResources(w io.Writer, detectors []*Detector) {
+	total := 0
+	events := make([][]LeakEvent, len(detectors))
+	for i, d := range detectors {
+		events[i] = d.Outstanding()
+		total += len(events[i])
+	}
+
+	if total == 0 {
+		fmt.Fprintln(w, "sqleak: no open resources")
+		return
+	}
+
+	fmt.Fprintf(w, "sqleak: %d open resources:\n", total)
+	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(tw, "DB\tRESOURCE\tLEAK\tOPEN FOR\tGOROUTINE\tOPENED AT\tQUERY")
+	for i, d := range detectors {
+		db := d.driverName
+		if d.name != "" {
+			db = d.name
+		}
+
+		for _, ev := range events[i] {
+			leak := "-"
+			if ev.LeakID != 0 {
+				leak = fmt.Sprintf("#%d", ev.LeakID)
+			}
+			goroutine := "-"
+			if ev.Goroutine != 0 {
+				goroutine = fmt.Sprint(ev.Goroutine)
+			}
+			at := "-"
+			if len(ev.Frames) > 0 {
+				at = fmt.Sprintf("%s (%s:%d)", ev.Frames[0].Function, ev.Frames[0].File, ev.Frames[0].Line)
+			}
+			query := "-"
+			if ev.Query != "" {
+				query = shortenQuery(ev.Query)
+			}
+			fmt.Fprintf(tw, "%s\t%s #%d\t%s\t%s\t%s\t%s\t%s\n", db, ev.Kind, ev.ResourceID, leak,
+				ev.Age.Round(time.Millisecond), goroutine, at, query)
+		}
+	}
+	_ = tw.Flush()
+}
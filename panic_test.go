@@ -0,0 +1,51 @@
+//go:build !sqleak_noop
+
+package sqleak
+
+import (
+	"bytes"
+	"context"
+	"os"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestDumpOnPanic(t *testing.T) {
+	mc, fc, _ := newTestConn(t, WithTimeout(time.Minute))
+	var out bytes.Buffer
+	panicOutput = &out
+	t.Cleanup(func() { panicOutput = os.Stderr })
+
+	rows, err := mc.QueryContext(context.Background(), "SELECT  *\n FROM orders", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer rows.Close()
+	fc.Advance(2 * time.Second)
+
+	recovered := func() (r any) {
+		defer func() { r = recover() }()
+		defer DumpOnPanic()
+		panic("boom")
+	}()
+
+	if recovered != "boom" {
+		t.Errorf("expected the panic to continue with its value, got %v", recovered)
+	}
+	text := out.String()
+	for _, want := range []string{"sqleak: 1 open resources:\n", "DB ", "sqleak.fakeDriver", "Rows #1", "2s", "SELECT * FROM orders"} {
+		if !strings.Contains(text, want) {
+			t.Errorf("expected %q in dump, got:\n%s", want, text)
+		}
+	}
+
+	// Without panic, nothing is recovered or written.
+	out.Reset()
+	func() {
+		defer DumpOnPanic()
+	}()
+	if out.Len() != 0 {
+		t.Errorf("expected no output without a panic, got:\n%s", out.String())
+	}
+}
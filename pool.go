@@ -0,0 +1,93 @@
+package sqleak
+
+import (
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"time"
+)
+
+// WithPoolStats adds the stats of the connection pool at the time of the report to every leak report,
+// showing whether the leak actually starves the pool. stats is typically the Stats method of the *sql.DB;
+// nil uses the *sql.DB returned by Open or OpenDB, and does nothing for WrapDriver and WrapConnector.
+func WithPoolStats(stats func() sql.DBStats) Option {
+	return func(ld *monitoredDriver) {
+		ld.poolFromOpen = stats == nil
+		ld.poolStats = stats
+	}
+}
+
+// PoolStats are the sql.DBStats of the connection pool when a leak was reported, see WithPoolStats.
+type PoolStats struct {
+	// MaxOpenConnections is the limit set by SetMaxOpenConns, 0 for unlimited.
+	MaxOpenConnections int `json:"max_open_connections"`
+	OpenConnections    int `json:"open_connections"`
+	InUse              int `json:"in_use"`
+	Idle               int `json:"idle"`
+	// WaitCount and WaitDuration are the total number of and time spent waiting for a connection so far.
+	WaitCount    int64         `json:"wait_count"`
+	WaitDuration time.Duration `json:"-"`
+}
+
+func newPoolStats(s sql.DBStats) *PoolStats {
+	return &PoolStats{
+		MaxOpenConnections: s.MaxOpenConnections,
+		OpenConnections:    s.OpenConnections,
+		InUse:              s.InUse,
+		Idle:               s.Idle,
+		WaitCount:          s.WaitCount,
+		WaitDuration:       s.WaitDuration,
+	}
+}
+
+// attachPoolStats sets the pool stats of a leak report, with WithPoolStats.
+func (d *Detector) attachPoolStats(ev *LeakEvent) {
+	if d.poolStats != nil {
+		ev.Pool = newPoolStats(d.poolStats())
+	}
+}
+
+// describe summarizes the pool stats in headlines.
+func (p *PoolStats) describe() string {
+	inUse := fmt.Sprintf("pool %d in use", p.InUse)
+	if p.MaxOpenConnections > 0 {
+		inUse = fmt.Sprintf("pool %d/%d in use", p.InUse, p.MaxOpenConnections)
+	}
+
+	return fmt.Sprintf("%s, %d idle, %d waits for %s", inUse, p.Idle, p.WaitCount, p.WaitDuration.Round(time.Millisecond))
+}
+
+// poolJSON encodes PoolStats with the wait duration as a Go duration string.
+type poolJSON struct {
+	*PoolStats
+	WaitDuration string `json:"wait_duration"`
+}
+
+func (p *PoolStats) toJSON() *poolJSON {
+	if p == nil {
+		return nil
+	}
+
+	return &poolJSON{PoolStats: p, WaitDuration: p.WaitDuration.String()}
+}
+
+// poolDecodeJSON decodes PoolStats with a lenient wait duration.
+type poolDecodeJSON struct {
+	PoolStats
+	WaitDuration json.RawMessage `json:"wait_duration"`
+}
+
+func (v *poolDecodeJSON) decode() (*PoolStats, error) {
+	if v == nil {
+		return nil, nil
+	}
+
+	wait, err := decodeDuration(v.WaitDuration)
+	if err != nil {
+		return nil, fmt.Errorf("sqleak: invalid pool wait duration: %w", err)
+	}
+	p := v.PoolStats
+	p.WaitDuration = wait
+
+	return &p, nil
+}
@@ -0,0 +1,48 @@
+//go:build !sqleak_noop
+
+package sqleak
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestPoolStats(t *testing.T) {
+	stats := sql.DBStats{MaxOpenConnections: 10, OpenConnections: 10, InUse: 10, WaitCount: 3, WaitDuration: 2 * time.Second}
+	var events []LeakEvent
+	mc, fc, logOutput := newTestConn(t,
+		WithTimeout(time.Second),
+		WithPoolStats(func() sql.DBStats { return stats }),
+		WithOnLeak(func(ev LeakEvent) { events = append(events, ev) }),
+	)
+
+	rows, _ := mc.QueryContext(context.Background(), "SELECT 1", nil)
+	defer rows.Close()
+	fc.Advance(time.Second)
+
+	if len(events) != 1 || events[0].Pool == nil || events[0].Pool.InUse != 10 || events[0].Pool.WaitDuration != 2*time.Second {
+		t.Fatalf("expected the pool stats in the event, got %+v", events)
+	}
+	if want := ", pool 10/10 in use, 0 idle, 3 waits for 2s)"; !strings.Contains(logOutput.String(), want) {
+		t.Errorf("expected %q in log, got:\n%s", want, logOutput.String())
+	}
+
+	data, err := json.Marshal(events[0])
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := `"pool":{"max_open_connections":10,"open_connections":10,"in_use":10,"idle":0,"wait_count":3,"wait_duration":"2s"}`; !strings.Contains(string(data), want) {
+		t.Errorf("expected %s in JSON, got %s", want, data)
+	}
+	decoded, err := DecodeLeakEvent(data)
+	if err != nil || decoded.Pool == nil || *decoded.Pool != *events[0].Pool {
+		t.Errorf("unexpected pool stats after JSON round trip: %+v, %v", decoded.Pool, err)
+	}
+	if !mc.detector.Config().PoolStats {
+		t.Error("expected PoolStats in the config")
+	}
+}
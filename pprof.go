@@ -0,0 +1,43 @@
+package sqleak
+
+import (
+	"runtime/pprof"
+	"strings"
+	"sync"
+)
+
+// WithPprofProfiles adds every open resource with the stack opening it to the pprof profiles sqleak.rows,
+// sqleak.stmt and sqleak.tx, removing it once closed. Like the goroutine profile, they are served by
+// net/http/pprof, e.g. go tool pprof http://localhost:6060/debug/pprof/sqleak.rows shows the call sites
+// of all open Rows. The profiles are shared by all Detectors of the process.
+func WithPprofProfiles() Option {
+	return func(ld *monitoredDriver) {
+		ld.pprofProfiles = true
+	}
+}
+
+var (
+	pprofProfilesOnce sync.Once
+	pprofProfiles     [3]*pprof.Profile // by kindIndex
+)
+
+// pprofProfile returns the profile of resources of kind, created on first use as pprof.NewProfile panics for
+// names that are already registered.
+func pprofProfile(kind Kind) *pprof.Profile {
+	pprofProfilesOnce.Do(func() {
+		for _, kind := range []Kind{KindRows, KindStmt, KindTx} {
+			pprofProfiles[kindIndex(kind)] = pprof.NewProfile("sqleak." + strings.ToLower(string(kind)))
+		}
+	})
+
+	return pprofProfiles[kindIndex(kind)]
+}
+
+// addToProfile records the resource with the stack of newMonitor's caller.
+func (m *monitor) addToProfile() {
+	pprofProfile(m.kind).Add(m, 2)
+}
+
+func (m *monitor) removeFromProfile() {
+	pprofProfile(m.kind).Remove(m)
+}
@@ -0,0 +1,37 @@
+//go:build !sqleak_noop
+
+package sqleak
+
+import (
+	"bytes"
+	"context"
+	"testing"
+	"time"
+)
+
+func TestPprofProfiles(t *testing.T) {
+	mc, _, _ := newTestConn(t, WithTimeout(time.Second), WithPprofProfiles())
+	rowsProfile := pprofProfile(KindRows)
+	before := rowsProfile.Count()
+
+	open, _ := mc.QueryContext(context.Background(), "SELECT 1", nil)
+	closed, _ := mc.QueryContext(context.Background(), "SELECT 2", nil)
+	_ = closed.Close()
+
+	if n := rowsProfile.Count() - before; n != 1 {
+		t.Errorf("expected 1 open Rows in the profile, got %d", n)
+	}
+
+	var buf bytes.Buffer
+	if err := rowsProfile.WriteTo(&buf, 1); err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Contains(buf.Bytes(), []byte("sqleak.rows profile")) || !bytes.Contains(buf.Bytes(), []byte("TestPprofProfiles")) {
+		t.Errorf("expected the opening stack in the profile, got:\n%s", buf.String())
+	}
+
+	_ = open.Close()
+	if n := rowsProfile.Count() - before; n != 0 {
+		t.Errorf("expected closed Rows to be removed from the profile, %d left", n)
+	}
+}
@@ -0,0 +1,23 @@
+package promsqleak_test
+
+import (
+	"log"
+	"time"
+
+	_ "github.com/mattn/go-sqlite3"
+	"github.com/prometheus/client_golang/prometheus"
+
+	"github.com/saiko-tech/sqleak"
+	"github.com/saiko-tech/sqleak/promsqleak"
+)
+
+func ExampleNewCollector() {
+	collector := promsqleak.NewCollector()
+	prometheus.MustRegister(collector)
+
+	db, err := sqleak.Open("sqlite3", ":memory:", sqleak.WithTimeout(time.Minute), sqleak.WithHooks(collector.Hooks()))
+	if err != nil {
+		log.Fatalf("failed to open DB: %v", err)
+	}
+	defer db.Close()
+}
@@ -0,0 +1,151 @@
+// Package promsqleak exposes metrics of sqleak Detectors as a prometheus.Collector:
+//
+//	collector := promsqleak.NewCollector()
+//	prometheus.MustRegister(collector)
+//	db, err := sqleak.Open("postgres", dsn, sqleak.WithTimeout(time.Minute), sqleak.WithHooks(collector.Hooks()))
+//
+// The metrics are labeled by resource kind and the Detector's name set by sqleak.WithName:
+//
+//   - sqleak_open_resources, a gauge of the currently open Rows, Stmt and Tx
+//   - sqleak_leaks_total, a counter of detected leaks, also labeled by the query fingerprint
+//   - sqleak_resource_lifetime_seconds, a histogram of how long resources were open until closed
+//   - sqleak_rows_fetched, a histogram of the number of rows read from Rows until closed
+//
+// WithLabels adds labels from sqleak.Annotate and sqleak.WithContextAttrs to all of them.
+package promsqleak
+
+import (
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+
+	"github.com/saiko-tech/sqleak"
+)
+
+// DefaultBuckets are the lifetime histogram buckets in seconds, from a millisecond to above an hour.
+var DefaultBuckets = prometheus.ExponentialBuckets(0.001, 4, 12)
+
+// RowsBuckets are the buckets of the rows fetched histogram, from no rows to a million.
+var RowsBuckets = append([]float64{0}, prometheus.ExponentialBuckets(1, 10, 7)...)
+
+// Collector collects the metrics of the resources it observes through its Hooks. One collector may observe
+// several Detectors, tell them apart with sqleak.WithName.
+type Collector struct {
+	open      *prometheus.GaugeVec
+	leaks     *prometheus.CounterVec
+	lifetimes *prometheus.HistogramVec
+	fetched   *prometheus.HistogramVec
+	labels    []string // attributes added as labels, see WithLabels
+}
+
+// Option configures a Collector.
+type Option func(*options)
+
+type options struct {
+	namespace string
+	buckets   []float64
+	labels    []string
+}
+
+// WithNamespace replaces the "sqleak" namespace of the metric names.
+func WithNamespace(namespace string) Option {
+	return func(o *options) {
+		o.namespace = namespace
+	}
+}
+
+// WithBuckets replaces DefaultBuckets of the lifetime histogram.
+func WithBuckets(buckets ...float64) Option {
+	return func(o *options) {
+		o.buckets = buckets
+	}
+}
+
+// WithLabels adds labels with the values of the given attributes of resources, set by sqleak.Annotate or
+// sqleak.WithContextAttrs, e.g. "tenant" or "route", empty for resources without them. Keep the number of their
+// values low, every combination is a time series; request IDs don't make good labels.
+func WithLabels(keys ...string) Option {
+	return func(o *options) {
+		o.labels = append(o.labels, keys...)
+	}
+}
+
+// NewCollector returns a collector to register with Prometheus.
+func NewCollector(opts ...Option) *Collector {
+	o := options{namespace: "sqleak", buckets: DefaultBuckets}
+	for _, opt := range opts {
+		opt(&o)
+	}
+
+	return &Collector{
+		open: prometheus.NewGaugeVec(prometheus.GaugeOpts{
+			Namespace: o.namespace,
+			Name:      "open_resources",
+			Help:      "Number of currently open database/sql resources.",
+		}, append([]string{"kind", "name"}, o.labels...)),
+		leaks: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: o.namespace,
+			Name:      "leaks_total",
+			Help:      "Number of resources not closed within the leak timeout.",
+		}, append([]string{"kind", "name", "query_fingerprint"}, o.labels...)),
+		lifetimes: prometheus.NewHistogramVec(prometheus.HistogramOpts{
+			Namespace: o.namespace,
+			Name:      "resource_lifetime_seconds",
+			Help:      "Time resources were open until closed.",
+			Buckets:   o.buckets,
+		}, append([]string{"kind", "name"}, o.labels...)),
+		fetched: prometheus.NewHistogramVec(prometheus.HistogramOpts{
+			Namespace: o.namespace,
+			Name:      "rows_fetched",
+			Help:      "Number of rows read from Rows until closed.",
+			Buckets:   RowsBuckets,
+		}, append([]string{"name"}, o.labels...)),
+		labels: o.labels,
+	}
+}
+
+// Hooks returns the hooks to pass to sqleak.WithHooks for every Detector the collector observes.
+func (c *Collector) Hooks() sqleak.Hooks {
+	return sqleak.Hooks{
+		OnOpen: func(r sqleak.Resource) {
+			c.open.WithLabelValues(c.values(r.Attrs, string(r.Kind), r.Name)...).Inc()
+		},
+		OnClose: func(r sqleak.Resource, openFor time.Duration) {
+			c.open.WithLabelValues(c.values(r.Attrs, string(r.Kind), r.Name)...).Dec()
+			c.lifetimes.WithLabelValues(c.values(r.Attrs, string(r.Kind), r.Name)...).Observe(openFor.Seconds())
+			if r.Kind == sqleak.KindRows {
+				c.fetched.WithLabelValues(c.values(r.Attrs, r.Name)...).Observe(float64(r.RowsFetched))
+			}
+		},
+		OnLeak: func(ev sqleak.LeakEvent) {
+			if ev.Type == sqleak.EventLeak && ev.Occurrence == 1 {
+				c.leaks.WithLabelValues(c.values(ev.Attrs, string(ev.Kind), ev.Name, ev.QueryFingerprint)...).Inc()
+			}
+		},
+	}
+}
+
+// values returns the given label values followed by those of the attributes of WithLabels.
+func (c *Collector) values(attrs map[string]string, values ...string) []string {
+	for _, key := range c.labels {
+		values = append(values, attrs[key])
+	}
+
+	return values
+}
+
+// Describe implements prometheus.Collector.
+func (c *Collector) Describe(ch chan<- *prometheus.Desc) {
+	c.open.Describe(ch)
+	c.leaks.Describe(ch)
+	c.lifetimes.Describe(ch)
+	c.fetched.Describe(ch)
+}
+
+// Collect implements prometheus.Collector.
+func (c *Collector) Collect(ch chan<- prometheus.Metric) {
+	c.open.Collect(ch)
+	c.leaks.Collect(ch)
+	c.lifetimes.Collect(ch)
+	c.fetched.Collect(ch)
+}
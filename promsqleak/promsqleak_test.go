@@ -0,0 +1,100 @@
+package promsqleak
+
+import (
+	"testing"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+
+	"github.com/saiko-tech/sqleak"
+)
+
+func TestCollector(t *testing.T) {
+	c := NewCollector(WithBuckets(1, 10))
+	registry := prometheus.NewPedanticRegistry()
+	registry.MustRegister(c)
+
+	hooks := c.Hooks()
+	rows := sqleak.Resource{Kind: sqleak.KindRows, Name: "orders", RowsFetched: 42}
+	hooks.OnOpen(rows)
+	hooks.OnOpen(rows)
+	hooks.OnOpen(sqleak.Resource{Kind: sqleak.KindTx, Name: "orders"})
+	hooks.OnLeak(sqleak.LeakEvent{Type: sqleak.EventLeak, Kind: sqleak.KindRows, Name: "orders", QueryFingerprint: "abc", Occurrence: 1})
+	hooks.OnLeak(sqleak.LeakEvent{Type: sqleak.EventLeak, Kind: sqleak.KindRows, Name: "orders", QueryFingerprint: "abc", Occurrence: 2})
+	hooks.OnLeak(sqleak.LeakEvent{Type: sqleak.EventClosedLate, Kind: sqleak.KindRows, Name: "orders", QueryFingerprint: "abc"})
+	hooks.OnClose(rows, 2*time.Second)
+
+	families, err := registry.Gather()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	got := map[string]float64{}
+	for _, family := range families {
+		for _, m := range family.GetMetric() {
+			key := family.GetName()
+			for _, label := range m.GetLabel() {
+				key += " " + label.GetName() + "=" + label.GetValue()
+			}
+			switch {
+			case m.Gauge != nil:
+				got[key] = m.GetGauge().GetValue()
+			case m.Counter != nil:
+				got[key] = m.GetCounter().GetValue()
+			case m.Histogram != nil:
+				got[key] = m.GetHistogram().GetSampleSum()
+				got[key+" count"] = float64(m.GetHistogram().GetSampleCount())
+			}
+		}
+	}
+
+	for key, want := range map[string]float64{
+		"sqleak_open_resources kind=Rows name=orders":                    1,
+		"sqleak_open_resources kind=Tx name=orders":                      1,
+		"sqleak_leaks_total kind=Rows name=orders query_fingerprint=abc": 1,
+		"sqleak_resource_lifetime_seconds kind=Rows name=orders":         2,
+		"sqleak_resource_lifetime_seconds kind=Rows name=orders count":   1,
+		"sqleak_rows_fetched name=orders":                                42,
+	} {
+		if got[key] != want {
+			t.Errorf("%s = %v, want %v; all metrics: %v", key, got[key], want, got)
+		}
+	}
+}
+
+func TestCollectorLabels(t *testing.T) {
+	c := NewCollector(WithLabels("tenant"))
+	registry := prometheus.NewPedanticRegistry()
+	registry.MustRegister(c)
+
+	hooks := c.Hooks()
+	hooks.OnOpen(sqleak.Resource{Kind: sqleak.KindRows, Attrs: map[string]string{"tenant": "acme", "request_id": "r-1"}})
+	hooks.OnOpen(sqleak.Resource{Kind: sqleak.KindRows})
+	hooks.OnLeak(sqleak.LeakEvent{Type: sqleak.EventLeak, Kind: sqleak.KindRows, Attrs: map[string]string{"tenant": "acme"}, Occurrence: 1})
+
+	families, err := registry.Gather()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	got := map[string]float64{}
+	for _, family := range families {
+		for _, m := range family.GetMetric() {
+			key := family.GetName()
+			for _, label := range m.GetLabel() {
+				key += " " + label.GetName() + "=" + label.GetValue()
+			}
+			got[key] = m.GetGauge().GetValue() + m.GetCounter().GetValue()
+		}
+	}
+
+	for key, want := range map[string]float64{
+		"sqleak_open_resources kind=Rows name= tenant=acme":                 1,
+		"sqleak_open_resources kind=Rows name= tenant=":                     1,
+		"sqleak_leaks_total kind=Rows name= query_fingerprint= tenant=acme": 1,
+	} {
+		if got[key] != want {
+			t.Errorf("%s = %v, want %v; all metrics: %v", key, got[key], want, got)
+		}
+	}
+}
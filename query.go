@@ -0,0 +1,128 @@
+package sqleak
+
+import (
+	"encoding/hex"
+	"hash/fnv"
+	"regexp"
+	"strings"
+)
+
+// WithQueryNormalizer replaces NormalizeQuery as the function reducing queries to their statement shape,
+// from which LeakEvent.QueryFingerprint is computed.
+func WithQueryNormalizer(normalize func(query string) string) Option {
+	return func(ld *monitoredDriver) {
+		ld.normalize = normalize
+	}
+}
+
+// WithShouldMonitor excludes the Rows and Stmt of queries for which shouldMonitor returns false from monitoring
+// entirely, as if opened with a context from Ignore, e.g. health checks, advisory locks or long-lived LISTEN queries.
+// It's called with the query text before anonymization on every open, Tx are always monitored.
+func WithShouldMonitor(shouldMonitor func(query string) bool) Option {
+	return func(ld *monitoredDriver) {
+		ld.shouldMonitor = shouldMonitor
+	}
+}
+
+// NormalizeQuery reduces a query to its statement shape: comments are removed, string and numeric literals
+// as well as numbered placeholders like $1 become ?, lists of placeholders like IN (?, ?, ?) collapse into (?),
+// and whitespace collapses into single spaces. Queries differing only in their parameters normalize equally,
+// e.g. "SELECT * FROM users WHERE id IN (1, 2) -- admin" to "SELECT * FROM users WHERE id IN (?)".
+func NormalizeQuery(query string) string {
+	var b strings.Builder
+	b.Grow(len(query))
+
+	space := false
+	for i := 0; i < len(query); i++ {
+		c := query[i]
+
+		switch {
+		case c == '-' && strings.HasPrefix(query[i:], "--"):
+			end := strings.IndexByte(query[i:], '\n')
+			if end < 0 {
+				end = len(query) - i
+			}
+			i += end - 1
+			space = true
+			continue
+		case c == '/' && strings.HasPrefix(query[i:], "/*"):
+			end := strings.Index(query[i+2:], "*/")
+			if end < 0 {
+				end = len(query) - i - 2
+			}
+			i += end + 3
+			space = true
+			continue
+		case c == ' ' || c == '\t' || c == '\n' || c == '\r':
+			space = true
+			continue
+		}
+
+		if space && b.Len() > 0 {
+			b.WriteByte(' ')
+		}
+		space = false
+
+		switch {
+		case c == '\'':
+			i = skipStringLiteral(query, i)
+			b.WriteByte('?')
+		case isDigit(c) && (i == 0 || !isIdentByte(query[i-1])),
+			c == '$' && i+1 < len(query) && isDigit(query[i+1]):
+			for i+1 < len(query) && (isIdentByte(query[i+1]) || query[i+1] == '.') {
+				i++
+			}
+			b.WriteByte('?')
+		default:
+			b.WriteByte(c)
+		}
+	}
+
+	return placeholderListPattern.ReplaceAllString(b.String(), "(?)")
+}
+
+var placeholderListPattern = regexp.MustCompile(`\(\s*\?(?:\s*,\s*\?)*\s*\)`)
+
+// skipStringLiteral returns the index of the quote closing the string literal starting at i.
+// Quotes are escaped by doubling them or, as in MySQL, by a backslash.
+func skipStringLiteral(query string, i int) int {
+	for i++; i < len(query); i++ {
+		switch query[i] {
+		case '\\':
+			i++
+		case '\'':
+			if i+1 < len(query) && query[i+1] == '\'' {
+				i++
+				continue
+			}
+			return i
+		}
+	}
+
+	return len(query)
+}
+
+func isDigit(c byte) bool {
+	return c >= '0' && c <= '9'
+}
+
+func isIdentByte(c byte) bool {
+	return c == '_' || isDigit(c) || c >= 'a' && c <= 'z' || c >= 'A' && c <= 'Z' || c >= 0x80
+}
+
+// queryFingerprint hashes the shape of a query, see NormalizeQuery and WithQueryNormalizer.
+func (d *Detector) queryFingerprint(query string) string {
+	if query == "" {
+		return ""
+	}
+
+	normalize := d.normalize
+	if normalize == nil {
+		normalize = NormalizeQuery
+	}
+
+	h := fnv.New64a()
+	_, _ = h.Write([]byte(normalize(query)))
+
+	return hex.EncodeToString(h.Sum(nil))
+}
@@ -0,0 +1,34 @@
+package sqleak
+
+import (
+	"database/sql"
+	"fmt"
+	"slices"
+	"time"
+)
+
+// Register wraps the driver registered as driverName with leak detection and registers the wrapped driver as name,
+// for code and frameworks that only take a driver name, e.g. sql.Open("postgres-leakcheck", dsn) with sqlx or goose.
+// All databases opened by name share the returned Detector. WithPoolStats(nil) has no effect, as there's no DB
+// returned by Open. Unlike sql.Register, it returns an error if name is taken already.
+func Register(name, driverName string, opts ...Option) (*Detector, error) {
+	if slices.Contains(sql.Drivers(), name) {
+		return nil, fmt.Errorf("sqleak: driver %q is already registered", name)
+	}
+
+	db, err := sql.Open(driverName, "")
+	if err != nil {
+		return nil, err
+	}
+	d := db.Driver()
+	if err = db.Close(); err != nil {
+		return nil, err
+	}
+
+	ld := newMonitoredDriver(d, 30*time.Second) // default timeout of 30 seconds, can be overridden by options
+	ld.driverName = driverName
+	ld.configure(opts)
+	sql.Register(name, ld)
+
+	return ld.Detector, nil
+}
@@ -0,0 +1,88 @@
+package sqleak
+
+import (
+	"fmt"
+	"log"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// maxPreparedQueries bounds the queries tracked per connection by WithRepreparedWarning.
+const maxPreparedQueries = 256
+
+// WithRepreparedWarning warns when the same query is prepared count times within window on one connection, each
+// time after the statement prepared before was closed already. Preparing a statement per execution costs a round
+// trip to the database for every prepare and close; prepare it once and reuse the sql.Stmt instead, or run the
+// query without Prepare. The warning names the application function preparing the statement and is logged once
+// per query and connection, Stats().Reprepares counts the warnings.
+func WithRepreparedWarning(count int, window time.Duration) Option {
+	return func(ld *monitoredDriver) {
+		ld.reprepared = &repreparedWarning{count: count, window: window}
+	}
+}
+
+type repreparedWarning struct {
+	count  int
+	window time.Duration
+	warned atomic.Int64
+}
+
+// prepareTracker counts the prepares of the queries of a single connection.
+type prepareTracker struct {
+	mu      sync.Mutex
+	queries map[string]*preparedQuery
+}
+
+type preparedQuery struct {
+	since  time.Time // start of the current window
+	count  int
+	last   *monitor // of the Stmt prepared last
+	warned bool
+}
+
+// prepared counts a prepare of query on the connection, with WithRepreparedWarning.
+func (mc *monitoredConn) prepared(query string, m *monitor) {
+	t := mc.prepares
+	if t == nil {
+		return
+	}
+	d := mc.detector
+	w := d.reprepared
+	now := d.clock.Now()
+
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	q := t.queries[query]
+	if q == nil {
+		if len(t.queries) >= maxPreparedQueries {
+			clear(t.queries)
+		}
+		q = &preparedQuery{since: now}
+		t.queries[query] = q
+	}
+
+	// Statements prepared while the previous one is still open are in use side by side, not prepared per execution.
+	switch {
+	case q.last != nil && q.last.state.Load() != stateClosed:
+	case now.Sub(q.since) > w.window:
+		q.since, q.count = now, 1
+	default:
+		q.count++
+	}
+	q.last = m
+
+	if q.count < w.count || q.warned {
+		return
+	}
+	q.warned = true
+	w.warned.Add(1)
+
+	msg := fmt.Sprintf("%sstatement prepared %d times within %s on the same connection, closed after each use: %q",
+		d.logPrefix(), q.count, w.window, shortenQuery(d.anonymizeQuery(query)))
+	if f, ok := applicationCaller(); ok {
+		msg += fmt.Sprintf(" prepared by %s (%s:%d)", f.Function, f.File, f.Line)
+	}
+	log.Print(msg + "; prepare it once and reuse the statement, or run the query without Prepare")
+}
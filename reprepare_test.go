@@ -0,0 +1,49 @@
+//go:build !sqleak_noop
+
+package sqleak
+
+import (
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestRepreparedWarning(t *testing.T) {
+	mc, fc, logOutput := newTestConn(t, WithTimeout(time.Hour), WithRepreparedWarning(3, time.Second))
+
+	prepare := func(query string) {
+		stmt, err := mc.Prepare(query)
+		if err != nil {
+			t.Fatal(err)
+		}
+		stmt.Close()
+	}
+
+	// Spread beyond the window, or kept open side by side.
+	for i := 0; i < 4; i++ {
+		prepare("SELECT 1")
+		fc.Advance(600 * time.Millisecond)
+	}
+	held, _ := mc.Prepare("SELECT 2")
+	defer held.Close()
+	prepare("SELECT 2")
+	prepare("SELECT 2")
+	if out := logOutput.String(); out != "" {
+		t.Fatalf("expected no warning, got:\n%s", out)
+	}
+
+	for i := 0; i < 5; i++ {
+		prepare("SELECT 3")
+	}
+
+	out := logOutput.String()
+	if want := `statement prepared 3 times within 1s on the same connection, closed after each use: "SELECT 3" prepared by `; !strings.Contains(out, want) {
+		t.Errorf("expected %q in log, got:\n%s", want, out)
+	}
+	if n := strings.Count(out, "statement prepared"); n != 1 {
+		t.Errorf("got %d warnings, want 1 per query:\n%s", n, out)
+	}
+	if n := mc.detector.Stats().Reprepares; n != 1 {
+		t.Errorf("got %d reprepares, want 1", n)
+	}
+}
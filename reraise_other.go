@@ -0,0 +1,9 @@
+//go:build !unix
+
+package sqleak
+
+import "os"
+
+// reraise does nothing: signals can't be sent to the own process outside of unix, and exiting would cut short the
+// application's own shutdown.
+func reraise(os.Signal) {}
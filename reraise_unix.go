@@ -0,0 +1,15 @@
+//go:build unix
+
+package sqleak
+
+import (
+	"os"
+	"syscall"
+)
+
+// reraise sends sig to the own process again, once it's no longer notified, so its default action takes place.
+func reraise(sig os.Signal) {
+	if s, ok := sig.(syscall.Signal); ok {
+		_ = syscall.Kill(os.Getpid(), s)
+	}
+}
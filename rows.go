@@ -1,9 +1,12 @@
 package sqleak
 
 import (
+	"context"
 	"database/sql/driver"
 	"io"
-	"time"
+	"reflect"
+	"sync"
+	"sync/atomic"
 )
 
 var (
@@ -13,18 +16,69 @@ var (
 	_ driver.RowsColumnTypeLength           = (*monitoredRows)(nil)
 	_ driver.RowsColumnTypeNullable         = (*monitoredRows)(nil)
 	_ driver.RowsColumnTypePrecisionScale   = (*monitoredRows)(nil)
+	_ driver.RowsColumnTypeScanType         = (*monitoredRows)(nil)
 )
 
 type monitoredRows struct {
 	driver.Rows
 	monitor *monitor
+	conn    *monitoredConn
+
+	// With WithAutoClose, mu serializes the application's use of the Rows with closing them from the timer.
+	guarded    bool
+	mu         sync.Mutex
+	closed     bool
+	autoClosed bool
+
+	lastNext atomic.Int64 // Unix nanoseconds of the last call to Next, with WithStallTimeout
 }
 
-func newMonitoredRows(rows driver.Rows, timeout time.Duration) *monitoredRows {
-	return &monitoredRows{
+func newMonitoredRows(ctx context.Context, rows driver.Rows, mc *monitoredConn, query string, args []driver.NamedValue) *monitoredRows {
+	var columns []Column
+	if mc.detector.columnMetadata {
+		columns = rowsColumns(rows)
+	}
+
+	r := &monitoredRows{
 		Rows:    rows,
-		monitor: newMonitor(timeout, "Rows"),
+		conn:    mc,
+		guarded: mc.detector.autoClose > 0,
 	}
+	r.monitor = newMonitor(ctx, mc, KindRows, query, args, columns, !mc.inTx.Load())
+	if r.guarded {
+		r.monitor.reclaim = r.autoClose
+	}
+	if d := mc.detector; d.stallTimeout > 0 && !r.monitor.ignored {
+		r.nextCalled()
+		d.clock.AfterFunc(d.stallTimeout, r.checkStall)
+	}
+
+	return r
+}
+
+// autoClose closes the Rows for WithAutoClose unless the application closed them already.
+func (r *monitoredRows) autoClose() (bool, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if r.closed {
+		return false, nil
+	}
+	r.closed = true
+	r.autoClosed = true
+	r.monitor.reclaimed.Store(true)
+	r.monitor.markClosed()
+
+	return true, r.Rows.Close()
+}
+
+func (r *monitoredRows) ColumnTypeScanType(index int) reflect.Type {
+	if v, ok := r.Rows.(driver.RowsColumnTypeScanType); ok {
+		return v.ColumnTypeScanType(index)
+	}
+
+	// Same fallback as database/sql uses for drivers without scan type information.
+	return reflect.TypeOf(new(any)).Elem()
 }
 
 func (r *monitoredRows) ColumnTypePrecisionScale(index int) (precision, scale int64, ok bool) {
@@ -60,6 +114,18 @@ func (r *monitoredRows) ColumnTypeDatabaseTypeName(index int) string {
 }
 
 func (r *monitoredRows) HasNextResultSet() bool {
+	if r.guarded {
+		r.mu.Lock()
+		defer r.mu.Unlock()
+		if r.autoClosed {
+			return false
+		}
+	}
+
+	return r.hasNextResultSet()
+}
+
+func (r *monitoredRows) hasNextResultSet() bool {
 	if v, ok := r.Rows.(driver.RowsNextResultSet); ok {
 		return v.HasNextResultSet()
 	}
@@ -68,19 +134,66 @@ func (r *monitoredRows) HasNextResultSet() bool {
 }
 
 func (r *monitoredRows) NextResultSet() error {
+	if r.guarded {
+		r.mu.Lock()
+		defer r.mu.Unlock()
+		if r.autoClosed {
+			return ErrAutoClosed
+		}
+	}
+
 	if v, ok := r.Rows.(driver.RowsNextResultSet); ok {
-		return v.NextResultSet()
+		r.conn.enter()
+		defer r.conn.leave()
+
+		err := v.NextResultSet()
+		if err == nil {
+			r.monitor.drained.Store(false)
+		}
+		return err
 	}
 
 	return io.EOF
 }
 
 func (r *monitoredRows) Close() error {
-	r.monitor.markClosed()
+	r.monitor.closing("Close")
+	if r.guarded {
+		r.mu.Lock()
+		defer r.mu.Unlock()
+		if r.closed {
+			return nil
+		}
+		r.closed = true
+	}
+
+	r.conn.enter()
+	defer r.conn.leave()
 
-	return r.Rows.Close()
+	return r.monitor.closeWith("Close", r.Rows.Close)
 }
 
 func (r *monitoredRows) Next(dest []driver.Value) (err error) {
-	return r.Rows.Next(dest)
+	if r.guarded {
+		r.mu.Lock()
+		defer r.mu.Unlock()
+		if r.autoClosed {
+			return ErrAutoClosed
+		}
+	}
+	r.monitor.using("Next")
+	r.monitor.touch()
+	r.nextCalled()
+
+	r.conn.enter()
+	err = r.Rows.Next(dest)
+	r.conn.leave()
+	switch {
+	case err == nil:
+		r.monitor.fetched.Add(1)
+	case err == io.EOF && !r.hasNextResultSet():
+		r.monitor.drained.Store(true)
+	}
+
+	return err
 }
@@ -0,0 +1,56 @@
+package sqleak
+
+import (
+	"context"
+	"fmt"
+	rtrace "runtime/trace"
+)
+
+// WithRuntimeTrace creates a runtime/trace task per resource while an execution trace is running, named like
+// "sqleak.Rows" and ended when the resource is closed. go tool trace then shows the lifetime of every Rows, Stmt and
+// Tx in the context of the opening code's tasks, and leaked resources as tasks that never end. The query and leak
+// reports are logged to the task.
+func WithRuntimeTrace() Option {
+	return func(ld *monitoredDriver) {
+		ld.runtimeTrace = true
+	}
+}
+
+// runtimeTraceCategory is the category of runtime/trace log messages.
+const runtimeTraceCategory = "sqleak"
+
+// startTask creates the resource's task if an execution trace is running.
+func (m *monitor) startTask(ctx context.Context) {
+	if !rtrace.IsEnabled() {
+		return
+	}
+
+	m.taskCtx, m.task = rtrace.NewTask(ctx, "sqleak."+string(m.kind))
+	rtrace.Logf(m.taskCtx, runtimeTraceCategory, "resource #%d opened", m.id)
+	if m.query != "" {
+		rtrace.Log(m.taskCtx, "query", m.detector.anonymizeQuery(m.query))
+	}
+}
+
+// logTask logs a report of the resource to its task.
+func (m *monitor) logTask(ev LeakEvent) {
+	if m.task == nil {
+		return
+	}
+
+	msg := fmt.Sprintf("leak #%d: %s not closed within %s", ev.LeakID, ev.Kind, ev.Timeout)
+	if ev.Occurrence > 1 {
+		msg = fmt.Sprintf("leak #%d: warning #%d, open for %s", ev.LeakID, ev.Occurrence, ev.Age)
+	}
+	rtrace.Log(m.taskCtx, runtimeTraceCategory, msg)
+}
+
+// endTask ends the resource's task once it is closed.
+func (m *monitor) endTask() {
+	if m.task == nil {
+		return
+	}
+
+	rtrace.Log(m.taskCtx, runtimeTraceCategory, "closed")
+	m.task.End()
+}
@@ -0,0 +1,44 @@
+//go:build !sqleak_noop
+
+package sqleak
+
+import (
+	"bytes"
+	"context"
+	rtrace "runtime/trace"
+	"testing"
+	"time"
+)
+
+func TestRuntimeTrace(t *testing.T) {
+	mc, fc, _ := newTestConn(t, WithTimeout(time.Second), WithRuntimeTrace())
+
+	var buf bytes.Buffer
+	if err := rtrace.Start(&buf); err != nil {
+		t.Skipf("execution trace already running: %v", err)
+	}
+
+	closed, _ := mc.QueryContext(context.Background(), "SELECT 1", nil)
+	_ = closed.Close()
+	leaked, _ := mc.QueryContext(context.Background(), "SELECT 2", nil)
+	defer leaked.Close()
+	fc.Advance(time.Second)
+
+	rtrace.Stop()
+
+	if task := leaked.(*monitoredRows).monitor.task; task == nil {
+		t.Fatal("expected a task while tracing")
+	}
+	for _, want := range []string{"sqleak.Rows", "SELECT 2", "leak #1: Rows not closed within 1s"} {
+		if !bytes.Contains(buf.Bytes(), []byte(want)) {
+			t.Errorf("expected %q in the execution trace", want)
+		}
+	}
+
+	// Without a running trace no tasks are created.
+	rows, _ := mc.QueryContext(context.Background(), "SELECT 3", nil)
+	defer rows.Close()
+	if rows.(*monitoredRows).monitor.task != nil {
+		t.Error("expected no task without a running trace")
+	}
+}
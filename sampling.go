@@ -0,0 +1,146 @@
+package sqleak
+
+import (
+	"context"
+	"math/rand/v2"
+	"runtime"
+	"sync"
+	"time"
+)
+
+// WithAdaptiveSampling monitors resources depending on how often their call site opens them.
+// Call sites opening up to perSite resources per window are always monitored,
+// hotter call sites are downsampled to about perSite monitored resources per window.
+// This keeps the overhead on hot paths bounded without making leaks from rarely used code paths
+// statistically invisible, as uniform sampling would.
+func WithAdaptiveSampling(perSite int, window time.Duration) Option {
+	return func(ld *monitoredDriver) {
+		s := ld.siteSampler()
+		s.perSite = int64(perSite)
+		s.window = window
+	}
+}
+
+// WithFirstCaptures guarantees that the first n resources opened by every call site are monitored with full stacks,
+// regardless of sampling. Resources opened afterwards are subject to sampling as usual.
+func WithFirstCaptures(n int) Option {
+	return func(ld *monitoredDriver) {
+		ld.siteSampler().firstN = int64(n)
+	}
+}
+
+// WithSampleRate monitors only the given fraction of opened resources, chosen at random, e.g. 0.01 for 1%.
+// Unmonitored resources skip stack capture and timers entirely, which keeps leak detection affordable at high query rates.
+// Rates are clamped to [0, 1]. Combined with WithAdaptiveSampling both apply, WithFirstCaptures takes precedence over either.
+func WithSampleRate(rate float64) Option {
+	return func(ld *monitoredDriver) {
+		ld.siteSampler().rate = min(max(rate, 0), 1)
+	}
+}
+
+// WithContextSampling monitors only resources opened with a context for which sampled returns true, e.g.
+// otelsqleak.Sampled for contexts of sampled traces, so the overhead scales with the tracing sample rate instead of
+// the query rate. Resources opened without a context, by Prepare or Begin, are checked with context.Background().
+// The other sampling options apply to the resources it lets through, WithFirstCaptures doesn't override it.
+func WithContextSampling(sampled func(ctx context.Context) bool) Option {
+	return func(ld *monitoredDriver) {
+		ld.sampleContext = sampled
+	}
+}
+
+// siteSampler returns the sampler, creating it on first use so sampling options compose.
+func (d *Detector) siteSampler() *siteSampler {
+	if d.sampler == nil {
+		d.sampler = &siteSampler{rate: 1}
+	}
+
+	return d.sampler
+}
+
+// maxCallSiteDepth bounds the number of program counters used to identify a call site.
+const maxCallSiteDepth = 32
+
+// callSite identifies the calling code path by hashing its program counters,
+// which is much cheaper than capturing a formatted stack trace.
+func callSite(skip int) uint64 {
+	var pcs [maxCallSiteDepth]uintptr
+	n := runtime.Callers(skip+1, pcs[:])
+
+	// FNV-1a
+	h := uint64(14695981039346656037)
+	for _, pc := range pcs[:n] {
+		h ^= uint64(pc)
+		h *= 1099511628211
+	}
+
+	return h
+}
+
+type siteSampler struct {
+	perSite int64 // 0 disables adaptive sampling
+	window  time.Duration
+	firstN  int64
+	rate    float64 // fraction of resources monitored, 1 unless WithSampleRate is set
+
+	overhead *overhead
+
+	sites sync.Map // uint64 -> *siteCounter
+}
+
+type siteCounter struct {
+	mu          sync.Mutex
+	windowStart time.Time
+	count       int64 // opens in the current window
+	prev        int64 // opens in the previous window
+	total       int64
+}
+
+// sample records an open at site and decides whether the resource should be monitored.
+// perCallSite reports whether sampling depends on the call site, which is only computed if it does.
+func (s *siteSampler) perCallSite() bool {
+	return s.perSite > 0 || s.firstN > 0
+}
+
+func (s *siteSampler) sample(site uint64, now time.Time) bool {
+	if !s.perCallSite() {
+		return s.sampleRate()
+	}
+
+	v, ok := s.sites.Load(site)
+	if !ok {
+		v, _ = s.sites.LoadOrStore(site, &siteCounter{windowStart: now})
+	}
+	c := v.(*siteCounter)
+
+	s.overhead.lock(&c.mu)
+	if elapsed := now.Sub(c.windowStart); elapsed >= s.window {
+		if elapsed >= 2*s.window {
+			c.prev = 0 // the site was idle for a whole window
+		} else {
+			c.prev = c.count
+		}
+		c.count = 0
+		c.windowStart = now
+	}
+	c.count++
+	c.total++
+	first := c.total <= s.firstN
+
+	// Estimate the site's rate from whichever window saw more opens, so bursts of a new hot site are downsampled right away.
+	rate := max(c.prev, c.count)
+	c.mu.Unlock()
+
+	if first {
+		return true
+	}
+	if s.perSite > 0 && rate > s.perSite && rand.Float64() >= float64(s.perSite)/float64(rate) {
+		return false
+	}
+
+	return s.sampleRate()
+}
+
+// sampleRate samples uniformly at the rate set by WithSampleRate.
+func (s *siteSampler) sampleRate() bool {
+	return s.rate >= 1 || rand.Float64() < s.rate
+}
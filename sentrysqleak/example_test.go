@@ -0,0 +1,19 @@
+package sentrysqleak_test
+
+import (
+	"log"
+	"time"
+
+	_ "github.com/mattn/go-sqlite3"
+
+	"github.com/saiko-tech/sqleak"
+	"github.com/saiko-tech/sqleak/sentrysqleak"
+)
+
+func ExampleNewSink() {
+	db, err := sqleak.Open("sqlite3", ":memory:", sqleak.WithTimeout(time.Minute), sqleak.WithSink(sentrysqleak.NewSink(nil)))
+	if err != nil {
+		log.Fatalf("failed to open DB: %v", err)
+	}
+	defer db.Close()
+}
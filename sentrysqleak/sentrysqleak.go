@@ -0,0 +1,134 @@
+// Package sentrysqleak reports sqleak leak events to Sentry, with the stack that opened the leaked resource
+// as the event's stack trace and one Sentry issue per call site and query.
+//
+//	db, err := sqleak.Open("postgres", dsn, sqleak.WithTimeout(time.Minute), sqleak.WithSink(sentrysqleak.NewSink(nil)))
+package sentrysqleak
+
+import (
+	"fmt"
+	"runtime"
+	"slices"
+
+	"github.com/getsentry/sentry-go"
+
+	"github.com/saiko-tech/sqleak"
+)
+
+// Sink is a sqleak.Sink capturing leak events on a Sentry hub. Only the first report of every leak and resources
+// still open at shutdown are captured, other events like late closes, repeated warnings or long-held transactions
+// are not.
+type Sink struct {
+	hub *sentry.Hub
+}
+
+// NewSink returns a sink capturing events on hub, or on sentry.CurrentHub at the time of each event if hub is nil.
+func NewSink(hub *sentry.Hub) *Sink {
+	return &Sink{hub: hub}
+}
+
+// WriteEvent captures ev, see Event.
+func (s *Sink) WriteEvent(ev sqleak.LeakEvent) error {
+	if ev.Type != sqleak.EventLeak && ev.Type != sqleak.EventOutstanding || ev.Occurrence > 1 {
+		return nil
+	}
+
+	hub := s.hub
+	if hub == nil {
+		hub = sentry.CurrentHub()
+	}
+	hub.CaptureEvent(Event(ev))
+
+	return nil
+}
+
+// Event converts a leak event into a Sentry event: an exception of type "sqleak: <Kind> leak" with the opening
+// stack, the level of the event's severity, tags for filtering and a fingerprint grouping the leaks of the same
+// call site and query shape into one issue.
+func Event(ev sqleak.LeakEvent) *sentry.Event {
+	event := sentry.NewEvent()
+	event.Level = level(ev.Severity)
+	event.Logger = "sqleak"
+	event.Message = ev.Text()
+	event.Fingerprint = fingerprint(ev)
+
+	event.Exception = []sentry.Exception{{
+		Type:       fmt.Sprintf("sqleak: %s leak", ev.Kind),
+		Value:      fmt.Sprintf("%s not closed within %s", ev.Kind, ev.Timeout),
+		Stacktrace: stacktrace(ev.Frames),
+	}}
+
+	event.Tags = map[string]string{
+		"sqleak.kind":     string(ev.Kind),
+		"sqleak.severity": string(ev.Severity),
+	}
+	for key, value := range map[string]string{
+		"sqleak.name":              ev.Name,
+		"sqleak.driver":            ev.Driver,
+		"sqleak.database":          ev.Database,
+		"sqleak.owner":             ev.Owner,
+		"sqleak.query_fingerprint": ev.QueryFingerprint,
+	} {
+		if value != "" {
+			event.Tags[key] = value
+		}
+	}
+	for key, value := range ev.Attrs {
+		event.Tags[key] = value
+	}
+
+	event.Extra = map[string]any{
+		"leak_id":     ev.LeakID,
+		"resource_id": ev.ResourceID,
+		"open_for":    ev.Age.String(),
+		"timeout":     ev.Timeout.String(),
+		"warning":     ev.Occurrence,
+	}
+	if ev.Query != "" {
+		event.Extra["query"] = ev.Query
+	}
+	if len(ev.Args) > 0 {
+		event.Extra["args"] = ev.Args
+	}
+	if !ev.OpenedAt.IsZero() {
+		event.Extra["opened_at"] = ev.OpenedAt
+	}
+
+	return event
+}
+
+func level(severity sqleak.Severity) sentry.Level {
+	switch severity {
+	case sqleak.SeverityInfo:
+		return sentry.LevelInfo
+	case sqleak.SeverityError:
+		return sentry.LevelError
+	case sqleak.SeverityCritical:
+		return sentry.LevelFatal
+	}
+
+	return sentry.LevelWarning
+}
+
+// fingerprint groups events by kind, the function that opened the resource and the query's shape.
+func fingerprint(ev sqleak.LeakEvent) []string {
+	site := ""
+	if len(ev.Frames) > 0 {
+		site = ev.Frames[0].Function
+	}
+
+	return []string{"sqleak", string(ev.Kind), site, ev.QueryFingerprint}
+}
+
+// stacktrace converts frames, innermost first, into a Sentry stack trace, which lists the outermost frame first.
+func stacktrace(frames []sqleak.Frame) *sentry.Stacktrace {
+	if len(frames) == 0 {
+		return nil
+	}
+
+	st := &sentry.Stacktrace{Frames: make([]sentry.Frame, 0, len(frames))}
+	for _, f := range slices.Backward(frames) {
+		st.Frames = append(st.Frames, sentry.NewFrame(runtime.Frame{Function: f.Function, File: f.File, Line: f.Line}))
+	}
+
+	return st
+}
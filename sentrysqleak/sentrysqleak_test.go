@@ -0,0 +1,74 @@
+package sentrysqleak
+
+import (
+	"slices"
+	"testing"
+	"time"
+
+	"github.com/getsentry/sentry-go"
+
+	"github.com/saiko-tech/sqleak"
+)
+
+func TestSink(t *testing.T) {
+	var captured []*sentry.Event
+	client, err := sentry.NewClient(sentry.ClientOptions{
+		BeforeSend: func(event *sentry.Event, _ *sentry.EventHint) *sentry.Event {
+			captured = append(captured, event)
+			return nil
+		},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	sink := NewSink(sentry.NewHub(client, sentry.NewScope()))
+
+	ev := sqleak.LeakEvent{
+		Type:             sqleak.EventLeak,
+		LeakID:           1,
+		Kind:             sqleak.KindRows,
+		Query:            "SELECT * FROM users WHERE id = $1",
+		QueryFingerprint: "8c5d2b1f0e6a4d37",
+		Severity:         sqleak.SeverityCritical,
+		Timeout:          time.Second,
+		Age:              time.Second,
+		Frames: []sqleak.Frame{
+			{Function: "github.com/org/app/store.(*Store).Get", File: "/app/store/store.go", Line: 42},
+			{Function: "main.main", File: "/app/main.go", Line: 10},
+		},
+	}
+	ignored := []sqleak.EventType{
+		sqleak.EventClosedLate, sqleak.EventStalled, sqleak.EventLongTx, sqleak.EventCloseFailed, sqleak.EventForcedRollback,
+	}
+	for _, typ := range append([]sqleak.EventType{sqleak.EventLeak}, ignored...) {
+		ev.Type = typ
+		if err = sink.WriteEvent(ev); err != nil {
+			t.Fatal(err)
+		}
+	}
+	repeated := ev
+	repeated.Type, repeated.Occurrence = sqleak.EventLeak, 2
+	if err = sink.WriteEvent(repeated); err != nil {
+		t.Fatal(err)
+	}
+
+	if len(captured) != 1 {
+		t.Fatalf("expected only the first report of the leak to be captured, got %d events", len(captured))
+	}
+	event := captured[0]
+	if event.Level != sentry.LevelFatal || event.Tags["sqleak.kind"] != "Rows" || event.Extra["query"] != ev.Query {
+		t.Errorf("unexpected event %+v", event)
+	}
+	if want := []string{"sqleak", "Rows", "github.com/org/app/store.(*Store).Get", "8c5d2b1f0e6a4d37"}; !slices.Equal(event.Fingerprint, want) {
+		t.Errorf("got fingerprint %v, want %v", event.Fingerprint, want)
+	}
+
+	if len(event.Exception) != 1 || event.Exception[0].Type != "sqleak: Rows leak" || event.Exception[0].Stacktrace == nil {
+		t.Fatalf("unexpected exception %+v", event.Exception)
+	}
+	frames := event.Exception[0].Stacktrace.Frames
+	if len(frames) != 2 || frames[0].Function != "main" || frames[1].Module != "github.com/org/app/store" ||
+		frames[1].Function != "(*Store).Get" || frames[1].Lineno != 42 {
+		t.Errorf("expected the frames outermost first, got %+v", frames)
+	}
+}
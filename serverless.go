@@ -0,0 +1,42 @@
+package sqleak
+
+import "net/http"
+
+// WithServerless enables leak detection for platforms that freeze the process between requests, such as AWS Lambda
+// or Cloud Run. Timers don't fire while the process is frozen, so the deadlines of open resources are additionally
+// evaluated whenever CheckDeadlines is called: after every request handled by Detector.FlushMiddleware,
+// when the sql.DB is closed, and from the platform's before-freeze or shutdown hook.
+func WithServerless() Option {
+	return func(ld *monitoredDriver) {
+		ld.serverless = true
+	}
+}
+
+// CheckDeadlines reports every resource whose leak timeout or repeat interval has elapsed and that wasn't reported yet.
+// Reports are written before CheckDeadlines returns. Without WithServerless it does nothing, timers report leaks.
+func (d *Detector) CheckDeadlines() {
+	if !d.serverless {
+		return
+	}
+
+	now := d.clock.Now()
+	for _, m := range d.openMonitors() {
+		if n, ok := m.due(now); ok {
+			m.fire(n)
+		}
+	}
+}
+
+// FlushMiddleware checks deadlines after next handled a request, so leaks are reported before the platform may
+// freeze the process once the response was sent. For gRPC servers, call CheckDeadlines from an interceptor instead.
+// Unlike the package level Middleware, it doesn't annotate requests, wrap handlers with both to have both.
+func (d *Detector) FlushMiddleware(next http.Handler) http.Handler {
+	if noop {
+		return next
+	}
+
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		defer d.CheckDeadlines()
+		next.ServeHTTP(w, r)
+	})
+}
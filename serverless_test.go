@@ -0,0 +1,99 @@
+//go:build !sqleak_noop
+
+package sqleak
+
+import (
+	"context"
+	"database/sql/driver"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestServerless(t *testing.T) {
+	mc, fc, logOutput := newTestConn(t, WithTimeout(time.Second), WithServerless())
+	ctx := context.Background()
+
+	var rows interface{ Close() error }
+	handler := mc.detector.FlushMiddleware(http.HandlerFunc(func(http.ResponseWriter, *http.Request) {
+		if rows == nil {
+			rows, _ = mc.QueryContext(ctx, "SELECT 1", nil)
+		}
+		closed, _ := mc.QueryContext(ctx, "SELECT 2", nil)
+		_ = closed.Close()
+	}))
+
+	handler.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/", nil))
+	if logOutput.Len() != 0 {
+		t.Fatalf("did not expect a report before the timeout:\n%s", logOutput.String())
+	}
+
+	// The process is frozen past the timeout, the timer didn't get to run.
+	fc.Freeze(2 * time.Second)
+	handler.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/", nil))
+	if got := strings.Count(logOutput.String(), "likely resource leak detected: Rows not closed"); got != 1 {
+		t.Fatalf("got %d leak warnings after the request, want 1:\n%s", got, logOutput.String())
+	}
+
+	// The late timer must not report the leak again.
+	fc.Advance(0)
+	mc.detector.CheckDeadlines()
+	if got := strings.Count(logOutput.String(), "likely resource leak detected"); got != 1 {
+		t.Errorf("got %d leak warnings after the late timer, want 1:\n%s", got, logOutput.String())
+	}
+
+	_ = rows.Close()
+	if !strings.Contains(logOutput.String(), "leaked resource closed late: Rows closed 2s after opening") {
+		t.Errorf("expected late close report, got:\n%s", logOutput.String())
+	}
+	if n := len(mc.detector.openMonitors()); n != 0 {
+		t.Errorf("%d monitors still pending", n)
+	}
+}
+
+func TestServerlessRepeatInterval(t *testing.T) {
+	mc, fc, logOutput := newTestConn(t, WithTimeout(time.Second), WithRepeatInterval(time.Second), WithServerless())
+
+	rows, _ := mc.QueryContext(context.Background(), "SELECT 1", nil)
+	defer rows.Close()
+
+	// Each check reports at most the next due warning, however long the process was frozen.
+	fc.Freeze(5 * time.Second)
+	mc.detector.CheckDeadlines()
+	mc.detector.CheckDeadlines()
+	if got := strings.Count(logOutput.String(), "likely resource leak detected"); got != 2 {
+		t.Fatalf("got %d warnings, want 2:\n%s", got, logOutput.String())
+	}
+	if !strings.Contains(logOutput.String(), `(leak #1, resource #1, query "SELECT 1", 0 rows fetched, warning #2, open for 5s, opened `) {
+		t.Errorf("expected second warning, got:\n%s", logOutput.String())
+	}
+
+	// The timer of the first warning is stale and the one armed by the second warning isn't due yet.
+	fc.Advance(0)
+	if got := strings.Count(logOutput.String(), "likely resource leak detected"); got != 2 {
+		t.Errorf("got %d warnings after stale timers ran, want 2:\n%s", got, logOutput.String())
+	}
+}
+
+func TestServerlessIdleTimeout(t *testing.T) {
+	mc, fc, logOutput := newTestConn(t, WithTimeout(time.Second), WithIdleTimeout(), WithServerless())
+
+	rows, _ := mc.QueryContext(context.Background(), "SELECT 1", nil)
+	defer rows.Close()
+
+	fc.Freeze(500 * time.Millisecond)
+	_ = rows.Next(make([]driver.Value, 1))
+	fc.Freeze(800 * time.Millisecond)
+	mc.detector.CheckDeadlines()
+	if logOutput.Len() != 0 {
+		t.Fatalf("did not expect a report of Rows open past the timeout but active since:\n%s", logOutput.String())
+	}
+
+	fc.Freeze(300 * time.Millisecond)
+	mc.detector.CheckDeadlines()
+	if !strings.Contains(logOutput.String(), "likely resource leak detected: Rows not closed and idle for 1.1s") {
+		t.Errorf("expected a report once idle for the timeout, got:\n%s", logOutput.String())
+	}
+}
@@ -0,0 +1,175 @@
+package sqleak
+
+import (
+	"fmt"
+	"log"
+	"os"
+	"os/signal"
+	"sort"
+	"strings"
+	"sync"
+	"syscall"
+	"time"
+)
+
+// ReportOutstanding reports every resource that is still open as an EventOutstanding, oldest first, and returns
+// their number. The text output starts with a summary line. Call it from the shutdown hook of frameworks with their
+// own lifecycle, right before closing the sql.DB; resources still open then are what keeps graceful shutdowns stuck.
+func (d *Detector) ReportOutstanding() int {
+	if noop {
+		return 0
+	}
+	events := d.Outstanding()
+
+	if !d.jsonOutput {
+		log.Printf("%soutstanding resources: %s", d.logPrefix(), summarizeOutstanding(events))
+	}
+	for _, ev := range events {
+		d.report(ev)
+	}
+
+	return len(events)
+}
+
+// Outstanding returns an EventOutstanding for every resource that is still open, oldest first, without reporting them.
+func (d *Detector) Outstanding() []LeakEvent {
+	if noop {
+		return nil
+	}
+	now := d.clock.Now()
+
+	var events []LeakEvent
+	for _, m := range d.openMonitors() {
+		if m.state.Load() == stateClosed {
+			continue
+		}
+		ev := m.leakEvent()
+		ev.Type = EventOutstanding
+		ev.quiet = false
+		ev.Age = now.Sub(m.openedAt)
+		events = append(events, ev)
+	}
+	sort.Slice(events, func(i, j int) bool { return events[i].OpenedAt.Before(events[j].OpenedAt) })
+
+	return events
+}
+
+// ExitReport summarizes the leaks of a batch job or CLI in one paragraph, meant to be printed right before it exits.
+// leaked counts the resources reported as leaked during the run plus those still open but not yet reported,
+// since a resource still open at exit leaked regardless of the timeout. Exit non-zero if it's not 0,
+// so scheduled jobs fail visibly instead of slowly exhausting the database.
+func (d *Detector) ExitReport() (summary string, leaked int) {
+	if noop {
+		return "", 0
+	}
+	detected := int(d.leakIDs.Load())
+	events := d.Outstanding()
+
+	leaked = detected
+	for _, ev := range events {
+		if ev.LeakID == 0 {
+			leaked++
+		}
+	}
+	if leaked == 0 {
+		return d.logPrefix() + "no resources leaked", 0
+	}
+
+	summary = d.logPrefix() + fmt.Sprintf("%d resources leaked: %d detected during the run, %s at exit", leaked, detected, summarizeOutstanding(events))
+	if len(events) > 0 {
+		oldest := events[0]
+		summary += fmt.Sprintf("; oldest %s open for %s, opened at %s", oldest.Kind, oldest.Age.Round(time.Millisecond), oldest.site())
+	}
+
+	return summary, leaked
+}
+
+func summarizeOutstanding(events []LeakEvent) string {
+	if len(events) == 0 {
+		return "none open"
+	}
+
+	counts := map[Kind]int{}
+	for _, ev := range events {
+		counts[ev.Kind]++
+	}
+
+	var kinds []string
+	for _, kind := range []Kind{KindRows, KindStmt, KindTx} {
+		if counts[kind] > 0 {
+			kinds = append(kinds, fmt.Sprintf("%s: %d", kind, counts[kind]))
+		}
+	}
+
+	return fmt.Sprintf("%d open (%s)", len(events), strings.Join(kinds, ", "))
+}
+
+// ReportOnShutdown calls ReportOutstanding once one of the signals arrives, SIGTERM and os.Interrupt by default.
+// After reporting, the signal is unregistered and raised again, so its default action still takes place unless the
+// application handles it itself, e.g. with signal.NotifyContext, which then receives it twice. Outside of unix the
+// signal is only reported, the application is expected to shut down from its own handler.
+// Calling stop unregisters the signals without reporting.
+func (d *Detector) ReportOnShutdown(signals ...os.Signal) (stop func()) {
+	if noop {
+		return func() {}
+	}
+	if len(signals) == 0 {
+		signals = []os.Signal{syscall.SIGTERM, os.Interrupt}
+	}
+
+	ch := make(chan os.Signal, 1)
+	signal.Notify(ch, signals...)
+
+	done := make(chan struct{})
+	go func() {
+		select {
+		case sig := <-ch:
+			d.ReportOutstanding()
+			signal.Stop(ch)
+			reraise(sig)
+		case <-done:
+			signal.Stop(ch)
+		}
+	}()
+
+	var once sync.Once
+	return func() {
+		once.Do(func() { close(done) })
+	}
+}
+
+// DumpOnSignal calls ReportOutstanding every time one of the signals arrives, SIGUSR1 by default, listing all open
+// resources with their stacks in the log and every sink and subscriber, e.g. to investigate a wedged instance with
+// kill -USR1. Outside of unix there is no default signal. Calling stop unregisters the signals.
+func (d *Detector) DumpOnSignal(signals ...os.Signal) (stop func()) {
+	if noop {
+		return func() {}
+	}
+	if len(signals) == 0 {
+		signals = defaultDumpSignals
+	}
+
+	ch := make(chan os.Signal, 1)
+	if len(signals) > 0 {
+		signal.Notify(ch, signals...)
+	}
+
+	done := make(chan struct{})
+	go func() {
+		defer signal.Stop(ch)
+
+		for {
+			select {
+			case <-ch:
+				d.ReportOutstanding()
+			case <-done:
+				return
+			}
+		}
+	}()
+
+	var once sync.Once
+	return func() {
+		once.Do(func() { close(done) })
+	}
+}
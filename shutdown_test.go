@@ -0,0 +1,158 @@
+//go:build !sqleak_noop
+
+package sqleak
+
+import (
+	"context"
+	"database/sql/driver"
+	"os"
+	"os/signal"
+	"runtime"
+	"strings"
+	"syscall"
+	"testing"
+	"time"
+)
+
+func TestReportOutstanding(t *testing.T) {
+	mc, fc, logOutput := newTestConn(t, WithTimeout(10*time.Second))
+	ctx := context.Background()
+
+	if n := mc.detector.ReportOutstanding(); n != 0 {
+		t.Errorf("got %d outstanding resources, want 0", n)
+	}
+	if !strings.Contains(logOutput.String(), "outstanding resources: none open") {
+		t.Errorf("expected summary, got:\n%s", logOutput.String())
+	}
+	logOutput.Reset()
+
+	tx, _ := mc.BeginTx(ctx, driver.TxOptions{})
+	defer tx.Rollback()
+	fc.Advance(15 * time.Second) // the Tx is reported as leak #1
+
+	rows, _ := mc.QueryContext(ctx, "SELECT 1", nil)
+	defer rows.Close()
+	closed, _ := mc.QueryContext(ctx, "SELECT 2", nil)
+	_ = closed.Close()
+	fc.Advance(time.Second)
+
+	if n := mc.detector.ReportOutstanding(); n != 2 {
+		t.Errorf("got %d outstanding resources, want 2", n)
+	}
+
+	out := logOutput.String()
+	for _, want := range []string{
+		"outstanding resources: 2 open (Rows: 1, Tx: 1)",
+		"resource still open: Tx open for 16s (leak #1, resource #1, opened ",
+		"resource still open: Rows open for 1s (resource #1, query \"SELECT 1\", 0 rows fetched, opened ",
+	} {
+		if !strings.Contains(out, want) {
+			t.Errorf("expected %q in log, got:\n%s", want, out)
+		}
+	}
+	if strings.Index(out, "Tx open for") > strings.Index(out, "Rows open for") {
+		t.Errorf("expected oldest resource first, got:\n%s", out)
+	}
+}
+
+func TestExitReport(t *testing.T) {
+	mc, fc, _ := newTestConn(t, WithTimeout(10*time.Second))
+	ctx := context.Background()
+
+	if summary, leaked := mc.detector.ExitReport(); leaked != 0 || summary != "no resources leaked" {
+		t.Errorf("got %q, %d for a clean run", summary, leaked)
+	}
+
+	rows, _ := mc.QueryContext(ctx, "SELECT 1", nil)
+	tx, _ := mc.BeginTx(ctx, driver.TxOptions{})
+	defer tx.Rollback()
+	fc.Advance(15 * time.Second) // both are reported as leaks
+	_ = rows.Close()
+
+	stmt, _ := mc.PrepareContext(ctx, "SELECT 2")
+	defer stmt.Close()
+	fc.Advance(time.Second) // open at exit, but not reported yet
+
+	summary, leaked := mc.detector.ExitReport()
+	if leaked != 3 {
+		t.Errorf("got %d leaked resources, want 3", leaked)
+	}
+	want := "3 resources leaked: 2 detected during the run, 2 open (Stmt: 1, Tx: 1) at exit; oldest Tx open for 16s, opened at "
+	if !strings.HasPrefix(summary, want) {
+		t.Errorf("got summary %q, want prefix %q", summary, want)
+	}
+}
+
+func TestReportOnShutdown(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("sending signals to the own process is not supported on windows")
+	}
+
+	events := make(chan LeakEvent, 1)
+	mc, _, _ := newTestConn(t, WithOnLeak(func(ev LeakEvent) { events <- ev }))
+
+	rows, _ := mc.QueryContext(context.Background(), "SELECT 1", nil)
+	defer rows.Close()
+
+	// The application's own handler, receiving the signal raised again once reported instead of exiting.
+	app := make(chan os.Signal, 2)
+	signal.Notify(app, syscall.SIGHUP)
+	defer signal.Stop(app)
+
+	stop := mc.detector.ReportOnShutdown(syscall.SIGHUP)
+	defer stop()
+
+	p, _ := os.FindProcess(os.Getpid())
+	if err := p.Signal(syscall.SIGHUP); err != nil {
+		t.Fatal(err)
+	}
+
+	select {
+	case ev := <-events:
+		if ev.Type != EventOutstanding || ev.Kind != KindRows {
+			t.Errorf("unexpected event: %+v", ev)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("no outstanding resources reported after the signal")
+	}
+
+	for i := range 2 {
+		select {
+		case <-app:
+		case <-time.After(5 * time.Second):
+			t.Fatalf("expected the signal to be raised again after reporting, got %d of 2", i)
+		}
+	}
+}
+
+func TestDumpOnSignal(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("sending signals to the own process is not supported on windows")
+	}
+
+	events := make(chan LeakEvent, 2)
+	mc, _, _ := newTestConn(t, WithOnLeak(func(ev LeakEvent) { events <- ev }))
+
+	rows, _ := mc.QueryContext(context.Background(), "SELECT 1", nil)
+	defer rows.Close()
+
+	stop := mc.detector.DumpOnSignal(syscall.SIGHUP)
+	defer stop()
+
+	// Every signal dumps the open resources again.
+	p, _ := os.FindProcess(os.Getpid())
+	for range 2 {
+		if err := p.Signal(syscall.SIGHUP); err != nil {
+			t.Fatal(err)
+		}
+
+		select {
+		case ev := <-events:
+			if ev.Type != EventOutstanding || ev.Kind != KindRows || ev.Stack == "" {
+				t.Errorf("unexpected event: %+v", ev)
+			}
+		case <-time.After(5 * time.Second):
+			t.Fatal("no open resources dumped after the signal")
+		}
+	}
+}
@@ -0,0 +1,51 @@
+package sqleak
+
+// Sink receives the leak reports of a Detector in addition to the log output, see WithSink.
+type Sink interface {
+	// WriteEvent is called synchronously from the detecting goroutine for every reported event.
+	// Errors are logged and don't stop other sinks from receiving the event.
+	WriteEvent(ev LeakEvent) error
+}
+
+// WithSink writes every reported leak event to sink as well, with its full stack. Events that WithDeduplication
+// doesn't log aren't written either. Combine it with WithSingleLineLog to keep stacks out of the application log.
+func WithSink(sink Sink) Option {
+	return func(ld *monitoredDriver) {
+		ld.sinks = append(ld.sinks, sink)
+	}
+}
+
+// WithSingleLineLog logs every report on a single line naming the function that opened the resource, like
+// WithCallerOnly, but still captures full stacks for events, sinks and subscribers.
+func WithSingleLineLog() Option {
+	return func(ld *monitoredDriver) {
+		ld.singleLineLog = true
+	}
+}
+
+// Syslog priorities of reports, see LeakEvent.priority.
+const (
+	priorityCritical = 2
+	priorityError    = 3
+	priorityWarning  = 4
+	priorityNotice   = 5
+	priorityInfo     = 6
+)
+
+// priority maps the severity of an event to a syslog priority, late closes are notices.
+func (ev LeakEvent) priority() int {
+	if ev.Type == EventClosedLate {
+		return priorityNotice
+	}
+
+	switch ev.Severity {
+	case SeverityCritical:
+		return priorityCritical
+	case SeverityError:
+		return priorityError
+	case SeverityInfo:
+		return priorityInfo
+	}
+
+	return priorityWarning
+}
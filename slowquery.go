@@ -0,0 +1,56 @@
+package sqleak
+
+import (
+	"fmt"
+	"log"
+	"sync/atomic"
+	"time"
+)
+
+// WithSlowQueryThreshold logs every query and execution that took longer than threshold to run, with its query
+// text and the application function that ran it, e.g. `slow query: took 2.5s, more than 1s: "SELECT ..." called from
+// main.listOrders (orders.go:42)`. Only the driver call is timed, the time spent iterating Rows is not part of it.
+// Query texts are anonymized with WithQueryAnonymizer, Stats().SlowQueries counts the slow queries.
+func WithSlowQueryThreshold(threshold time.Duration) Option {
+	return func(ld *monitoredDriver) {
+		ld.slowQuery = &slowQuery{threshold: threshold}
+	}
+}
+
+type slowQuery struct {
+	threshold time.Duration
+	count     atomic.Int64
+}
+
+// queryStarted returns the start of running a query, see queryDone.
+func (d *Detector) queryStarted() time.Time {
+	if d.slowQuery == nil {
+		return time.Time{}
+	}
+
+	return d.clock.Now()
+}
+
+// queryDone logs the query that started at start if it was slow, with WithSlowQueryThreshold.
+// It's meant to be deferred by the method running the query.
+func (d *Detector) queryDone(start time.Time, query string) {
+	s := d.slowQuery
+	if s == nil {
+		return
+	}
+
+	took := d.clock.Now().Sub(start)
+	if took <= s.threshold {
+		return
+	}
+	s.count.Add(1)
+
+	msg := fmt.Sprintf("%sslow query: took %s, more than %s: %q", d.logPrefix(), took.Round(time.Millisecond), s.threshold,
+		shortenQuery(d.anonymizeQuery(query)))
+
+	if f, ok := applicationCaller(); ok {
+		msg += fmt.Sprintf(" called from %s (%s:%d)", f.Function, f.File, f.Line)
+	}
+
+	log.Print(msg)
+}
@@ -0,0 +1,51 @@
+//go:build !sqleak_noop
+
+package sqleak
+
+import (
+	"context"
+	"database/sql/driver"
+	"strings"
+	"testing"
+	"time"
+)
+
+// slowConn takes queryTime on the fake clock to run a query.
+type slowConn struct {
+	fakeConn
+	clock     *fakeClock
+	queryTime time.Duration
+}
+
+func (c *slowConn) QueryContext(ctx context.Context, query string, args []driver.NamedValue) (driver.Rows, error) {
+	c.clock.Advance(c.queryTime)
+	return c.fakeConn.QueryContext(ctx, query, args)
+}
+
+func TestSlowQueryThreshold(t *testing.T) {
+	mc, fc, logOutput := newTestConn(t, WithTimeout(time.Hour), WithSlowQueryThreshold(time.Second))
+	sc := &slowConn{clock: fc}
+	mc = newMonitoredConn(sc, mc.detector)
+	ctx := context.Background()
+
+	for _, queryTime := range []time.Duration{time.Second, 2500 * time.Millisecond} {
+		sc.queryTime = queryTime
+		rows, err := mc.QueryContext(ctx, "SELECT 1", nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		fc.Advance(time.Minute) // iterating doesn't count
+		rows.Close()
+	}
+
+	out := logOutput.String()
+	if want := `slow query: took 2.5s, more than 1s: "SELECT 1" called from `; !strings.Contains(out, want) {
+		t.Errorf("expected %q in log, got:\n%s", want, out)
+	}
+	if n := strings.Count(out, "slow query"); n != 1 {
+		t.Errorf("got %d slow queries logged, want 1:\n%s", n, out)
+	}
+	if n := mc.detector.Stats().SlowQueries; n != 1 {
+		t.Errorf("got %d slow queries, want 1", n)
+	}
+}
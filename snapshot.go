@@ -0,0 +1,76 @@
+package sqleak
+
+import (
+	"sort"
+	"time"
+)
+
+// OpenResource describes a resource that is open at the time of a Snapshot.
+type OpenResource struct {
+	Resource
+	// Age is how long the resource has been open.
+	Age time.Duration
+	// Leaked reports whether the resource exceeded its timeout and was reported as leaked.
+	Leaked bool
+	// Goroutine is the ID of the goroutine that opened the resource, 0 if it was not sampled.
+	Goroutine uint64
+	// Stack and Frames are the opening stack, as in LeakEvent.
+	Stack  string
+	Frames []Frame
+	// Owner is the owner of the opening call site as determined by WithOwnerResolver, if set.
+	Owner string
+	// TraceID and SpanID identify the trace the resource was opened in, with WithTraceExtractor.
+	TraceID, SpanID string
+	// Attrs are the attributes of the context the resource was opened with, with WithContextAttrs.
+	Attrs map[string]string
+}
+
+// Snapshot lists the resources currently open through the Detector, oldest first, e.g. to render in an admin UI or
+// to assert on in integration tests. Unlike Outstanding, it includes no per-event details like cost or severity.
+func (d *Detector) Snapshot() []OpenResource {
+	if noop {
+		return nil
+	}
+	now := d.clock.Now()
+
+	var resources []OpenResource
+	for _, m := range d.openMonitors() {
+		state := m.state.Load()
+		if state == stateClosed {
+			continue
+		}
+
+		ev := m.leakEvent()
+		resources = append(resources, OpenResource{
+			Resource:  m.resource(),
+			Age:       now.Sub(m.openedAt),
+			Leaked:    state == stateLeaked,
+			Goroutine: ev.Goroutine,
+			Stack:     ev.Stack,
+			Frames:    ev.Frames,
+			Owner:     ev.Owner,
+			TraceID:   ev.TraceID,
+			SpanID:    ev.SpanID,
+			Attrs:     ev.Attrs,
+		})
+	}
+	sortResources(resources)
+
+	return resources
+}
+
+// Snapshot lists the resources currently open through every Detector in the process, oldest first.
+// Resources of Detectors set up WithName tell their Detector by Resource.Name.
+func Snapshot() []OpenResource {
+	var resources []OpenResource
+	for _, d := range registeredDetectors() {
+		resources = append(resources, d.Snapshot()...)
+	}
+	sortResources(resources)
+
+	return resources
+}
+
+func sortResources(resources []OpenResource) {
+	sort.SliceStable(resources, func(i, j int) bool { return resources[i].OpenedAt.Before(resources[j].OpenedAt) })
+}
@@ -0,0 +1,51 @@
+//go:build !sqleak_noop
+
+package sqleak
+
+import (
+	"context"
+	"database/sql/driver"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestSnapshot(t *testing.T) {
+	mc, fc, _ := newTestConn(t, WithTimeout(time.Second), WithName("orders"))
+	ctx := context.Background()
+	d := mc.detector
+
+	rows, _ := mc.QueryContext(ctx, "SELECT 1", nil)
+	defer rows.Close()
+	fc.Advance(time.Second)
+	tx, _ := mc.BeginTx(ctx, driver.TxOptions{})
+	defer tx.Rollback()
+	closed, _ := mc.QueryContext(ctx, "SELECT 2", nil)
+	closed.Close()
+	fc.Advance(time.Second / 2)
+
+	resources := d.Snapshot()
+	if len(resources) != 2 {
+		t.Fatalf("got %d resources, want 2", len(resources))
+	}
+	r := resources[0]
+	if r.Kind != KindRows || r.Query != "SELECT 1" || r.Name != "orders" || !r.Leaked || r.LeakID != 1 || r.Age != 1500*time.Millisecond {
+		t.Errorf("unexpected first resource %+v", r)
+	}
+	if !strings.HasPrefix(r.Stack, "goroutine ") || r.Goroutine == 0 {
+		t.Errorf("expected the opening stack and goroutine, got %d:\n%s", r.Goroutine, r.Stack)
+	}
+	if r := resources[1]; r.Kind != KindTx || r.Leaked || r.Age != time.Second/2 {
+		t.Errorf("unexpected second resource %+v", r)
+	}
+
+	var found int
+	for _, r := range Snapshot() {
+		if r.Name == "orders" {
+			found++
+		}
+	}
+	if found != 2 {
+		t.Errorf("expected the package level Snapshot to include the 2 resources, got %d", found)
+	}
+}
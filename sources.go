@@ -0,0 +1,87 @@
+package sqleak
+
+import (
+	"sort"
+	"sync"
+	"time"
+)
+
+// maxLeakSources bounds the call sites aggregated for TopLeakSources, leaks of further sites are not aggregated.
+const maxLeakSources = 1024
+
+// LeakSource aggregates the leaks of one call site, see TopLeakSources.
+type LeakSource struct {
+	// Fingerprint identifies the call site like Capture.Fingerprint, or by kind and query shape without a stack.
+	Fingerprint string
+	Kind        Kind
+	// Site is the innermost application frame of the site, or its query, as in deduplicated log lines.
+	Site string
+	// Count is the number of leaks of the site.
+	Count     int64
+	FirstSeen time.Time
+	LastSeen  time.Time
+	// Query, Stack and Frames are those of the first leak of the site, as an example.
+	Query  string
+	Stack  string
+	Frames []Frame
+}
+
+type leakSources struct {
+	mu    sync.Mutex
+	sites map[string]*LeakSource
+}
+
+// record counts the first report of a leak towards the source with the given fingerprint.
+func (s *leakSources) record(fingerprint string, ev LeakEvent, now time.Time) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	src, ok := s.sites[fingerprint]
+	if !ok {
+		if len(s.sites) >= maxLeakSources {
+			return
+		}
+		if s.sites == nil {
+			s.sites = make(map[string]*LeakSource)
+		}
+		src = &LeakSource{
+			Fingerprint: fingerprint,
+			Kind:        ev.Kind,
+			Site:        ev.site(),
+			FirstSeen:   now,
+			Query:       ev.Query,
+			Stack:       ev.Stack,
+			Frames:      ev.Frames,
+		}
+		s.sites[fingerprint] = src
+	}
+	src.Count++
+	src.LastSeen = now
+}
+
+// TopLeakSources returns the n call sites that leaked the most resources since the Detector started, most leaks
+// first, and the most recent ones first among sites with as many leaks. It answers "which code paths leak the most"
+// of a running process without searching its logs. n <= 0 returns all sites.
+func (d *Detector) TopLeakSources(n int) []LeakSource {
+	if noop {
+		return nil
+	}
+	d.sources.mu.Lock()
+	sources := make([]LeakSource, 0, len(d.sources.sites))
+	for _, src := range d.sources.sites {
+		sources = append(sources, *src)
+	}
+	d.sources.mu.Unlock()
+
+	sort.Slice(sources, func(i, j int) bool {
+		if sources[i].Count != sources[j].Count {
+			return sources[i].Count > sources[j].Count
+		}
+		return sources[i].LastSeen.After(sources[j].LastSeen)
+	})
+	if n > 0 && len(sources) > n {
+		sources = sources[:n]
+	}
+
+	return sources
+}
@@ -0,0 +1,51 @@
+//go:build !sqleak_noop
+
+package sqleak
+
+import (
+	"context"
+	"database/sql/driver"
+	"testing"
+	"time"
+)
+
+func TestTopLeakSources(t *testing.T) {
+	// Without stacks, sites are told apart by kind and query, as the stacks of this package's tests are trimmed.
+	mc, fc, _ := newTestConn(t, WithTimeout(time.Second), WithRepeatInterval(time.Second), WithoutStacks())
+	ctx := context.Background()
+
+	leakRows := func() {
+		rows, _ := mc.QueryContext(ctx, "SELECT 1", nil)
+		t.Cleanup(func() { rows.Close() })
+	}
+	leakTx := func() {
+		tx, _ := mc.BeginTx(ctx, driver.TxOptions{})
+		t.Cleanup(func() { tx.Rollback() })
+	}
+
+	leakRows()
+	leakTx()
+	fc.Advance(time.Second)
+	leakRows()
+	leakRows()
+	fc.Advance(time.Second) // also repeats the first warnings, which must not count
+
+	sources := mc.detector.TopLeakSources(0)
+	if len(sources) != 2 {
+		t.Fatalf("got %d sources, want 2: %+v", len(sources), sources)
+	}
+	rows := sources[0]
+	if rows.Kind != KindRows || rows.Count != 3 || rows.Query != "SELECT 1" || rows.Site != `query "SELECT 1"` {
+		t.Errorf("unexpected top source %+v", rows)
+	}
+	if want := fc.Now().Add(-time.Second); !rows.FirstSeen.Equal(want) || !rows.LastSeen.Equal(fc.Now()) {
+		t.Errorf("got first and last seen %s and %s", rows.FirstSeen, rows.LastSeen)
+	}
+	if tx := sources[1]; tx.Kind != KindTx || tx.Count != 1 {
+		t.Errorf("unexpected second source %+v", tx)
+	}
+
+	if top := mc.detector.TopLeakSources(1); len(top) != 1 || top[0].Kind != KindRows {
+		t.Errorf("expected only the Rows source, got %+v", top)
+	}
+}
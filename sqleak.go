@@ -4,15 +4,12 @@ import (
 	"context"
 	"database/sql"
 	"database/sql/driver"
-	"log"
-	"runtime"
-	"sync"
 	"time"
 )
 
 type dsnConnector struct {
 	dsn    string
-	driver driver.Driver
+	driver *monitoredDriver
 }
 
 func (c dsnConnector) Connect(_ context.Context) (driver.Conn, error) {
@@ -23,45 +20,9 @@ func (c dsnConnector) Driver() driver.Driver {
 	return c.driver
 }
 
-var stackPool = sync.Pool{
-	New: func() interface{} {
-		buf := make([]byte, 8*1024)
-		return &buf
-	},
-}
-
-type monitor struct {
-	timeout  time.Duration
-	stack    []byte
-	closed   bool
-	resource string
-}
-
-func (m *monitor) markClosed() {
-	m.closed = true
-}
-
-func newMonitor(timeout time.Duration, resource string) *monitor {
-	buf := stackPool.Get().(*[]byte)
-
-	n := runtime.Stack(*buf, false)
-
-	mon := &monitor{
-		timeout:  timeout,
-		stack:    (*buf)[:n],
-		closed:   false,
-		resource: resource,
-	}
-
-	time.AfterFunc(mon.timeout, func() {
-		if !mon.closed {
-			log.Printf("likely resource leak detected: %s not closed within %s after opening:\n%s", mon.resource, mon.timeout, string(mon.stack))
-		}
-
-		stackPool.Put(buf)
-	})
-
-	return mon
+func (c dsnConnector) Close() error {
+	c.driver.stop()
+	return c.driver.closeError()
 }
 
 type Option func(*monitoredDriver)
@@ -72,6 +33,14 @@ func WithTimeout(timeout time.Duration) Option {
 	}
 }
 
+// WithName tags every report, Stats and Config of the wrapped driver with a name,
+// e.g. WithName("orders-replica"), to tell multiple wrapped databases apart. Log lines start with "[name] ".
+func WithName(name string) Option {
+	return func(ld *monitoredDriver) {
+		ld.name = name
+	}
+}
+
 func WithDriverWrapper(f func(driver.Driver) driver.Driver) Option {
 	return func(ld *monitoredDriver) {
 		ld.driver = f(ld.driver)
@@ -90,28 +59,55 @@ func Open(driverName, dataSourceName string, opts ...Option) (*sql.DB, error) {
 	}
 
 	ld := newMonitoredDriver(d, 30*time.Second) // default timeout of 30 seconds, can be overridden by options
+	ld.driverName = driverName
+	ld.configure(opts)
 
-	for _, opt := range opts {
-		opt(ld)
-	}
-
+	var connector driver.Connector = dsnConnector{dsn: dataSourceName, driver: ld}
 	if _, ok := d.(driver.DriverContext); ok {
-		connector, err := ld.OpenConnector(dataSourceName)
-		if err != nil {
+		if connector, err = ld.OpenConnector(dataSourceName); err != nil {
 			return nil, err
 		}
-		return sql.OpenDB(connector), nil
 	}
 
-	return sql.OpenDB(dsnConnector{dsn: dataSourceName, driver: ld}), nil
+	db = sql.OpenDB(connector)
+	if ld.poolFromOpen {
+		ld.poolStats = db.Stats
+	}
+
+	return db, nil
 }
 
-func WrapDriver(d driver.Driver, opts ...Option) driver.Driver {
-	ld := newMonitoredDriver(d, 30*time.Second) // default timeout of 30 seconds, can be overridden by options
+// OpenDB is a wrapper over sql.OpenDB with leak detection instrumentation, for drivers configured through a
+// driver.Connector rather than a data source name, like pgx's stdlib.GetConnector or mysql.NewConnector.
+// Reports don't name the database without a data source name to parse it from. Closing the DB closes the connector
+// as well, if it implements io.Closer.
+func OpenDB(connector driver.Connector, opts ...Option) *sql.DB {
+	wrapped := WrapConnector(connector, opts...).(*monitoredConnector)
 
-	for _, opt := range opts {
-		opt(ld)
+	db := sql.OpenDB(wrapped)
+	if wrapped.driver.poolFromOpen {
+		wrapped.driver.poolStats = db.Stats
 	}
 
+	return db
+}
+
+// WrapConnector wraps connector with leak detection instrumentation, to compose it with other connector wrappers
+// like otelsql in any order before passing the result to sql.OpenDB. Its Driver is the instrumented driver, get the
+// Detector with DetectorFromDriver(wrapped.Driver()), as wrappers around it may hide it from DetectorOf.
+// Closing the wrapped connector stops the Detector and closes connector, if it implements io.Closer.
+// WithPoolStats(nil) has no effect, use WithPoolStats(db.Stats) instead.
+func WrapConnector(connector driver.Connector, opts ...Option) driver.Connector {
+	ld := newMonitoredDriver(connector.Driver(), 30*time.Second) // default timeout of 30 seconds, can be overridden by options
+	ld.configure(opts)
+
+	return newMonitoredConnector(connector, ld, nil)
+}
+
+// WrapDriver wraps d with leak detection instrumentation, see DetectorFromDriver for accessing its Detector.
+func WrapDriver(d driver.Driver, opts ...Option) driver.Driver {
+	ld := newMonitoredDriver(d, 30*time.Second) // default timeout of 30 seconds, can be overridden by options
+	ld.configure(opts)
+
 	return ld
 }
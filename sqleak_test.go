@@ -1,8 +1,18 @@
+//go:build !sqleak_noop
+
 package sqleak_test
 
 import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"encoding/json"
+	"fmt"
 	"log"
+	"os"
 	"strings"
+	"sync"
+	"sync/atomic"
 	"testing"
 	"time"
 
@@ -12,9 +22,7 @@ import (
 )
 
 func TestConnectionLeakDetection(t *testing.T) {
-	var logOutput strings.Builder
-	log.SetOutput(&logOutput)
-	defer log.SetOutput(nil) // reset after test
+	logOutput := captureLog(t)
 
 	db, err := sqleak.Open("sqlite3", ":memory:",
 		sqleak.WithTimeout(100*time.Millisecond), // set low timeout for test
@@ -48,9 +56,7 @@ func TestConnectionLeakDetection(t *testing.T) {
 }
 
 func TestProperClosePreventsLeakWarning(t *testing.T) {
-	var logOutput strings.Builder
-	log.SetOutput(&logOutput)
-	defer log.SetOutput(nil) // reset after test
+	logOutput := captureLog(t)
 
 	db, err := sqleak.Open("sqlite3", ":memory:",
 		sqleak.WithTimeout(100*time.Millisecond),
@@ -115,9 +121,7 @@ func Example() {
 }
 
 func TestExample(t *testing.T) {
-	var logOutput strings.Builder
-	log.SetOutput(&logOutput)
-	defer log.SetOutput(nil) // reset after test
+	logOutput := captureLog(t)
 
 	// Run the example function to see if it logs a leak warning
 	Example()
@@ -129,3 +133,847 @@ func TestExample(t *testing.T) {
 
 	t.Logf("Log output: %s", logOutput.String())
 }
+
+// syncBuffer is a goroutine-safe log sink, since leak warnings are written from timer goroutines.
+type syncBuffer struct {
+	mu  sync.Mutex
+	buf strings.Builder
+}
+
+func (b *syncBuffer) Write(p []byte) (int, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.buf.Write(p)
+}
+
+func (b *syncBuffer) String() string {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.buf.String()
+}
+
+// captureLog redirects the standard logger into a buffer for the duration of the test.
+func captureLog(t *testing.T) *syncBuffer {
+	t.Helper()
+
+	var buf syncBuffer
+	log.SetOutput(&buf)
+	t.Cleanup(func() { log.SetOutput(os.Stderr) })
+
+	return &buf
+}
+
+func TestVerboseExplainsLeak(t *testing.T) {
+	logOutput := captureLog(t)
+
+	db, err := sqleak.Open("sqlite3", ":memory:",
+		sqleak.WithTimeout(100*time.Millisecond),
+		sqleak.WithVerbose(),
+	)
+	if err != nil {
+		t.Fatalf("failed to open DB: %v", err)
+	}
+	defer db.Close()
+
+	tx, err := db.Begin()
+	if err != nil {
+		t.Fatalf("begin failed: %v", err)
+	}
+
+	time.Sleep(200 * time.Millisecond)
+	_ = tx.Rollback()
+
+	for _, want := range []string{"likely resource leak detected: Tx", "defer tx.Rollback()", "SQLITE_BUSY"} {
+		if !strings.Contains(logOutput.String(), want) {
+			t.Errorf("expected %q in log, got:\n%s", want, logOutput.String())
+		}
+	}
+}
+
+func TestOwnerResolver(t *testing.T) {
+	logOutput := captureLog(t)
+
+	var (
+		mu       sync.Mutex
+		resolved []string
+	)
+	db, err := sqleak.Open("sqlite3", ":memory:",
+		sqleak.WithTimeout(100*time.Millisecond),
+		sqleak.WithOwnerResolver(func(f sqleak.Frame) string {
+			mu.Lock()
+			defer mu.Unlock()
+			resolved = append(resolved, f.Function)
+			if strings.HasPrefix(f.Function, "github.com/saiko-tech/sqleak_test.") {
+				return "team-sqleak"
+			}
+			return ""
+		}),
+	)
+	if err != nil {
+		t.Fatalf("failed to open DB: %v", err)
+	}
+	defer db.Close()
+
+	rows, err := db.Query("SELECT 1")
+	if err != nil {
+		t.Fatalf("query failed: %v", err)
+	}
+
+	time.Sleep(200 * time.Millisecond)
+	_ = rows.Close()
+
+	if !strings.Contains(logOutput.String(), "owner: team-sqleak)") {
+		t.Errorf("expected owner in log, got:\n%s", logOutput.String())
+	}
+	mu.Lock()
+	defer mu.Unlock()
+	for _, fn := range resolved {
+		if strings.HasPrefix(fn, "database/sql.") || strings.HasPrefix(fn, "github.com/saiko-tech/sqleak.") {
+			t.Errorf("resolver called for infrastructure frame %s", fn)
+		}
+	}
+}
+
+func TestJSONOutput(t *testing.T) {
+	logOutput := captureLog(t)
+
+	db, err := sqleak.Open("sqlite3", ":memory:",
+		sqleak.WithTimeout(100*time.Millisecond),
+		sqleak.WithJSONOutput(),
+	)
+	if err != nil {
+		t.Fatalf("failed to open DB: %v", err)
+	}
+	defer db.Close()
+
+	rows, err := db.Query("SELECT 1")
+	if err != nil {
+		t.Fatalf("query failed: %v", err)
+	}
+
+	time.Sleep(200 * time.Millisecond)
+	_ = rows.Close()
+
+	lines := strings.Split(strings.TrimSpace(logOutput.String()), "\n")
+	if len(lines) != 2 {
+		t.Fatalf("expected a JSON line for the leak and one for the late close, got:\n%s", logOutput.String())
+	}
+
+	var ev, closed sqleak.LeakEvent
+	if err = json.Unmarshal([]byte(lines[0]), &ev); err != nil {
+		t.Fatalf("failed to decode %q: %v", lines[0], err)
+	}
+	if ev.Type != sqleak.EventLeak || ev.Kind != sqleak.KindRows || ev.Timeout != 100*time.Millisecond || ev.Driver != "sqlite3" || len(ev.Frames) == 0 {
+		t.Errorf("unexpected event: %+v", ev)
+	}
+
+	if err = json.Unmarshal([]byte(lines[1]), &closed); err != nil {
+		t.Fatalf("failed to decode %q: %v", lines[1], err)
+	}
+	if closed.Type != sqleak.EventClosedLate || closed.LeakID != ev.LeakID || closed.Age < 200*time.Millisecond {
+		t.Errorf("unexpected follow-up event: %+v", closed)
+	}
+}
+
+func TestOnLeak(t *testing.T) {
+	captureLog(t)
+
+	leaks := make(chan sqleak.LeakInfo, 1)
+	db, err := sqleak.Open("sqlite3", ":memory:",
+		sqleak.WithTimeout(100*time.Millisecond),
+		sqleak.WithOnLeak(func(info sqleak.LeakInfo) { leaks <- info }),
+	)
+	if err != nil {
+		t.Fatalf("failed to open DB: %v", err)
+	}
+	defer db.Close()
+
+	before := time.Now()
+	stmt, err := db.Prepare("SELECT 1")
+	if err != nil {
+		t.Fatalf("prepare failed: %v", err)
+	}
+	defer stmt.Close()
+
+	select {
+	case info := <-leaks:
+		if info.Kind != sqleak.KindStmt {
+			t.Errorf("got kind %s, want %s", info.Kind, sqleak.KindStmt)
+		}
+		if info.Timeout != 100*time.Millisecond {
+			t.Errorf("got timeout %s, want %s", info.Timeout, 100*time.Millisecond)
+		}
+		if info.OpenedAt.Before(before) || !strings.Contains(info.Frames[0].Function, "TestOnLeak") {
+			t.Errorf("unexpected open time %s or stack:\n%s", info.OpenedAt, info.Stack)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("OnLeak callback was not invoked")
+	}
+}
+
+func TestOverheadStats(t *testing.T) {
+	db, err := sqleak.Open("sqlite3", ":memory:")
+	if err != nil {
+		t.Fatalf("failed to open DB: %v", err)
+	}
+	defer db.Close()
+
+	for i := 0; i < 10; i++ {
+		rows, err := db.Query("SELECT 1")
+		if err != nil {
+			t.Fatalf("query failed: %v", err)
+		}
+		_ = rows.Close()
+	}
+
+	overhead := sqleak.DetectorOf(db).Stats().Overhead
+	if overhead.Opens < 10 || overhead.StackCaptures < 10 || overhead.TimerSchedules < 10 {
+		t.Errorf("expected at least 10 measured opens, got %+v", overhead)
+	}
+	if overhead.PerOpen() <= 0 {
+		t.Errorf("expected positive overhead per open, got %s", overhead.PerOpen())
+	}
+}
+
+func TestEvents(t *testing.T) {
+	captureLog(t)
+
+	db, err := sqleak.Open("sqlite3", ":memory:",
+		sqleak.WithTimeout(100*time.Millisecond),
+	)
+	if err != nil {
+		t.Fatalf("failed to open DB: %v", err)
+	}
+
+	events, cancel := sqleak.Events(db)
+	defer cancel()
+
+	tx, err := db.Begin()
+	if err != nil {
+		t.Fatalf("begin failed: %v", err)
+	}
+
+	select {
+	case ev := <-events:
+		if ev.Kind != sqleak.KindTx {
+			t.Errorf("got kind %s, want %s", ev.Kind, sqleak.KindTx)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("no leak event received")
+	}
+
+	_ = tx.Rollback()
+	if ev := <-events; ev.Type != sqleak.EventClosedLate {
+		t.Errorf("got event type %s after rollback, want %s", ev.Type, sqleak.EventClosedLate)
+	}
+	_ = db.Close()
+
+	if _, ok := <-events; ok {
+		t.Error("expected events channel to be closed after db.Close")
+	}
+}
+
+func TestEventsCancel(t *testing.T) {
+	db, err := sqleak.Open("sqlite3", ":memory:")
+	if err != nil {
+		t.Fatalf("failed to open DB: %v", err)
+	}
+	defer db.Close()
+
+	events, cancel := sqleak.Events(db)
+	cancel()
+	if _, ok := <-events; ok {
+		t.Error("expected events channel to be closed after cancel")
+	}
+
+	unbuffered, cancel := sqleak.DetectorOf(db).Subscribe(-1)
+	defer cancel()
+	if cap(unbuffered) != 0 {
+		t.Errorf("expected a negative buffer to be treated as 0, got %d", cap(unbuffered))
+	}
+}
+
+func TestQueryAnonymizer(t *testing.T) {
+	captureLog(t)
+
+	events := make(chan sqleak.LeakEvent, 1)
+	db, err := sqleak.Open("sqlite3", ":memory:",
+		sqleak.WithTimeout(100*time.Millisecond),
+		sqleak.WithQueryAnonymizer(sqleak.HMACTablesAnonymizer([]byte("secret"))),
+		sqleak.WithOnLeak(func(ev sqleak.LeakEvent) { events <- ev }),
+	)
+	if err != nil {
+		t.Fatalf("failed to open DB: %v", err)
+	}
+	defer db.Close()
+
+	if _, err = db.Exec("CREATE TABLE accounts (id INTEGER PRIMARY KEY, email TEXT)"); err != nil {
+		t.Fatalf("failed to create table: %v", err)
+	}
+
+	const query = "SELECT email FROM accounts WHERE email = 'jane@example.com'"
+	rows, err := db.Query(query)
+	if err != nil {
+		t.Fatalf("query failed: %v", err)
+	}
+	defer rows.Close()
+
+	ev := <-events
+	want := sqleak.HMACAnonymizer([]byte("secret"))(query) + " tables=accounts"
+	if ev.Query != want {
+		t.Errorf("got query %q, want %q", ev.Query, want)
+	}
+	if strings.Contains(ev.Query, "example.com") {
+		t.Errorf("raw query text leaked into event: %q", ev.Query)
+	}
+}
+
+func TestNormalizeQuery(t *testing.T) {
+	for query, want := range map[string]string{
+		"SELECT * FROM users WHERE id IN (1, 2) -- admin":      "SELECT * FROM users WHERE id IN (?)",
+		"SELECT  name\n\tFROM users WHERE id = $1 AND n > 2.5": "SELECT name FROM users WHERE id = ? AND n > ?",
+		"SELECT 'it''s', 'a\\'b' /* hint */ FROM t2":           "SELECT ?, ? FROM t2",
+		"INSERT INTO logs (id, msg) VALUES (?, ?), (?, ?)":     "INSERT INTO logs (id, msg) VALUES (?), (?)",
+		"UPDATE t SET col1 = -42 WHERE name = 'x' /* unclosed": "UPDATE t SET col1 = -? WHERE name = ?",
+	} {
+		if got := sqleak.NormalizeQuery(query); got != want {
+			t.Errorf("NormalizeQuery(%q) = %q, want %q", query, got, want)
+		}
+	}
+}
+
+func TestQueryFingerprint(t *testing.T) {
+	captureLog(t)
+
+	db, err := sqleak.Open("sqlite3", ":memory:", sqleak.WithTimeout(100*time.Millisecond))
+	if err != nil {
+		t.Fatalf("failed to open DB: %v", err)
+	}
+	defer db.Close()
+
+	events, cancel := sqleak.DetectorOf(db).Subscribe(3)
+	defer cancel()
+
+	for _, query := range []string{"SELECT 1", "SELECT  2", "SELECT 'other'"} {
+		rows, err := db.Query(query)
+		if err != nil {
+			t.Fatalf("query failed: %v", err)
+		}
+		defer rows.Close()
+	}
+
+	fingerprints := map[string]bool{}
+	for i := 0; i < 3; i++ {
+		ev := <-events
+		if ev.QueryFingerprint == "" {
+			t.Errorf("no query fingerprint for %q", ev.Query)
+		}
+		fingerprints[ev.QueryFingerprint] = true
+	}
+	if len(fingerprints) != 1 {
+		t.Errorf("queries of the same shape got %d fingerprints, want 1", len(fingerprints))
+	}
+}
+
+func TestPoolStatsOfOpen(t *testing.T) {
+	captureLog(t)
+
+	events := make(chan sqleak.LeakEvent, 1)
+	db, err := sqleak.Open("sqlite3", ":memory:",
+		sqleak.WithTimeout(100*time.Millisecond),
+		sqleak.WithPoolStats(nil),
+		sqleak.WithOnLeak(func(ev sqleak.LeakEvent) { events <- ev }),
+	)
+	if err != nil {
+		t.Fatalf("failed to open DB: %v", err)
+	}
+	defer db.Close()
+	db.SetMaxOpenConns(2)
+
+	rows, err := db.Query("SELECT 1")
+	if err != nil {
+		t.Fatalf("query failed: %v", err)
+	}
+	defer rows.Close()
+
+	ev := <-events
+	if ev.Pool == nil || ev.Pool.InUse != 1 || ev.Pool.MaxOpenConnections != 2 {
+		t.Errorf("expected the stats of the opened DB, got %+v", ev.Pool)
+	}
+}
+
+func TestCheckoutWarningOfConn(t *testing.T) {
+	logOutput := captureLog(t)
+
+	db, err := sqleak.Open("sqlite3", ":memory:", sqleak.WithTimeout(time.Hour), sqleak.WithCheckoutWarning(50*time.Millisecond))
+	if err != nil {
+		t.Fatalf("failed to open DB: %v", err)
+	}
+	defer db.Close()
+
+	// Queries return their connection in time, the Conn doesn't.
+	if _, err := db.Exec("SELECT 1"); err != nil {
+		t.Fatalf("exec failed: %v", err)
+	}
+	conn, err := db.Conn(context.Background())
+	if err != nil {
+		t.Fatalf("failed to get a Conn: %v", err)
+	}
+	time.Sleep(100 * time.Millisecond)
+	conn.Close()
+
+	out := logOutput.String()
+	if n := strings.Count(out, "checked out for"); n != 1 || !strings.Contains(out, "TestCheckoutWarningOfConn") {
+		t.Errorf("expected a single warning with the stack of the test, got:\n%s", out)
+	}
+	if !strings.Contains(out, "connection #1 returned to the pool after ") {
+		t.Errorf("expected the return to be logged, got:\n%s", out)
+	}
+}
+
+func TestSlowQueryCaller(t *testing.T) {
+	logOutput := captureLog(t)
+
+	db, err := sqleak.Open("sqlite3", ":memory:", sqleak.WithTimeout(time.Hour), sqleak.WithSlowQueryThreshold(time.Nanosecond))
+	if err != nil {
+		t.Fatalf("failed to open DB: %v", err)
+	}
+	defer db.Close()
+
+	if _, err := db.Exec("SELECT 1"); err != nil {
+		t.Fatalf("exec failed: %v", err)
+	}
+
+	if want := `"SELECT 1" called from github.com/saiko-tech/sqleak_test.TestSlowQueryCaller (`; !strings.Contains(logOutput.String(), want) {
+		t.Errorf("expected %q in log, got:\n%s", want, logOutput.String())
+	}
+}
+
+func TestCanceledContext(t *testing.T) {
+	logOutput := captureLog(t)
+
+	db, err := sqleak.Open("sqlite3", ":memory:", sqleak.WithTimeout(50*time.Millisecond))
+	if err != nil {
+		t.Fatalf("failed to open DB: %v", err)
+	}
+	defer db.Close()
+
+	// database/sql closes the Rows of the cancelled request, which never does.
+	ctx, cancel := context.WithCancel(context.Background())
+	if _, err := db.QueryContext(ctx, "SELECT 1"); err != nil {
+		t.Fatalf("query failed: %v", err)
+	}
+	cancel()
+	time.Sleep(100 * time.Millisecond)
+
+	if strings.Contains(logOutput.String(), "leak") {
+		t.Errorf("expected no leak report for the Rows of a cancelled context, got:\n%s", logOutput.String())
+	}
+	if stats := sqleak.DetectorOf(db).Stats(); stats.Rows.Open != 0 {
+		t.Errorf("expected the Rows to be closed, got %d open", stats.Rows.Open)
+	}
+}
+
+// registrations makes the driver names of TestRegister unique, drivers can't be unregistered between runs.
+var registrations atomic.Int64
+
+func TestRegister(t *testing.T) {
+	logOutput := captureLog(t)
+	name := fmt.Sprintf("sqlite3-leakcheck-%s-%d", t.Name(), registrations.Add(1))
+
+	detector, err := sqleak.Register(name, "sqlite3", sqleak.WithTimeout(50*time.Millisecond))
+	if err != nil {
+		t.Fatalf("failed to register: %v", err)
+	}
+	if _, err := sqleak.Register(name, "sqlite3"); err == nil {
+		t.Error("expected an error registering the name twice")
+	}
+
+	db, err := sql.Open(name, ":memory:")
+	if err != nil {
+		t.Fatalf("failed to open DB: %v", err)
+	}
+	defer db.Close()
+	if sqleak.DetectorOf(db) != detector {
+		t.Error("expected the DB to be monitored by the registered Detector")
+	}
+
+	rows, err := db.Query("SELECT 1")
+	if err != nil {
+		t.Fatalf("query failed: %v", err)
+	}
+	defer rows.Close()
+	time.Sleep(100 * time.Millisecond)
+
+	if !strings.Contains(logOutput.String(), "likely resource leak detected") {
+		t.Errorf("expected a leak report, got:\n%s", logOutput.String())
+	}
+	if c := detector.Config(); c.Driver != "sqlite3" {
+		t.Errorf("expected the wrapped driver's name in the config, got %q", c.Driver)
+	}
+}
+
+// sqliteConnector connects to an in-memory SQLite database, as sqlite3 has no driver.Connector of its own.
+type sqliteConnector struct{ driver driver.Driver }
+
+func (c sqliteConnector) Connect(context.Context) (driver.Conn, error) {
+	return c.driver.Open(":memory:")
+}
+
+func (c sqliteConnector) Driver() driver.Driver {
+	return c.driver
+}
+
+func TestOpenDB(t *testing.T) {
+	logOutput := captureLog(t)
+
+	sqlDB, err := sql.Open("sqlite3", "")
+	if err != nil {
+		t.Fatalf("failed to open DB: %v", err)
+	}
+	sqlDB.Close()
+
+	db := sqleak.OpenDB(sqliteConnector{sqlDB.Driver()}, sqleak.WithTimeout(50*time.Millisecond))
+	defer db.Close()
+
+	rows, err := db.Query("SELECT 1")
+	if err != nil {
+		t.Fatalf("query failed: %v", err)
+	}
+	defer rows.Close()
+	time.Sleep(100 * time.Millisecond)
+
+	if !strings.Contains(logOutput.String(), "likely resource leak detected") {
+		t.Errorf("expected a leak report, got:\n%s", logOutput.String())
+	}
+	if sqleak.DetectorOf(db) == nil {
+		t.Error("expected the DB to have a Detector")
+	}
+}
+
+func TestWrapConnector(t *testing.T) {
+	logOutput := captureLog(t)
+
+	sqlDB, err := sql.Open("sqlite3", "")
+	if err != nil {
+		t.Fatalf("failed to open DB: %v", err)
+	}
+	sqlDB.Close()
+
+	wrapped := sqleak.WrapConnector(sqliteConnector{sqlDB.Driver()}, sqleak.WithTimeout(50*time.Millisecond))
+	detector := sqleak.DetectorFromDriver(wrapped.Driver())
+	if detector == nil {
+		t.Fatal("expected the wrapped connector's driver to have a Detector")
+	}
+
+	// Another wrapper around the instrumented connector, like otelsql's.
+	db := sql.OpenDB(outerConnector{wrapped})
+	defer db.Close()
+
+	rows, err := db.Query("SELECT 1")
+	if err != nil {
+		t.Fatalf("query failed: %v", err)
+	}
+	defer rows.Close()
+	time.Sleep(100 * time.Millisecond)
+
+	if !strings.Contains(logOutput.String(), "likely resource leak detected") {
+		t.Errorf("expected a leak report, got:\n%s", logOutput.String())
+	}
+	if got := detector.Stats().Rows.Leaked; got != 1 {
+		t.Errorf("expected 1 leaked Rows, got %d", got)
+	}
+}
+
+// outerConnector wraps a connector without instrumenting it, hiding the instrumented driver.
+type outerConnector struct {
+	driver.Connector
+}
+
+func (c outerConnector) Driver() driver.Driver {
+	return outerDriver{c.Connector.Driver()}
+}
+
+type outerDriver struct {
+	driver.Driver
+}
+
+func TestOpenHandle(t *testing.T) {
+	db, err := sqleak.OpenHandle("sqlite3", ":memory:", sqleak.WithTimeout(time.Hour))
+	if err != nil {
+		t.Fatalf("failed to open DB: %v", err)
+	}
+	defer db.Close()
+
+	rows, err := db.Query("SELECT 1")
+	if err != nil {
+		t.Fatalf("query failed: %v", err)
+	}
+
+	if got := db.LeakStats().Rows.Open; got != 1 {
+		t.Errorf("expected 1 open Rows, got %d", got)
+	}
+	if resources := db.Snapshot(); len(resources) != 1 || resources[0].Query != "SELECT 1" {
+		t.Errorf("expected a snapshot of the open Rows, got %+v", resources)
+	}
+	err = db.VerifyNoLeaks()
+	if err == nil || !strings.Contains(err.Error(), "1 open (Rows: 1)") {
+		t.Errorf("expected an error naming the open Rows, got %v", err)
+	}
+
+	rows.Close()
+	if err := db.VerifyNoLeaks(); err != nil {
+		t.Errorf("expected no leaks after closing the Rows, got %v", err)
+	}
+
+	plain, err := sql.Open("sqlite3", ":memory:")
+	if err != nil {
+		t.Fatalf("failed to open DB: %v", err)
+	}
+	defer plain.Close()
+	if sqleak.HandleOf(plain) != nil {
+		t.Error("expected no handle for a DB without instrumentation")
+	}
+}
+
+func TestReadLeakEventsAcrossSchemaVersions(t *testing.T) {
+	stream := strings.Join([]string{
+		`2025/05/29 16:19:31 some unrelated log line`,
+		`{"kind":"Rows","timeout":100000000,"frames":[]}`,
+		`{"schema_version":1,"kind":"Tx","timeout":"30s","driver":"pgx"}`,
+		`{"schema_version":7,"kind":"Stmt","timeout":"1m","query":"SELECT 1","fingerprint":{"future":true}}`,
+		`{"level":"info","msg":"not a leak event"}`,
+	}, "\n")
+
+	var events []sqleak.LeakEvent
+	err := sqleak.ReadLeakEvents(strings.NewReader(stream), func(ev sqleak.LeakEvent) error {
+		events = append(events, ev)
+		return nil
+	})
+	if err != nil {
+		t.Fatalf("ReadLeakEvents failed: %v", err)
+	}
+
+	if len(events) != 3 {
+		t.Fatalf("got %d events, want 3: %+v", len(events), events)
+	}
+	if events[0].Kind != sqleak.KindRows || events[0].Timeout != 100*time.Millisecond || events[0].SchemaVersion != 0 {
+		t.Errorf("unexpected legacy event: %+v", events[0])
+	}
+	if events[1].Driver != "pgx" || events[1].Timeout != 30*time.Second {
+		t.Errorf("unexpected current event: %+v", events[1])
+	}
+	if events[2].SchemaVersion != 7 || events[2].Query != "SELECT 1" {
+		t.Errorf("unexpected future event: %+v", events[2])
+	}
+
+	b, err := json.Marshal(events[1])
+	if err != nil {
+		t.Fatalf("marshal failed: %v", err)
+	}
+	if !strings.Contains(string(b), fmt.Sprintf(`"schema_version":%d`, sqleak.LeakEventSchemaVersion)) {
+		t.Errorf("encoded event lacks schema version: %s", b)
+	}
+}
+
+func TestReadLeakEventsMalformed(t *testing.T) {
+	for name, line := range map[string]string{
+		"invalid duration": `{"kind":"Rows","timeout":"soon"}`,
+		"truncated":        `{"schema_version":3,"kind":"Rows","timeout":"1s","que`,
+	} {
+		t.Run(name, func(t *testing.T) {
+			stream := `{"kind":"Tx","timeout":"1s"}` + "\n" + line + "\n" + `{"kind":"Stmt","timeout":"1s"}`
+
+			var events int
+			err := sqleak.ReadLeakEvents(strings.NewReader(stream), func(sqleak.LeakEvent) error {
+				events++
+				return nil
+			})
+			if err == nil || !strings.Contains(err.Error(), "line 2") {
+				t.Fatalf("got error %v, want one for line 2", err)
+			}
+			if events != 1 {
+				t.Errorf("got %d events before the error, want 1", events)
+			}
+		})
+	}
+}
+
+func TestConfig(t *testing.T) {
+	db, err := sqleak.Open("sqlite3", ":memory:",
+		sqleak.WithTimeout(5*time.Second),
+		sqleak.WithRepeatInterval(time.Minute),
+		sqleak.WithLeakBudget(10, 24*time.Hour, nil),
+	)
+	if err != nil {
+		t.Fatalf("failed to open DB: %v", err)
+	}
+	defer db.Close()
+
+	cfg := sqleak.DetectorOf(db).Config()
+	if cfg.Driver != "sqlite3" || cfg.Timeout != 5*time.Second || cfg.RepeatInterval != time.Minute || cfg.BudgetLimit != 10 {
+		t.Errorf("unexpected config: %+v", cfg)
+	}
+
+	b, err := json.Marshal(cfg)
+	if err != nil {
+		t.Fatalf("marshal failed: %v", err)
+	}
+	for _, want := range []string{`"timeout":"5s"`, `"repeat_interval":"1m0s"`, `"budget_window":"24h0m0s"`} {
+		if !strings.Contains(string(b), want) {
+			t.Errorf("expected %s in %s", want, b)
+		}
+	}
+	if strings.Contains(string(b), "hold_interval") {
+		t.Errorf("unset options should be omitted: %s", b)
+	}
+}
+
+func TestCallerOnly(t *testing.T) {
+	logOutput := captureLog(t)
+
+	db, err := sqleak.Open("sqlite3", ":memory:",
+		sqleak.WithTimeout(100*time.Millisecond),
+		sqleak.WithCallerOnly(),
+	)
+	if err != nil {
+		t.Fatalf("failed to open DB: %v", err)
+	}
+	defer db.Close()
+
+	rows, err := db.Query("SELECT 1")
+	if err != nil {
+		t.Fatalf("query failed: %v", err)
+	}
+
+	time.Sleep(200 * time.Millisecond)
+	out := logOutput.String()
+	_ = rows.Close()
+
+	lines := strings.Split(strings.TrimSpace(out), "\n")
+	if len(lines) != 1 {
+		t.Fatalf("expected a single line report, got:\n%s", out)
+	}
+	if want := ") at github.com/saiko-tech/sqleak_test.TestCallerOnly ("; !strings.Contains(lines[0], want) {
+		t.Errorf("expected %q in log, got:\n%s", want, out)
+	}
+	if !strings.Contains(lines[0], "sqleak_test.go:") {
+		t.Errorf("expected caller file:line in log, got:\n%s", out)
+	}
+}
+
+func TestColumnMetadata(t *testing.T) {
+	logOutput := captureLog(t)
+
+	db, err := sqleak.Open("sqlite3", ":memory:",
+		sqleak.WithTimeout(100*time.Millisecond),
+		sqleak.WithColumnMetadata(),
+	)
+	if err != nil {
+		t.Fatalf("failed to open DB: %v", err)
+	}
+	defer db.Close()
+	db.SetMaxOpenConns(1)
+
+	if _, err = db.Exec("CREATE TABLE files (id INTEGER, name TEXT, data BLOB, thumb BLOB)"); err != nil {
+		t.Fatalf("create failed: %v", err)
+	}
+
+	events, cancel := sqleak.DetectorOf(db).Subscribe(1)
+	defer cancel()
+
+	rows, err := db.Query("SELECT id, name, data, thumb FROM files")
+	if err != nil {
+		t.Fatalf("query failed: %v", err)
+	}
+
+	time.Sleep(200 * time.Millisecond)
+	ev := <-events
+	_ = rows.Close()
+
+	if want := `(leak #1, resource #1, query "SELECT id, name, data, thumb FROM files", returning 4 columns including 1 TEXT, 2 BLOB, `; !strings.Contains(logOutput.String(), want) {
+		t.Errorf("expected %q in log, got:\n%s", want, logOutput.String())
+	}
+	if len(ev.Columns) != 4 || ev.Columns[2] != (sqleak.Column{Name: "data", DatabaseType: "BLOB"}) {
+		t.Errorf("unexpected columns: %+v", ev.Columns)
+	}
+}
+
+func TestCaptureArgs(t *testing.T) {
+	logOutput := captureLog(t)
+
+	redact := func(arg driver.NamedValue) string {
+		if _, ok := arg.Value.(string); ok {
+			return "***"
+		}
+		return fmt.Sprint(arg.Value)
+	}
+	db, err := sqleak.Open("sqlite3", ":memory:",
+		sqleak.WithTimeout(100*time.Millisecond),
+		sqleak.WithCaptureArgs(redact),
+	)
+	if err != nil {
+		t.Fatalf("failed to open DB: %v", err)
+	}
+	defer db.Close()
+
+	events, cancel := sqleak.DetectorOf(db).Subscribe(1)
+	defer cancel()
+
+	rows, err := db.Query("SELECT ?, ?", 42, "hunter2")
+	if err != nil {
+		t.Fatalf("query failed: %v", err)
+	}
+
+	time.Sleep(200 * time.Millisecond)
+	ev := <-events
+	_ = rows.Close()
+
+	out := logOutput.String()
+	if want := `query "SELECT ?, ?", args [42, ***]`; !strings.Contains(out, want) {
+		t.Errorf("expected %q in log, got:\n%s", want, out)
+	}
+	if strings.Contains(out, "hunter2") {
+		t.Errorf("redacted argument in log:\n%s", out)
+	}
+
+	b, err := json.Marshal(ev)
+	if err != nil {
+		t.Fatalf("marshal failed: %v", err)
+	}
+	decoded, err := sqleak.DecodeLeakEvent(b)
+	if err != nil {
+		t.Fatalf("decode failed: %v", err)
+	}
+	if strings.Join(decoded.Args, ",") != "42,***" {
+		t.Errorf("unexpected args after JSON round trip: %q", decoded.Args)
+	}
+}
+
+func TestDetectorFromDriver(t *testing.T) {
+	plain, err := sql.Open("sqlite3", ":memory:")
+	if err != nil {
+		t.Fatalf("failed to open DB: %v", err)
+	}
+	defer plain.Close()
+
+	d := sqleak.WrapDriver(plain.Driver(), sqleak.WithTimeout(time.Minute))
+	detector := sqleak.DetectorFromDriver(d)
+	if detector == nil || detector.Config().Timeout != time.Minute {
+		t.Fatalf("unexpected detector %+v", detector)
+	}
+	if sqleak.DetectorFromDriver(plain.Driver()) != nil {
+		t.Error("expected no detector for an unwrapped driver")
+	}
+
+	connector, err := d.(driver.DriverContext).OpenConnector(":memory:")
+	if err != nil {
+		t.Fatalf("failed to open connector: %v", err)
+	}
+	db := sql.OpenDB(connector)
+	defer db.Close()
+
+	if sqleak.DetectorOf(db) != detector {
+		t.Error("DetectorOf and DetectorFromDriver returned different detectors")
+	}
+}
@@ -0,0 +1,76 @@
+// Package sqleaktest verifies in tests that no SQL resources and no goroutines were leaked.
+//
+// A leaked Rows and a leaked goroutine are usually the same bug, e.g. a worker blocked while iterating Rows,
+// so both are checked together and reported in one message.
+package sqleaktest
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+	"testing"
+
+	"go.uber.org/goleak"
+
+	"github.com/saiko-tech/sqleak"
+)
+
+// VerifyAll fails t if resources of the detector are still open or goroutines were leaked,
+// as determined by goleak.Find with opts. It is meant to run at the end of a test, e.g. via t.Cleanup,
+// after the sql.DB was closed: the pool's own goroutines are reported as leaks while it is open.
+// A nil detector only checks for goroutine leaks.
+func VerifyAll(t testing.TB, detector *sqleak.Detector, opts ...goleak.Option) {
+	t.Helper()
+
+	if err := verify(detector, opts...); err != nil {
+		t.Error(err)
+	}
+}
+
+// VerifyAllTestMain runs the tests of m, then checks for leaks like VerifyAll and exits.
+// Use it in TestMain:
+//
+//	func TestMain(m *testing.M) {
+//		sqleaktest.VerifyAllTestMain(m, sqleak.DetectorOf(db))
+//	}
+func VerifyAllTestMain(m *testing.M, detector *sqleak.Detector, opts ...goleak.Option) {
+	code := m.Run()
+	if code == 0 {
+		if err := verify(detector, opts...); err != nil {
+			fmt.Fprintf(os.Stderr, "sqleaktest: %v\n", err)
+			code = 1
+		}
+	}
+
+	os.Exit(code)
+}
+
+// verify checks for goroutine leaks first: goleak retries until leaked goroutines are gone,
+// giving them time to close the resources they hold.
+func verify(detector *sqleak.Detector, opts ...goleak.Option) error {
+	goroutineErr := goleak.Find(opts...)
+
+	var outstanding []sqleak.LeakEvent
+	if detector != nil {
+		outstanding = detector.Outstanding()
+	}
+
+	if goroutineErr == nil && len(outstanding) == 0 {
+		return nil
+	}
+
+	var b strings.Builder
+	b.WriteString("found leaks")
+	if len(outstanding) > 0 {
+		fmt.Fprintf(&b, "\n\n%d SQL resources still open:", len(outstanding))
+		for _, ev := range outstanding {
+			b.WriteString("\n" + ev.Text())
+		}
+	}
+	if goroutineErr != nil {
+		fmt.Fprintf(&b, "\n\n%v", goroutineErr)
+	}
+
+	return errors.New(b.String())
+}
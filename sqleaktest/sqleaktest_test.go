@@ -0,0 +1,49 @@
+//go:build !sqleak_noop
+
+package sqleaktest
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	_ "github.com/mattn/go-sqlite3"
+	"go.uber.org/goleak"
+
+	"github.com/saiko-tech/sqleak"
+)
+
+func TestVerify(t *testing.T) {
+	ignore := goleak.IgnoreCurrent()
+
+	db, err := sqleak.Open("sqlite3", ":memory:", sqleak.WithTimeout(time.Minute))
+	if err != nil {
+		t.Fatalf("failed to open DB: %v", err)
+	}
+	detector := sqleak.DetectorOf(db)
+
+	rows, err := db.Query("SELECT 1")
+	if err != nil {
+		t.Fatalf("query failed: %v", err)
+	}
+
+	block := make(chan struct{})
+	go func() { <-block }()
+
+	_ = db.Close()
+	err = verify(detector, ignore)
+	if err == nil {
+		t.Fatal("expected leaks to be found")
+	}
+	for _, want := range []string{"1 SQL resources still open:", "resource still open: Rows open for", "TestVerify", "found unexpected goroutines"} {
+		if !strings.Contains(err.Error(), want) {
+			t.Errorf("expected %q in error, got:\n%v", want, err)
+		}
+	}
+
+	close(block)
+	_ = rows.Close()
+	if err = verify(detector, ignore); err != nil {
+		t.Errorf("expected no leaks, got:\n%v", err)
+	}
+}
@@ -0,0 +1,300 @@
+package sqleak
+
+import (
+	"bytes"
+	"runtime"
+	"strconv"
+	"strings"
+)
+
+// Frame is a single function call of a captured stack trace.
+type Frame struct {
+	// Function is the fully qualified function name, e.g. "github.com/org/app/store.(*Store).List".
+	Function string `json:"function"`
+	File     string `json:"file"`
+	Line     int    `json:"line"`
+}
+
+// WithFullStacks disables trimming of runtime, database/sql and sqleak frames from the top of reported stacks.
+// By default a reported stack starts at the frame that called into database/sql.
+func WithFullStacks() Option {
+	return func(ld *monitoredDriver) {
+		ld.fullStacks = true
+	}
+}
+
+// WithStackFilter limits reported stacks to the frames of functions in the given module or package path prefixes,
+// e.g. WithStackFilter("github.com/myorg/"). See WithStackFilterFunc.
+func WithStackFilter(prefixes ...string) Option {
+	return WithStackFilterFunc(func(f Frame) bool {
+		for _, prefix := range prefixes {
+			if strings.HasPrefix(f.Function, prefix) {
+				return true
+			}
+		}
+		return false
+	})
+}
+
+// WithStackFilterFunc limits reported stacks to the frames keep returns true for.
+// If it matches no frame of a stack, the stack is reported unfiltered. Owners are resolved before filtering.
+func WithStackFilterFunc(keep func(Frame) bool) Option {
+	return func(ld *monitoredDriver) {
+		ld.stackFilter = keep
+	}
+}
+
+// WithCallerOnly records only the program counters of the opening goroutine instead of its full stack trace,
+// and reports each leak as a single line naming the application function and file:line that opened the resource.
+// It's cheaper than capturing stack traces, meant for high-volume production services.
+func WithCallerOnly() Option {
+	return func(ld *monitoredDriver) {
+		ld.callerOnly = true
+	}
+}
+
+// WithoutStacks disables stack capture entirely: leaks are still detected, but reported by kind and query only,
+// together with the number of leaks of that query so far, e.g. "3 Rows leaked from query ...".
+// It's the cheapest mode, for hot query paths where even WithCallerOnly is too expensive.
+func WithoutStacks() Option {
+	return func(ld *monitoredDriver) {
+		ld.withoutStacks = true
+	}
+}
+
+// defaultStackBufferSize is the size of the buffers stacks are captured into, unless WithStackBufferSize is set.
+const defaultStackBufferSize = 8 * 1024
+
+// WithStackBufferSize sets the size of the buffers stacks are captured into, 8KB by default.
+// Deeper stacks, common with ORMs, are truncated to the buffer size and end in a "...truncated" line.
+// Sizes below 1 keep the default.
+func WithStackBufferSize(n int) Option {
+	return func(ld *monitoredDriver) {
+		if n > 0 {
+			ld.stackBufferSize = n
+		}
+	}
+}
+
+// truncatedMarker ends stacks that didn't fit the stack buffer.
+const truncatedMarker = "...truncated\n"
+
+// truncateStack copies a stack that filled the whole buffer, dropping its last, partially written frame.
+func truncateStack(buf []byte) []byte {
+	if i := bytes.LastIndexByte(buf, '\n'); i >= 0 {
+		buf = buf[:i+1]
+	}
+	// Drop a function line whose file line didn't fit anymore.
+	if i := bytes.LastIndexByte(buf[:max(len(buf)-1, 0)], '\n'); i >= 0 && buf[i+1] != '\t' {
+		buf = buf[:i+1]
+	}
+
+	return append(append([]byte(nil), buf...), truncatedMarker...)
+}
+
+// goroutineID parses the ID of the goroutine from the "goroutine N [status]:" header of a runtime.Stack trace.
+func goroutineID(stack []byte) uint64 {
+	rest, ok := bytes.CutPrefix(stack, []byte("goroutine "))
+	if !ok {
+		return 0
+	}
+	id, _, _ := bytes.Cut(rest, []byte(" "))
+
+	n, _ := strconv.ParseUint(string(id), 10, 64)
+	return n
+}
+
+// currentGoroutineID returns the ID of the calling goroutine, capturing only the header of its stack.
+func currentGoroutineID() uint64 {
+	var buf [64]byte
+	return goroutineID(buf[:runtime.Stack(buf[:], false)])
+}
+
+// maxCallerDepth bounds the program counters recorded by WithCallerOnly, enough to get past database/sql and sqleak.
+const maxCallerDepth = 32
+
+// applicationCaller returns the innermost application frame of the calling goroutine, skipping runtime,
+// database/sql and sqleak frames.
+func applicationCaller() (Frame, bool) {
+	var pcs [maxCallerDepth]uintptr
+	frames := trimFrames(callerFrames(pcs[:runtime.Callers(2, pcs[:])]))
+	if len(frames) == 0 {
+		return Frame{}, false
+	}
+
+	return frames[0], true
+}
+
+// callerFrames symbolizes program counters recorded by runtime.Callers, innermost call first.
+func callerFrames(pcs []uintptr) []Frame {
+	var frames []Frame
+
+	iter := runtime.CallersFrames(pcs)
+	for {
+		f, more := iter.Next()
+		frames = append(frames, Frame{Function: f.Function, File: f.File, Line: f.Line})
+		if !more {
+			return frames
+		}
+	}
+}
+
+// parseStack parses the output of runtime.Stack for a single goroutine into frames, innermost call first.
+// Parsing happens only when a leak is reported, keeping the open path limited to the cheap runtime.Stack call.
+func parseStack(stack string) []Frame {
+	lines := strings.Split(stack, "\n")
+
+	var frames []Frame
+	for i := 0; i < len(lines); i++ {
+		line := lines[i]
+		if line == "" || strings.HasPrefix(line, "goroutine ") || strings.HasPrefix(line, "...") || strings.HasPrefix(line, "\t") {
+			continue
+		}
+
+		frame := Frame{Function: functionName(line)}
+		if i+1 < len(lines) && strings.HasPrefix(lines[i+1], "\t") {
+			frame.File, frame.Line = fileLine(lines[i+1])
+			i++
+		}
+		frames = append(frames, frame)
+	}
+
+	return frames
+}
+
+func functionName(line string) string {
+	if name, ok := strings.CutPrefix(line, "created by "); ok {
+		name, _, _ = strings.Cut(name, " in goroutine ")
+		return name
+	}
+
+	// Strip the argument list, the function name itself may contain parentheses, e.g. "pkg.(*T).M(0x1, ...)".
+	if strings.HasSuffix(line, ")") {
+		if i := strings.LastIndex(line, "("); i > 0 {
+			return line[:i]
+		}
+	}
+
+	return line
+}
+
+func fileLine(line string) (string, int) {
+	line = strings.TrimPrefix(line, "\t")
+	line, _, _ = strings.Cut(line, " +0x")
+
+	i := strings.LastIndex(line, ":")
+	if i < 0 {
+		return line, 0
+	}
+
+	n, err := strconv.Atoi(line[i+1:])
+	if err != nil {
+		return line, 0
+	}
+
+	return line[:i], n
+}
+
+// infrastructurePrefixes are function name prefixes of frames that never are the application's call site.
+var infrastructurePrefixes = []string{
+	"runtime.",
+	"database/sql.",
+	"github.com/saiko-tech/sqleak.",
+}
+
+func isInfrastructureFrame(f Frame) bool {
+	for _, prefix := range infrastructurePrefixes {
+		if strings.HasPrefix(f.Function, prefix) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// trimStack removes the leading infrastructure frames of a runtime.Stack trace, keeping the goroutine header.
+// A stack consisting of infrastructure frames only is returned unchanged.
+func trimStack(stack string) string {
+	header, rest, ok := strings.Cut(stack, "\n")
+	if !ok || !strings.HasPrefix(header, "goroutine ") {
+		return stack
+	}
+
+	for rest != "" {
+		line, next, _ := strings.Cut(rest, "\n")
+		if !isInfrastructureFrame(Frame{Function: functionName(line)}) {
+			return header + "\n" + rest
+		}
+		if strings.HasPrefix(next, "\t") {
+			_, next, _ = strings.Cut(next, "\n")
+		}
+		rest = next
+	}
+
+	return stack
+}
+
+// trimFrames is trimStack for parsed frames.
+func trimFrames(frames []Frame) []Frame {
+	for i, f := range frames {
+		if !isInfrastructureFrame(f) {
+			return frames[i:]
+		}
+	}
+
+	return frames
+}
+
+// filterStack keeps the goroutine header and the frames of a runtime.Stack trace that keep returns true for.
+// A stack without any matching frame is returned unchanged.
+func filterStack(stack string, keep func(Frame) bool) string {
+	header, rest, ok := strings.Cut(stack, "\n")
+	if !ok || !strings.HasPrefix(header, "goroutine ") {
+		return stack
+	}
+
+	var b strings.Builder
+	b.WriteString(header + "\n")
+
+	kept := false
+	for rest != "" {
+		line, next, _ := strings.Cut(rest, "\n")
+		entry := line + "\n"
+
+		frame := Frame{Function: functionName(line)}
+		if strings.HasPrefix(next, "\t") {
+			var loc string
+			loc, next, _ = strings.Cut(next, "\n")
+			frame.File, frame.Line = fileLine(loc)
+			entry += loc + "\n"
+		}
+		rest = next
+
+		if keep(frame) {
+			b.WriteString(entry)
+			kept = true
+		}
+	}
+
+	if !kept {
+		return stack
+	}
+
+	return b.String()
+}
+
+// filterFrames is filterStack for parsed frames.
+func filterFrames(frames []Frame, keep func(Frame) bool) []Frame {
+	var kept []Frame
+	for _, f := range frames {
+		if keep(f) {
+			kept = append(kept, f)
+		}
+	}
+
+	if len(kept) == 0 {
+		return frames
+	}
+
+	return kept
+}
@@ -0,0 +1,45 @@
+package sqleak
+
+import "time"
+
+// WithStallTimeout reports Rows on which Next was not called for idle as an EventStalled, with the time since the
+// last call in LeakEvent.Idle. Unlike the leak timeout, which bounds the lifetime of Rows, it catches consumers that
+// started iterating and then blocked, e.g. on a slow downstream call per row, or abandoned the Rows without closing
+// them. Rows read to the end are not reported, as nothing is left to iterate. Each Rows is reported once at most,
+// regardless of sampling.
+func WithStallTimeout(idle time.Duration) Option {
+	return func(ld *monitoredDriver) {
+		ld.stallTimeout = idle
+	}
+}
+
+// nextCalled records the time of a call to Next, with WithStallTimeout.
+func (r *monitoredRows) nextCalled() {
+	if d := r.monitor.detector; d.stallTimeout > 0 {
+		r.lastNext.Store(d.clock.Now().UnixNano())
+	}
+}
+
+// checkStall reports the Rows if Next was not called for the stall timeout, or checks again once it could be.
+func (r *monitoredRows) checkStall() {
+	m := r.monitor
+	d := m.detector
+	if m.state.Load() == stateClosed || m.drained.Load() {
+		return
+	}
+
+	now := d.clock.Now()
+	idle := now.Sub(time.Unix(0, r.lastNext.Load()))
+	if idle < d.stallTimeout {
+		d.clock.AfterFunc(d.stallTimeout-idle, r.checkStall)
+		return
+	}
+
+	ev := m.leakEvent()
+	ev.Type = EventStalled
+	ev.Age = now.Sub(m.openedAt)
+	ev.Idle = idle
+	ev.quiet = false
+	d.attachPoolStats(&ev)
+	d.report(ev)
+}
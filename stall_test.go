@@ -0,0 +1,55 @@
+//go:build !sqleak_noop
+
+package sqleak
+
+import (
+	"context"
+	"database/sql/driver"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestStallTimeout(t *testing.T) {
+	var events []LeakEvent
+	mc, fc, logOutput := newTestConn(t,
+		WithTimeout(time.Hour),
+		WithStallTimeout(30*time.Second),
+		WithOnLeak(func(ev LeakEvent) { events = append(events, ev) }),
+	)
+	ctx := context.Background()
+	dest := make([]driver.Value, 1)
+
+	// Iterated to the end, then left open.
+	drained, _ := mc.QueryContext(ctx, "SELECT 1", nil)
+	defer drained.Close()
+	for drained.Next(dest) == nil {
+	}
+
+	rows, _ := mc.QueryContext(ctx, "SELECT 2", nil)
+	defer rows.Close()
+	fc.Advance(20 * time.Second)
+	if err := rows.Next(dest); err != nil {
+		t.Fatal(err)
+	}
+	fc.Advance(20 * time.Second)
+	if len(events) != 0 {
+		t.Fatalf("expected no report while Next is being called, got %+v", events)
+	}
+
+	fc.Advance(10 * time.Second)
+	if len(events) != 1 {
+		t.Fatalf("got %d events, want the stalled Rows only", len(events))
+	}
+	if ev := events[0]; ev.Type != EventStalled || ev.ResourceID != 2 || ev.Idle != 30*time.Second || ev.Age != 50*time.Second || ev.RowsFetched != 1 {
+		t.Errorf("unexpected event %+v", ev)
+	}
+	if want := `stalled Rows iteration: Rows stalled: Next not called for 30s, open for 50s (resource #2, query "SELECT 2"`; !strings.Contains(logOutput.String(), want) {
+		t.Errorf("expected %q in log, got:\n%s", want, logOutput.String())
+	}
+
+	fc.Advance(time.Minute)
+	if len(events) != 1 {
+		t.Errorf("expected a single report per Rows, got %+v", events[1:])
+	}
+}
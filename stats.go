@@ -0,0 +1,182 @@
+package sqleak
+
+import (
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// Stats describes the runtime state of a Detector.
+type Stats struct {
+	// Name is the name set by WithName, if any.
+	Name string
+	// Rows, Stmt and Tx count the resources of each kind, like sql.DBStats does for connections.
+	Rows, Stmt, Tx KindStats
+	// Overhead is the time the instrumentation itself spent on opening resources.
+	Overhead OverheadStats
+	// DroppedEvents counts leak events not delivered to a subscriber because its buffer was full.
+	DroppedEvents int64
+	// Budget is the state of the leak budget, the zero value if WithLeakBudget is not set.
+	Budget BudgetStats
+	// SlowConnects counts the connections that took longer to open than WithSlowConnectThreshold allows.
+	SlowConnects int64
+	// LongCheckouts counts the connections held out of the pool for longer than WithCheckoutWarning allows.
+	LongCheckouts int64
+	// SlowQueries counts the queries and executions that took longer than WithSlowQueryThreshold allows.
+	SlowQueries int64
+	// Reprepares counts the queries WithRepreparedWarning warned about for being prepared per execution.
+	Reprepares int64
+	// UnusedStmts counts the Stmt closed without being executed, with WithUnusedStmtWarning.
+	UnusedStmts int64
+	// Misuses counts the resources closed twice or used after being closed, with WithMisuseDetection.
+	Misuses int64
+	// ManualTxLeaks counts the connections returned to the pool within a transaction begun by a SQL statement,
+	// with WithManualTxTracking.
+	ManualTxLeaks int64
+	// SuppressedLeaks counts the leaks not logged because WithSuppressions matched their opening stack.
+	SuppressedLeaks int64
+	// Breaker is the state of the circuit breaker, the zero value if WithCircuitBreaker is not set.
+	Breaker BreakerStats
+}
+
+// KindStats counts the resources of one kind.
+type KindStats struct {
+	// Open is the number of resources currently open.
+	Open int64
+	// Leaked is the number of resources reported as leaked since the Detector started.
+	Leaked int64
+	// MaxAge is the longest a resource was observed open, when it was closed or reported as leaked.
+	MaxAge time.Duration
+	// Lifetime is the distribution of how long closed resources were open, e.g. to set the timeout above its p99.
+	Lifetime Histogram
+}
+
+// OverheadStats accumulates the cost of the instrumentation on the paths opening and closing resources.
+type OverheadStats struct {
+	// Opens is the number of resources a monitor was created for.
+	Opens int64
+
+	StackCaptures    int64
+	StackCaptureTime time.Duration
+
+	TimerSchedules    int64
+	TimerScheduleTime time.Duration
+
+	// LockWaits counts acquisitions of the hold tracker and sampler mutexes on the open and close paths.
+	// The registry of open resources is a lock-free map and is not measured.
+	LockWaits    int64
+	LockWaitTime time.Duration
+}
+
+// PerOpen returns the mean time added by the instrumentation per opened resource.
+func (o OverheadStats) PerOpen() time.Duration {
+	if o.Opens == 0 {
+		return 0
+	}
+
+	return (o.StackCaptureTime + o.TimerScheduleTime + o.LockWaitTime) / time.Duration(o.Opens)
+}
+
+// Stats returns a snapshot of the Detector's statistics.
+func (d *Detector) Stats() Stats {
+	if noop {
+		return Stats{}
+	}
+	stats := Stats{
+		Name:          d.name,
+		Rows:          d.kinds[kindIndex(KindRows)].stats(),
+		Stmt:          d.kinds[kindIndex(KindStmt)].stats(),
+		Tx:            d.kinds[kindIndex(KindTx)].stats(),
+		Overhead:      d.overhead.stats(),
+		DroppedEvents: d.droppedEvents.Load(),
+	}
+	if d.budget != nil {
+		stats.Budget = d.budget.stats(d.clock.Now())
+	}
+	if d.breaker != nil {
+		stats.Breaker = d.breaker.stats()
+	}
+	if d.slowConnect != nil {
+		stats.SlowConnects = d.slowConnect.count.Load()
+	}
+	if d.checkout != nil {
+		stats.LongCheckouts = d.checkout.count.Load()
+	}
+	if d.slowQuery != nil {
+		stats.SlowQueries = d.slowQuery.count.Load()
+	}
+	if d.reprepared != nil {
+		stats.Reprepares = d.reprepared.warned.Load()
+	}
+	if d.unusedStmts != nil {
+		stats.UnusedStmts = d.unusedStmts.count.Load()
+	}
+	if d.misuse != nil {
+		stats.Misuses = d.misuse.count.Load()
+	}
+	if d.manualTx != nil {
+		stats.ManualTxLeaks = d.manualTx.leaks.Load()
+	}
+	if d.suppress != nil {
+		stats.SuppressedLeaks = d.suppress.count.Load()
+	}
+
+	return stats
+}
+
+// overhead measures the instrumentation using the real monotonic clock, independently of the Detector's clock.
+type overhead struct {
+	opens             atomic.Int64
+	stackCaptures     atomic.Int64
+	stackCaptureNanos atomic.Int64
+	timerSchedules    atomic.Int64
+	timerNanos        atomic.Int64
+	lockWaits         atomic.Int64
+	lockWaitNanos     atomic.Int64
+}
+
+func (o *overhead) lock(mu *sync.Mutex) {
+	start := time.Now()
+	mu.Lock()
+	o.lockWaits.Add(1)
+	o.lockWaitNanos.Add(int64(time.Since(start)))
+}
+
+func (o *overhead) stats() OverheadStats {
+	return OverheadStats{
+		Opens:             o.opens.Load(),
+		StackCaptures:     o.stackCaptures.Load(),
+		StackCaptureTime:  time.Duration(o.stackCaptureNanos.Load()),
+		TimerSchedules:    o.timerSchedules.Load(),
+		TimerScheduleTime: time.Duration(o.timerNanos.Load()),
+		LockWaits:         o.lockWaits.Load(),
+		LockWaitTime:      time.Duration(o.lockWaitNanos.Load()),
+	}
+}
+
+// kindCounters accumulates the KindStats of one kind.
+type kindCounters struct {
+	open     atomic.Int64
+	leaked   atomic.Int64
+	maxAge   atomic.Int64
+	lifetime lifetimes
+}
+
+// observeAge raises the maximum age to age if it is higher.
+func (c *kindCounters) observeAge(age time.Duration) {
+	for {
+		prev := c.maxAge.Load()
+		if int64(age) <= prev || c.maxAge.CompareAndSwap(prev, int64(age)) {
+			return
+		}
+	}
+}
+
+func (c *kindCounters) stats() KindStats {
+	return KindStats{
+		Open:     c.open.Load(),
+		Leaked:   c.leaked.Load(),
+		MaxAge:   time.Duration(c.maxAge.Load()),
+		Lifetime: c.lifetime.histogram(),
+	}
+}
@@ -0,0 +1,204 @@
+// Package statsdsqleak emits metrics of sqleak Detectors over StatsD or DogStatsD, for alerting on leaks without
+// parsing logs:
+//
+//	emitter, err := statsdsqleak.New("127.0.0.1:8125", statsdsqleak.WithDogStatsD("service:orders"))
+//	db, err := sqleak.Open("postgres", dsn, sqleak.WithTimeout(time.Minute), sqleak.WithHooks(emitter.Hooks()))
+//
+// It reports the gauge sqleak.open of currently open resources, the counter sqleak.leaks of detected leaks, the
+// timer sqleak.close_latency of how long resources were open until closed and the histogram sqleak.rows_fetched of
+// the rows read from Rows until closed, all by resource kind. With plain StatsD the kind is appended to the metric
+// name, e.g. sqleak.open.rows, with DogStatsD it becomes the tag kind:rows.
+package statsdsqleak
+
+import (
+	"fmt"
+	"net"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/saiko-tech/sqleak"
+)
+
+const (
+	// defaultFlushInterval is how often gauges and counters are sent.
+	defaultFlushInterval = 10 * time.Second
+	// maxPacketSize keeps packets below the MTU of common networks, as recommended for StatsD.
+	maxPacketSize = 1432
+)
+
+var kinds = []sqleak.Kind{sqleak.KindRows, sqleak.KindStmt, sqleak.KindTx}
+
+// Emitter collects the metrics of the resources it observes through its Hooks and sends them to a StatsD server.
+// One emitter may observe several Detectors.
+type Emitter struct {
+	conn      net.Conn
+	prefix    string
+	dogstatsd bool
+	tags      []string
+	interval  time.Duration
+
+	open  map[sqleak.Kind]*atomic.Int64
+	leaks map[sqleak.Kind]*atomic.Int64
+
+	mu     sync.Mutex // guards buf
+	buf    []byte
+	stop   chan struct{}
+	done   chan struct{}
+	closed sync.Once
+}
+
+// Option configures an Emitter.
+type Option func(*Emitter)
+
+// WithPrefix replaces the "sqleak." prefix of metric names.
+func WithPrefix(prefix string) Option {
+	return func(e *Emitter) {
+		e.prefix = prefix
+	}
+}
+
+// WithDogStatsD sends metrics with DogStatsD tags, the kind tag and the given ones like "env:prod".
+func WithDogStatsD(tags ...string) Option {
+	return func(e *Emitter) {
+		e.dogstatsd = true
+		e.tags = append(e.tags, tags...)
+	}
+}
+
+// WithFlushInterval sets how often gauges, counters and buffered timings are sent, every 10 seconds by default.
+// Intervals below 1 keep the default.
+func WithFlushInterval(interval time.Duration) Option {
+	return func(e *Emitter) {
+		if interval > 0 {
+			e.interval = interval
+		}
+	}
+}
+
+// New starts an emitter sending UDP packets to the StatsD server at addr. Close it to send the last metrics.
+func New(addr string, opts ...Option) (*Emitter, error) {
+	conn, err := net.Dial("udp", addr)
+	if err != nil {
+		return nil, fmt.Errorf("statsdsqleak: %w", err)
+	}
+
+	e := &Emitter{
+		conn:     conn,
+		prefix:   "sqleak.",
+		interval: defaultFlushInterval,
+		open:     make(map[sqleak.Kind]*atomic.Int64),
+		leaks:    make(map[sqleak.Kind]*atomic.Int64),
+		stop:     make(chan struct{}),
+		done:     make(chan struct{}),
+	}
+	for _, kind := range kinds {
+		e.open[kind], e.leaks[kind] = new(atomic.Int64), new(atomic.Int64)
+	}
+	for _, opt := range opts {
+		opt(e)
+	}
+
+	go e.run()
+	return e, nil
+}
+
+// Hooks returns the hooks to pass to sqleak.WithHooks for every Detector the emitter observes.
+func (e *Emitter) Hooks() sqleak.Hooks {
+	return sqleak.Hooks{
+		OnOpen: func(r sqleak.Resource) {
+			if n, ok := e.open[r.Kind]; ok {
+				n.Add(1)
+			}
+		},
+		OnClose: func(r sqleak.Resource, openFor time.Duration) {
+			if n, ok := e.open[r.Kind]; ok {
+				n.Add(-1)
+			}
+			e.add(e.metric("close_latency", r.Kind, fmt.Sprintf("%g|ms", float64(openFor)/float64(time.Millisecond))))
+			if r.Kind == sqleak.KindRows {
+				e.add(e.metric("rows_fetched", r.Kind, fmt.Sprintf("%d|h", r.RowsFetched)))
+			}
+		},
+		OnLeak: func(ev sqleak.LeakEvent) {
+			if n, ok := e.leaks[ev.Kind]; ok && ev.Type == sqleak.EventLeak && ev.Occurrence == 1 {
+				n.Add(1)
+			}
+		},
+	}
+}
+
+// Close sends the remaining metrics and closes the connection.
+func (e *Emitter) Close() error {
+	e.closed.Do(func() { close(e.stop) })
+	<-e.done
+
+	return e.conn.Close()
+}
+
+func (e *Emitter) run() {
+	defer close(e.done)
+
+	ticker := time.NewTicker(e.interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ticker.C:
+			e.flush()
+		case <-e.stop:
+			e.flush()
+			return
+		}
+	}
+}
+
+// flush sends the gauges, the counters accumulated since the last flush and the buffered timings.
+func (e *Emitter) flush() {
+	for _, kind := range kinds {
+		e.add(e.metric("open", kind, fmt.Sprintf("%d|g", e.open[kind].Load())))
+		if n := e.leaks[kind].Swap(0); n > 0 {
+			e.add(e.metric("leaks", kind, fmt.Sprintf("%d|c", n)))
+		}
+	}
+
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	e.send()
+}
+
+// metric formats a metric line of the given kind, value and type, e.g. "5|g".
+func (e *Emitter) metric(name string, kind sqleak.Kind, value string) string {
+	kindName := strings.ToLower(string(kind))
+	if !e.dogstatsd {
+		return fmt.Sprintf("%s%s.%s:%s", e.prefix, name, kindName, value)
+	}
+
+	tags := append([]string{"kind:" + kindName}, e.tags...)
+	return fmt.Sprintf("%s%s:%s|#%s", e.prefix, name, value, strings.Join(tags, ","))
+}
+
+// add buffers a metric line, sending the buffer first if the line doesn't fit into the packet.
+func (e *Emitter) add(line string) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	if len(e.buf) > 0 && len(e.buf)+1+len(line) > maxPacketSize {
+		e.send()
+	}
+	if len(e.buf) > 0 {
+		e.buf = append(e.buf, '\n')
+	}
+	e.buf = append(e.buf, line...)
+}
+
+// send writes the buffer as one packet, ignoring errors as StatsD clients do.
+func (e *Emitter) send() {
+	if len(e.buf) == 0 {
+		return
+	}
+
+	_, _ = e.conn.Write(e.buf)
+	e.buf = e.buf[:0]
+}
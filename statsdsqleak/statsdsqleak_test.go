@@ -0,0 +1,94 @@
+package statsdsqleak
+
+import (
+	"net"
+	"slices"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/saiko-tech/sqleak"
+)
+
+func listen(t *testing.T) (string, func() []string) {
+	t.Helper()
+
+	conn, err := net.ListenPacket("udp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { conn.Close() })
+
+	return conn.LocalAddr().String(), func() []string {
+		buf := make([]byte, maxPacketSize)
+		_ = conn.SetReadDeadline(time.Now().Add(5 * time.Second))
+		n, _, err := conn.ReadFrom(buf)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return strings.Split(string(buf[:n]), "\n")
+	}
+}
+
+func TestEmitter(t *testing.T) {
+	for _, c := range []struct {
+		name string
+		opts []Option
+		want []string
+	}{
+		{"statsd", nil, []string{
+			"sqleak.close_latency.rows:1500|ms",
+			"sqleak.rows_fetched.rows:42|h",
+			"sqleak.open.rows:1|g",
+			"sqleak.leaks.rows:1|c",
+			"sqleak.open.stmt:0|g",
+			"sqleak.open.tx:1|g",
+		}},
+		{"dogstatsd", []Option{WithDogStatsD("env:test"), WithPrefix("db.")}, []string{
+			"db.close_latency:1500|ms|#kind:rows,env:test",
+			"db.rows_fetched:42|h|#kind:rows,env:test",
+			"db.open:1|g|#kind:rows,env:test",
+			"db.leaks:1|c|#kind:rows,env:test",
+			"db.open:0|g|#kind:stmt,env:test",
+			"db.open:1|g|#kind:tx,env:test",
+		}},
+	} {
+		t.Run(c.name, func(t *testing.T) {
+			addr, receive := listen(t)
+			e, err := New(addr, append(c.opts, WithFlushInterval(time.Hour))...)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			hooks := e.Hooks()
+			rows := sqleak.Resource{Kind: sqleak.KindRows, RowsFetched: 42}
+			hooks.OnOpen(rows)
+			hooks.OnOpen(rows)
+			hooks.OnOpen(sqleak.Resource{Kind: sqleak.KindTx})
+			hooks.OnLeak(sqleak.LeakEvent{Type: sqleak.EventLeak, Kind: sqleak.KindRows, Occurrence: 1})
+			hooks.OnLeak(sqleak.LeakEvent{Type: sqleak.EventLeak, Kind: sqleak.KindRows, Occurrence: 2})
+			hooks.OnClose(rows, 1500*time.Millisecond)
+
+			if err = e.Close(); err != nil {
+				t.Fatal(err)
+			}
+			if got := receive(); !slices.Equal(got, c.want) {
+				t.Errorf("got metrics\n%s\nwant\n%s", strings.Join(got, "\n"), strings.Join(c.want, "\n"))
+			}
+		})
+	}
+}
+
+func TestFlushIntervalInvalid(t *testing.T) {
+	addr, _ := listen(t)
+
+	e, err := New(addr, WithFlushInterval(0))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer e.Close()
+
+	if e.interval != defaultFlushInterval {
+		t.Errorf("expected the default flush interval for 0, got %s", e.interval)
+	}
+}
@@ -17,29 +17,44 @@ type monitoredStmt struct {
 	driver.Stmt
 	monitor       *monitor
 	monitoredConn *monitoredConn
+	query         string
+	preparedBy    []uintptr // with WithUnusedStmtWarning
 }
 
-func newMonitoredStmt(stmt driver.Stmt, mc *monitoredConn) *monitoredStmt {
-	return &monitoredStmt{
+func newMonitoredStmt(ctx context.Context, stmt driver.Stmt, mc *monitoredConn, query string) *monitoredStmt {
+	s := &monitoredStmt{
 		Stmt:          stmt,
-		monitor:       newMonitor(mc.timeout, "Stmt"),
+		monitor:       newMonitor(ctx, mc, KindStmt, query, nil, nil, false),
 		monitoredConn: mc,
+		query:         query,
 	}
+	if !s.monitor.ignored {
+		mc.prepared(query, s.monitor)
+		s.recordPreparer()
+	}
+
+	return s
 }
 
 func (s *monitoredStmt) Close() error {
-	s.monitor.markClosed()
+	s.monitor.closing("Close")
+	if s.monitor.state.Load() != stateClosed {
+		s.checkUnused()
+	}
 
-	return s.Stmt.Close()
+	s.monitoredConn.enter()
+	defer s.monitoredConn.leave()
+
+	return s.monitor.closeWith("Close", s.Stmt.Close)
 }
 
-func (s *monitoredStmt) Query(args []driver.Value) (driver.Rows, error) {
-	rows, err := s.Stmt.Query(args)
-	if err != nil {
-		return nil, err
-	}
+// Exec and Query are the legacy variants of ExecContext and QueryContext and share their code paths.
+func (s *monitoredStmt) Exec(args []driver.Value) (driver.Result, error) {
+	return s.ExecContext(context.Background(), valueToNamedValue(args))
+}
 
-	return newMonitoredRows(rows, s.monitor.timeout), nil
+func (s *monitoredStmt) Query(args []driver.Value) (driver.Rows, error) {
+	return s.QueryContext(context.Background(), valueToNamedValue(args))
 }
 
 // Copied from stdlib database/sql package: src/database/sql/ctxutil.go.
@@ -54,7 +69,28 @@ func namedValueToValue(named []driver.NamedValue) ([]driver.Value, error) {
 	return dargs, nil
 }
 
+// valueToNamedValue converts legacy positional arguments, the inverse of namedValueToValue.
+func valueToNamedValue(args []driver.Value) []driver.NamedValue {
+	nargs := make([]driver.NamedValue, len(args))
+	for n, v := range args {
+		nargs[n] = driver.NamedValue{Ordinal: n + 1, Value: v}
+	}
+	return nargs
+}
+
 func (s *monitoredStmt) ExecContext(ctx context.Context, args []driver.NamedValue) (result driver.Result, err error) {
+	if err = s.monitoredConn.admit(); err != nil {
+		return nil, err
+	}
+	s.monitor.using("Exec")
+	s.monitoredConn.enter()
+	defer s.monitoredConn.leave()
+	s.monitor.execs.Add(1)
+	s.monitor.touch()
+	s.monitoredConn.touchTx()
+	s.monitoredConn.trackManualTx(s.query)
+	defer s.monitoredConn.detector.queryDone(s.monitoredConn.detector.queryStarted(), s.query)
+
 	if execer, ok := s.Stmt.(driver.StmtExecContext); ok {
 		return execer.ExecContext(ctx, args)
 	}
@@ -75,6 +111,17 @@ func (s *monitoredStmt) ExecContext(ctx context.Context, args []driver.NamedValu
 }
 
 func (s *monitoredStmt) QueryContext(ctx context.Context, args []driver.NamedValue) (rows driver.Rows, err error) {
+	if err = s.monitoredConn.admit(); err != nil {
+		return nil, err
+	}
+	s.monitor.using("Query")
+	s.monitoredConn.enter()
+	defer s.monitoredConn.leave()
+	s.monitor.execs.Add(1)
+	s.monitor.touch()
+	s.monitoredConn.touchTx()
+	defer s.monitoredConn.detector.queryDone(s.monitoredConn.detector.queryStarted(), s.query)
+
 	if query, ok := s.Stmt.(driver.StmtQueryContext); ok {
 		if rows, err = query.QueryContext(ctx, args); err != nil {
 			return nil, err
@@ -97,7 +144,7 @@ func (s *monitoredStmt) QueryContext(ctx context.Context, args []driver.NamedVal
 		}
 	}
 
-	return newMonitoredRows(rows, s.monitor.timeout), nil
+	return newMonitoredRows(ctx, rows, s.monitoredConn, s.query, args), nil
 }
 
 func (s *monitoredStmt) CheckNamedValue(namedValue *driver.NamedValue) error {
@@ -0,0 +1,200 @@
+//go:build !sqleak_noop
+
+package sqleak
+
+import (
+	"bytes"
+	"context"
+	"database/sql/driver"
+	"log"
+	"math/rand"
+	"os"
+	"runtime"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+// stressResource is a resource opened by the harness together with its planned lifetime.
+type stressResource struct {
+	kind     string
+	openedAt int // tick
+	lifetime int // ticks
+	close    func() error
+}
+
+func (r stressResource) leaks(timeoutTicks int) bool {
+	return r.lifetime >= timeoutTicks
+}
+
+func openStressResource(t *testing.T, mc *monitoredConn, rng *rand.Rand) stressResource {
+	ctx := context.Background()
+
+	switch rng.Intn(3) {
+	case 0:
+		rows, err := mc.QueryContext(ctx, "SELECT 1", nil)
+		if err != nil {
+			t.Error(err)
+		}
+		return stressResource{kind: "Rows", close: rows.Close}
+	case 1:
+		stmt, err := mc.PrepareContext(ctx, "SELECT 1")
+		if err != nil {
+			t.Error(err)
+		}
+		return stressResource{kind: "Stmt", close: stmt.Close}
+	default:
+		tx, err := mc.BeginTx(ctx, driver.TxOptions{})
+		if err != nil {
+			t.Error(err)
+		}
+		if rng.Intn(2) == 0 {
+			return stressResource{kind: "Tx", close: tx.Commit}
+		}
+		return stressResource{kind: "Tx", close: tx.Rollback}
+	}
+}
+
+// TestStressCloseOrdering opens and closes Rows/Stmt/Tx across many goroutines in randomized interleavings
+// against a fake clock and checks that exactly the resources held past the timeout are reported.
+// Run with -race to also cover the monitor's synchronization.
+func TestStressCloseOrdering(t *testing.T) {
+	const (
+		tick         = time.Second
+		timeoutTicks = 5
+		workers      = 8
+	)
+	ticks, opensPerTick := 60, 4
+	if testing.Short() {
+		ticks, opensPerTick = 20, 2
+	}
+
+	var logOutput bytes.Buffer // only written from Advance, which runs on the test goroutine
+	log.SetOutput(&logOutput)
+	defer log.SetOutput(os.Stderr)
+
+	fc := newFakeClock()
+	d := WrapDriver(fakeDriver{}, WithTimeout(timeoutTicks*tick), withClock(fc))
+
+	var memBefore runtime.MemStats
+	runtime.GC()
+	runtime.ReadMemStats(&memBefore)
+
+	conns := make([]*monitoredConn, workers)
+	for i := range conns {
+		conn, err := d.Open("")
+		if err != nil {
+			t.Fatal(err)
+		}
+		conns[i] = conn.(*monitoredConn)
+	}
+
+	seed := time.Now().UnixNano()
+	t.Logf("seed: %d", seed)
+	rng := rand.New(rand.NewSource(seed))
+
+	var (
+		open     []stressResource
+		expected = map[string]int{}
+	)
+
+	for now := 0; now < ticks+2*timeoutTicks; now++ {
+		// Partition the resources that are due at this tick randomly across workers,
+		// so resources are closed by different goroutines than the ones that opened them.
+		var still []stressResource
+		due := make([][]stressResource, workers)
+		for _, r := range open {
+			if r.openedAt+r.lifetime == now {
+				w := rng.Intn(workers)
+				due[w] = append(due[w], r)
+				if r.leaks(timeoutTicks) {
+					expected[r.kind]++
+				}
+			} else {
+				still = append(still, r)
+			}
+		}
+		open = still
+
+		seeds := make([]int64, workers)
+		for w := range seeds {
+			seeds[w] = rng.Int63()
+		}
+
+		var (
+			mu     sync.Mutex
+			wg     sync.WaitGroup
+			opened []stressResource
+		)
+		for w := 0; w < workers; w++ {
+			wg.Add(1)
+			go func(w int) {
+				defer wg.Done()
+
+				wrng := rand.New(rand.NewSource(seeds[w]))
+				toClose := due[w]
+				wrng.Shuffle(len(toClose), func(i, j int) { toClose[i], toClose[j] = toClose[j], toClose[i] })
+
+				var mine []stressResource
+				for len(toClose) > 0 || (now < ticks && len(mine) < opensPerTick) {
+					// Randomly interleave opens and closes within the tick.
+					if len(toClose) > 0 && (wrng.Intn(2) == 0 || now >= ticks || len(mine) >= opensPerTick) {
+						if err := toClose[0].close(); err != nil {
+							t.Error(err)
+						}
+						toClose = toClose[1:]
+						continue
+					}
+
+					r := openStressResource(t, conns[w], wrng)
+					r.openedAt = now
+					r.lifetime = 1 + wrng.Intn(2*timeoutTicks)
+					mine = append(mine, r)
+				}
+
+				mu.Lock()
+				opened = append(opened, mine...)
+				mu.Unlock()
+			}(w)
+		}
+		wg.Wait()
+
+		open = append(open, opened...)
+		fc.Advance(tick)
+	}
+
+	if len(open) != 0 {
+		t.Fatalf("harness bug: %d resources still open", len(open))
+	}
+
+	got := map[string]int{}
+	for _, line := range strings.Split(logOutput.String(), "\n") {
+		for _, kind := range []string{"Rows", "Stmt", "Tx"} {
+			if strings.Contains(line, "likely resource leak detected: "+kind+" not closed") {
+				got[kind]++
+			}
+		}
+	}
+
+	for _, kind := range []string{"Rows", "Stmt", "Tx"} {
+		if got[kind] != expected[kind] {
+			t.Errorf("%s: got %d leak warnings, want %d", kind, got[kind], expected[kind])
+		}
+	}
+
+	if n := fc.Pending(); n != 0 {
+		t.Errorf("%d monitor timers still pending after all deadlines passed", n)
+	}
+
+	logOutput.Reset()
+	runtime.GC()
+	var memAfter runtime.MemStats
+	runtime.ReadMemStats(&memAfter)
+
+	// Every monitor has fired, so nothing opened by the harness should still be reachable.
+	const budget = 16 << 20
+	if growth := int64(memAfter.HeapInuse) - int64(memBefore.HeapInuse); growth > budget {
+		t.Errorf("heap grew by %d bytes, want at most %d", growth, budget)
+	}
+}
@@ -0,0 +1,44 @@
+package sqleak
+
+// WithStrictClose makes closing the sql.DB fail with a *LeakError if any resource was reported as leaked during its
+// lifetime or is still open when it is closed, so services can fail their shutdown checks when leaks occurred.
+// onLeaks, if not nil, is called with the error as well, for frameworks that discard the error of db.Close.
+func WithStrictClose(onLeaks func(*LeakError)) Option {
+	return func(ld *monitoredDriver) {
+		ld.strictClose = true
+		ld.onStrictClose = onLeaks
+	}
+}
+
+// LeakError is returned by closing a sql.DB set up WithStrictClose when resources leaked.
+type LeakError struct {
+	// Summary is the paragraph of Detector.ExitReport.
+	Summary string
+	// Leaked counts the resources reported as leaked plus those still open but not yet reported, see ExitReport.
+	Leaked int
+	// Outstanding holds an EventOutstanding for every resource still open when the sql.DB was closed.
+	Outstanding []LeakEvent
+}
+
+func (e *LeakError) Error() string {
+	return "sqleak: " + e.Summary
+}
+
+// closeError returns the *LeakError of WithStrictClose once the sql.DB is closed, nil if nothing leaked.
+func (d *Detector) closeError() error {
+	if !d.strictClose {
+		return nil
+	}
+
+	summary, leaked := d.ExitReport()
+	if leaked == 0 {
+		return nil
+	}
+
+	err := &LeakError{Summary: summary, Leaked: leaked, Outstanding: d.Outstanding()}
+	if d.onStrictClose != nil {
+		d.onStrictClose(err)
+	}
+
+	return err
+}
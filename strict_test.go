@@ -0,0 +1,54 @@
+//go:build !sqleak_noop
+
+package sqleak
+
+import (
+	"database/sql"
+	"database/sql/driver"
+	"errors"
+	"io"
+	"log"
+	"os"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestStrictClose(t *testing.T) {
+	log.SetOutput(io.Discard)
+	t.Cleanup(func() { log.SetOutput(os.Stderr) })
+
+	open := func(opts ...Option) (*sql.DB, *fakeClock) {
+		fc := newFakeClock()
+		d := WrapDriver(fakeDriver{}, append([]Option{withClock(fc), WithTimeout(time.Second)}, opts...)...)
+		connector, err := d.(driver.DriverContext).OpenConnector("")
+		if err != nil {
+			t.Fatal(err)
+		}
+		return sql.OpenDB(connector), fc
+	}
+
+	var called *LeakError
+	db, fc := open(WithStrictClose(func(err *LeakError) { called = err }))
+	leaked, _ := db.Query("SELECT 1")
+	fc.Advance(time.Second)
+	leaked.Close()
+	stillOpen, _ := db.Query("SELECT 2")
+	defer stillOpen.Close()
+
+	err := db.Close()
+	var leakErr *LeakError
+	if !errors.As(err, &leakErr) || leakErr.Leaked != 2 || len(leakErr.Outstanding) != 1 || called != leakErr {
+		t.Fatalf("expected a LeakError for 2 leaks with 1 still open, also passed to the callback, got %v", err)
+	}
+	if want := "sqleak: 2 resources leaked: 1 detected during the run, 1 open (Rows: 1) at exit"; !strings.HasPrefix(err.Error(), want) {
+		t.Errorf("got error %q, want it to start with %q", err, want)
+	}
+
+	clean, _ := open(WithStrictClose(nil))
+	rows, _ := clean.Query("SELECT 1")
+	rows.Close()
+	if err := clean.Close(); err != nil {
+		t.Errorf("expected closing without leaks to succeed, got %v", err)
+	}
+}
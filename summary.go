@@ -0,0 +1,97 @@
+package sqleak
+
+import (
+	"fmt"
+	"log"
+	"strings"
+	"sync"
+	"time"
+)
+
+// WithSummaryInterval logs a heartbeat line every interval like
+// "open: 3 Rows (oldest 42s), 1 Tx (oldest 12m0s), leaks so far: 7", to watch the open resources without any
+// per-leak noise. There is no heartbeat by default, intervals below 1 keep it off.
+func WithSummaryInterval(interval time.Duration) Option {
+	return func(ld *monitoredDriver) {
+		if interval <= 0 {
+			return
+		}
+		ld.summary = &summaryLogger{interval: interval}
+	}
+}
+
+type summaryLogger struct {
+	interval time.Duration
+
+	mu      sync.Mutex
+	timer   timer
+	stopped bool
+}
+
+func (s *summaryLogger) start(d *Detector) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.timer = d.clock.AfterFunc(s.interval, func() { s.tick(d) })
+}
+
+func (s *summaryLogger) stop() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.stopped = true
+	if s.timer != nil {
+		s.timer.Stop()
+	}
+}
+
+func (s *summaryLogger) tick(d *Detector) {
+	log.Print(d.logPrefix() + d.openSummary())
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if !s.stopped {
+		s.timer = d.clock.AfterFunc(s.interval, func() { s.tick(d) })
+	}
+}
+
+// openSummary counts the open resources by kind with the age of the oldest, and the leaks so far.
+func (d *Detector) openSummary() string {
+	var (
+		counts [3]int
+		oldest [3]time.Time
+	)
+	for _, m := range d.openMonitors() {
+		if m.state.Load() == stateClosed {
+			continue
+		}
+		i := kindIndex(m.kind)
+		counts[i]++
+		if oldest[i].IsZero() || m.openedAt.Before(oldest[i]) {
+			oldest[i] = m.openedAt
+		}
+	}
+
+	now := d.clock.Now()
+	var kinds []string
+	for _, kind := range []Kind{KindRows, KindStmt, KindTx} {
+		if i := kindIndex(kind); counts[i] > 0 {
+			kinds = append(kinds, fmt.Sprintf("%d %s (oldest %s)", counts[i], kind, roundAge(now.Sub(oldest[i]))))
+		}
+	}
+	if len(kinds) == 0 {
+		kinds = []string{"none"}
+	}
+
+	return fmt.Sprintf("open: %s, leaks so far: %d", strings.Join(kinds, ", "), d.leakIDs.Load())
+}
+
+// roundAge rounds ages for summaries, to seconds unless they are shorter.
+func roundAge(age time.Duration) time.Duration {
+	if age < time.Second {
+		return age.Round(time.Millisecond)
+	}
+
+	return age.Round(time.Second)
+}
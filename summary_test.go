@@ -0,0 +1,55 @@
+//go:build !sqleak_noop
+
+package sqleak
+
+import (
+	"context"
+	"database/sql/driver"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestSummaryInterval(t *testing.T) {
+	mc, fc, logOutput := newTestConn(t, WithTimeout(time.Minute), WithSummaryInterval(5*time.Minute))
+	ctx := context.Background()
+
+	fc.Advance(5 * time.Minute)
+	tx, _ := mc.BeginTx(ctx, driver.TxOptions{})
+	defer tx.Rollback()
+	fc.Advance(3 * time.Minute)
+	for i := 0; i < 3; i++ {
+		rows, _ := mc.QueryContext(ctx, "SELECT 1", nil)
+		defer rows.Close()
+	}
+	fc.Advance(2 * time.Minute)
+
+	var lines []string
+	for _, line := range strings.Split(logOutput.String(), "\n") {
+		if _, summary, ok := strings.Cut(line, "open: "); ok {
+			lines = append(lines, summary)
+		}
+	}
+	want := []string{
+		"none, leaks so far: 0",
+		"3 Rows (oldest 2m0s), 1 Tx (oldest 5m0s), leaks so far: 4",
+	}
+	if strings.Join(lines, "\n") != strings.Join(want, "\n") {
+		t.Errorf("got summaries:\n%s\nwant:\n%s", strings.Join(lines, "\n"), strings.Join(want, "\n"))
+	}
+
+	mc.detector.stop()
+	fc.Advance(5 * time.Minute)
+	if n := strings.Count(logOutput.String(), "leaks so far"); n != 2 {
+		t.Errorf("expected no summaries after stop, got %d in total", n)
+	}
+}
+
+func TestSummaryIntervalInvalid(t *testing.T) {
+	mc, fc, logOutput := newTestConn(t, WithSummaryInterval(0))
+
+	fc.Advance(time.Minute)
+	if fc.Pending() != 0 || logOutput.Len() != 0 || mc.detector.Config().SummaryInterval != 0 {
+		t.Errorf("expected no heartbeat for a zero interval, got %d timers and:\n%s", fc.Pending(), logOutput.String())
+	}
+}
@@ -0,0 +1,62 @@
+package sqleak
+
+import (
+	"strings"
+	"sync/atomic"
+)
+
+// WithSuppressions silences the reports of leaks opened by code paths accepted as long-lived: a leak is suppressed
+// if a pattern is a prefix of the function of one of the opening frames, e.g. a package path like
+// "example.com/app/reports.", or occurs anywhere in the opening stack, e.g. a file name. Suppressed leaks are
+// neither logged nor written to sinks, but still passed to WithOnLeak and hooks and counted in Stats, with
+// Stats().SuppressedLeaks counting them separately. Resources not sampled or opened WithoutStacks can't be matched.
+// It may be passed several times.
+func WithSuppressions(patterns ...string) Option {
+	return func(ld *monitoredDriver) {
+		if ld.suppress == nil {
+			ld.suppress = &suppressions{}
+		}
+		ld.suppress.patterns = append(ld.suppress.patterns, patterns...)
+	}
+}
+
+type suppressions struct {
+	patterns []string
+	count    atomic.Int64
+}
+
+// matches reports whether the opening stack or frames of a leak match one of the patterns.
+func (s *suppressions) matches(stack string, frames []Frame) bool {
+	for _, pattern := range s.patterns {
+		if strings.Contains(stack, pattern) {
+			return true
+		}
+		for _, f := range frames {
+			if strings.HasPrefix(f.Function, pattern) || strings.Contains(f.File, pattern) {
+				return true
+			}
+		}
+	}
+
+	return false
+}
+
+// suppressed reports whether the leak of the resource is suppressed, counting it if so.
+func (m *monitor) suppressed() bool {
+	s := m.detector.suppress
+	if s == nil {
+		return false
+	}
+
+	// Untrimmed, to match frames of any package.
+	stack, frames := string(m.stack), parseStack(string(m.stack))
+	if m.callers != nil {
+		frames = callerFrames(m.callers)
+	}
+	if !s.matches(stack, frames) {
+		return false
+	}
+	s.count.Add(1)
+
+	return true
+}
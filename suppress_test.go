@@ -0,0 +1,76 @@
+//go:build !sqleak_noop
+
+package sqleak
+
+import (
+	"context"
+	"database/sql/driver"
+	"strings"
+	"testing"
+	"time"
+)
+
+func openArchive(t *testing.T, mc *monitoredConn) driver.Rows {
+	rows, err := mc.QueryContext(context.Background(), "SELECT * FROM archive", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return rows
+}
+
+func TestSuppressions(t *testing.T) {
+	var events []LeakEvent
+	mc, fc, logOutput := newTestConn(t,
+		WithTimeout(time.Second),
+		WithSuppressions("github.com/saiko-tech/sqleak.openArchive"),
+		WithOnLeak(func(ev LeakEvent) { events = append(events, ev) }),
+	)
+
+	archive := openArchive(t, mc)
+	defer archive.Close()
+	rows, _ := mc.QueryContext(context.Background(), "SELECT 1", nil)
+	defer rows.Close()
+	fc.Advance(time.Second)
+
+	if len(events) != 2 {
+		t.Fatalf("expected both leaks in callbacks, got %+v", events)
+	}
+	out := logOutput.String()
+	if strings.Contains(out, "archive") || !strings.Contains(out, `"SELECT 1"`) {
+		t.Errorf("expected only the unsuppressed leak in log, got:\n%s", out)
+	}
+	if stats := mc.detector.Stats(); stats.Rows.Leaked != 2 || stats.SuppressedLeaks != 1 {
+		t.Errorf("expected suppressed leaks to be counted, got %+v", stats)
+	}
+}
+
+func TestSuppressionsFailMode(t *testing.T) {
+	mc, fc, _ := newTestConn(t,
+		WithTimeout(time.Second),
+		WithFailMode(FailPanic),
+		WithSuppressions("github.com/saiko-tech/sqleak.openArchive"),
+	)
+
+	archive := openArchive(t, mc)
+	defer archive.Close()
+
+	defer func() {
+		if recovered := recover(); recovered != nil {
+			t.Fatalf("expected no panic for a suppressed leak, got %v", recovered)
+		}
+	}()
+	fc.Advance(time.Second)
+
+	if stats := mc.detector.Stats(); stats.SuppressedLeaks != 1 {
+		t.Errorf("expected the suppressed leak to be counted, got %+v", stats)
+	}
+}
+
+func TestSuppressionsConfigCopy(t *testing.T) {
+	mc, _, _ := newTestConn(t, WithSuppressions("example.com/app/reports."))
+
+	mc.detector.Config().Suppressions[0] = "example.com/app/"
+	if got := mc.detector.Config().Suppressions; got[0] != "example.com/app/reports." {
+		t.Errorf("expected Config to return a copy of the suppressions, got %q", got)
+	}
+}
@@ -0,0 +1,35 @@
+//go:build !windows && !plan9
+
+package sqleak
+
+import "log/syslog"
+
+// SyslogSink is a Sink writing every report as a single line to the system logger, at the priority of its severity:
+// critical, error, warning or info, and notice for late closes. The stack stays out of the line, see WithSingleLineLog.
+type SyslogSink struct {
+	w *syslog.Writer
+}
+
+// NewSyslogSink returns a sink writing to w, e.g. from syslog.New(syslog.LOG_DAEMON, "myapp").
+// The priority w was created with is replaced by the one of each event, its facility is kept.
+func NewSyslogSink(w *syslog.Writer) *SyslogSink {
+	return &SyslogSink{w: w}
+}
+
+// WriteEvent writes ev at its priority.
+func (s *SyslogSink) WriteEvent(ev LeakEvent) error {
+	line := ev.singleLine()
+
+	switch ev.priority() {
+	case priorityCritical:
+		return s.w.Crit(line)
+	case priorityError:
+		return s.w.Err(line)
+	case priorityNotice:
+		return s.w.Notice(line)
+	case priorityInfo:
+		return s.w.Info(line)
+	}
+
+	return s.w.Warning(line)
+}
@@ -0,0 +1,66 @@
+//go:build !windows && !plan9 && !sqleak_noop
+
+package sqleak
+
+import (
+	"log/syslog"
+	"net"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+// listenUnixgram receives datagrams on a socket in a temporary directory.
+func listenUnixgram(t *testing.T) (string, func() string) {
+	t.Helper()
+
+	path := filepath.Join(t.TempDir(), "log.sock")
+	conn, err := net.ListenUnixgram("unixgram", &net.UnixAddr{Name: path, Net: "unixgram"})
+	if err != nil {
+		t.Skipf("unix datagram sockets unavailable: %v", err)
+	}
+	t.Cleanup(func() { conn.Close() })
+
+	return path, func() string {
+		buf := make([]byte, 64*1024)
+		_ = conn.SetReadDeadline(time.Now().Add(5 * time.Second))
+		n, err := conn.Read(buf)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return string(buf[:n])
+	}
+}
+
+func TestSyslogSink(t *testing.T) {
+	path, receive := listenUnixgram(t)
+	w, err := syslog.Dial("unixgram", path, syslog.LOG_DAEMON|syslog.LOG_INFO, "sqleak-test")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer w.Close()
+	sink := NewSyslogSink(w)
+
+	for _, c := range []struct {
+		ev     LeakEvent
+		prefix string
+	}{
+		{LeakEvent{Type: EventLeak, Kind: KindTx, Severity: SeverityCritical, Timeout: time.Second}, "<26>"},
+		{LeakEvent{Type: EventLeak, Kind: KindRows, Severity: SeverityWarning, Timeout: time.Second}, "<28>"},
+		{LeakEvent{Type: EventClosedLate, Kind: KindRows, Severity: SeverityCritical, Timeout: time.Second}, "<29>"},
+	} {
+		c.ev.Frames = []Frame{{Function: "main.run", File: "main.go", Line: 7}}
+		if err = sink.WriteEvent(c.ev); err != nil {
+			t.Fatal(err)
+		}
+
+		msg := receive()
+		if !strings.HasPrefix(msg, c.prefix) || !strings.Contains(msg, "sqleak-test[") || strings.Count(msg, "\n") > 1 {
+			t.Errorf("expected a single line with priority %s, got %q", c.prefix, msg)
+		}
+		if c.ev.Type == EventLeak && !strings.Contains(msg, " at main.run (main.go:7)") {
+			t.Errorf("expected the opening function in %q", msg)
+		}
+	}
+}
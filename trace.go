@@ -0,0 +1,19 @@
+package sqleak
+
+import "context"
+
+// Trace ties a resource to the trace of the context it was opened with, see WithTraceExtractor.
+type Trace struct {
+	TraceID, SpanID string
+	// OnLeak, if set, is called with every leak report of the resource, e.g. to add an event to the span.
+	OnLeak func(LeakEvent)
+}
+
+// WithTraceExtractor reads the trace of the context every monitored resource is opened with and adds its trace and
+// span ID to the resource's leak reports, tying leaks to the traces of the requests that caused them.
+// otelsqleak.Extract reads OpenTelemetry spans, e.g. those started by otelsql.
+func WithTraceExtractor(extract func(ctx context.Context) Trace) Option {
+	return func(ld *monitoredDriver) {
+		ld.extractTrace = extract
+	}
+}
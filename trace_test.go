@@ -0,0 +1,46 @@
+//go:build !sqleak_noop
+
+package sqleak
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"time"
+)
+
+type traceKey struct{}
+
+func TestTraceExtractor(t *testing.T) {
+	var spanEvents []LeakEvent
+	mc, fc, logOutput := newTestConn(t, WithTimeout(time.Second), WithTraceExtractor(func(ctx context.Context) Trace {
+		id, _ := ctx.Value(traceKey{}).(string)
+		if id == "" {
+			return Trace{}
+		}
+		return Trace{TraceID: id, SpanID: "00f067aa0ba902b7", OnLeak: func(ev LeakEvent) { spanEvents = append(spanEvents, ev) }}
+	}))
+
+	ctx := context.WithValue(context.Background(), traceKey{}, "4bf92f3577b34da6a3ce929d0e0e4736")
+	traced, _ := mc.QueryContext(ctx, "SELECT 1", nil)
+	defer traced.Close()
+	untraced, _ := mc.QueryContext(context.Background(), "SELECT 2", nil)
+	defer untraced.Close()
+	fc.Advance(time.Second)
+
+	if len(spanEvents) != 1 || spanEvents[0].TraceID != "4bf92f3577b34da6a3ce929d0e0e4736" || spanEvents[0].Query != "SELECT 1" {
+		t.Fatalf("expected the traced leak on its span, got %+v", spanEvents)
+	}
+	if want := ", trace 4bf92f3577b34da6a3ce929d0e0e4736 span 00f067aa0ba902b7"; strings.Count(logOutput.String(), want) != 1 {
+		t.Errorf("expected %q once in log, got:\n%s", want, logOutput.String())
+	}
+
+	b, err := spanEvents[0].MarshalJSON()
+	if err != nil {
+		t.Fatal(err)
+	}
+	decoded, err := DecodeLeakEvent(b)
+	if err != nil || decoded.TraceID != spanEvents[0].TraceID || decoded.SpanID != "00f067aa0ba902b7" {
+		t.Errorf("trace lost in JSON: %s", b)
+	}
+}
@@ -1,32 +1,72 @@
 package sqleak
 
 import (
+	"context"
 	"database/sql/driver"
-	"time"
+	"sync"
 )
 
 var _ driver.Tx = (*monitoredTx)(nil)
 
 type monitoredTx struct {
 	driver.Tx
-	monitor *monitor
+	monitor       *monitor
+	monitoredConn *monitoredConn
+
+	mu         sync.Mutex // serializes ending the Tx with WithAutoRollback and WithLongTxThreshold
+	done       bool
+	rolledBack bool // by WithAutoRollback
 }
 
-func newMonitoredTx(tx driver.Tx, timeout time.Duration) *monitoredTx {
-	return &monitoredTx{
-		Tx:      tx,
-		monitor: newMonitor(timeout, "Tx"),
+func newMonitoredTx(ctx context.Context, tx driver.Tx, mc *monitoredConn) *monitoredTx {
+	mc.inTx.Store(true)
+
+	mt := &monitoredTx{
+		Tx:            tx,
+		monitor:       newMonitor(ctx, mc, KindTx, "", nil, nil, true),
+		monitoredConn: mc,
+	}
+	mc.tx.Store(mt.monitor)
+	if mt.monitor.ignored {
+		return mt
 	}
+	if d := mc.detector; d.autoRollback > 0 {
+		d.clock.AfterFunc(d.autoRollback, mt.forceRollback)
+	}
+	if d := mc.detector; d.longTx > 0 {
+		d.clock.AfterFunc(d.longTx, mt.reportLongHeld)
+	}
+
+	return mt
 }
 
 func (mt *monitoredTx) Commit() error {
-	mt.monitor.markClosed()
+	if mt.end("Commit") {
+		return ErrForcedRollback
+	}
 
-	return mt.Tx.Commit()
+	return mt.monitor.closeWith("Commit", mt.Tx.Commit)
 }
 
 func (mt *monitoredTx) Rollback() error {
-	mt.monitor.markClosed()
+	if mt.end("Rollback") {
+		return nil
+	}
+
+	return mt.monitor.closeWith("Rollback", mt.Tx.Rollback)
+}
+
+// end marks the Tx as ended by the application with op, to be marked closed by closeWith, and reports whether
+// WithAutoRollback rolled it back before.
+func (mt *monitoredTx) end(op string) (rolledBack bool) {
+	mt.mu.Lock()
+	defer mt.mu.Unlock()
+
+	mt.monitor.closing(op)
+	mt.done = true
+	mt.monitoredConn.inTx.Store(false)
+	mt.monitoredConn.tx.Store(nil)
+	mt.monitoredConn.rolledBack.Store(false)
 
-	return mt.Tx.Rollback()
+	return mt.rolledBack
 }
@@ -0,0 +1,49 @@
+package sqleak
+
+import (
+	"fmt"
+	"log"
+	"runtime"
+	"sync/atomic"
+)
+
+// WithUnusedStmtWarning warns when a Stmt is closed without having been executed, naming the application function
+// that prepared it. Such dead prepares cost two round trips to the database for nothing and are otherwise only seen
+// in packet captures. Leak reports of Stmt say whether they were executed regardless of this option.
+// Stats().UnusedStmts counts the warnings.
+func WithUnusedStmtWarning() Option {
+	return func(ld *monitoredDriver) {
+		ld.unusedStmts = &unusedStmts{}
+	}
+}
+
+type unusedStmts struct {
+	count atomic.Int64
+}
+
+// recordPreparer records the callers preparing the Stmt, with WithUnusedStmtWarning.
+// They are only symbolized if the Stmt turns out to be unused.
+func (s *monitoredStmt) recordPreparer() {
+	if s.monitoredConn.detector.unusedStmts == nil {
+		return
+	}
+
+	var pcs [maxCallerDepth]uintptr
+	n := runtime.Callers(3, pcs[:])
+	s.preparedBy = append([]uintptr(nil), pcs[:n]...)
+}
+
+// checkUnused warns about closing the Stmt if it was never executed, with WithUnusedStmtWarning.
+func (s *monitoredStmt) checkUnused() {
+	d := s.monitoredConn.detector
+	if d.unusedStmts == nil || s.monitor.ignored || s.monitor.execs.Load() > 0 {
+		return
+	}
+	d.unusedStmts.count.Add(1)
+
+	msg := fmt.Sprintf("%sStmt closed without being executed: %q", d.logPrefix(), shortenQuery(d.anonymizeQuery(s.query)))
+	if frames := trimFrames(callerFrames(s.preparedBy)); len(frames) > 0 {
+		msg += fmt.Sprintf(" prepared by %s (%s:%d)", frames[0].Function, frames[0].File, frames[0].Line)
+	}
+	log.Print(msg)
+}
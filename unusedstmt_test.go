@@ -0,0 +1,45 @@
+//go:build !sqleak_noop
+
+package sqleak
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestUnusedStmtWarning(t *testing.T) {
+	mc, fc, logOutput := newTestConn(t, WithTimeout(time.Second), WithUnusedStmtWarning())
+	ctx := context.Background()
+
+	used, _ := mc.Prepare("SELECT 1")
+	rows, _ := used.Query(nil) //nolint:staticcheck
+	rows.Close()
+	used.Close()
+
+	unused, _ := mc.Prepare("SELECT 2")
+	unused.Close()
+	unused.Close()
+
+	leaked, _ := mc.PrepareContext(ctx, "SELECT 3")
+	fc.Advance(time.Second)
+	leaked.Close()
+
+	out := logOutput.String()
+	for _, want := range []string{
+		`Stmt closed without being executed: "SELECT 2" prepared by `,
+		`Stmt closed without being executed: "SELECT 3" prepared by `,
+		`(leak #1, resource #3, query "SELECT 3", never executed, opened `,
+	} {
+		if !strings.Contains(out, want) {
+			t.Errorf("expected %q in log, got:\n%s", want, out)
+		}
+	}
+	if n := strings.Count(out, "closed without being executed"); n != 2 {
+		t.Errorf("got %d warnings, want 2:\n%s", n, out)
+	}
+	if n := mc.detector.Stats().UnusedStmts; n != 2 {
+		t.Errorf("got %d unused Stmt, want 2", n)
+	}
+}
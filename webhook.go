@@ -0,0 +1,194 @@
+package sqleak
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"strconv"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// Defaults of NewWebhookSink.
+const (
+	defaultWebhookQueue   = 64
+	defaultWebhookRetries = 3
+	defaultWebhookBackoff = time.Second
+	maxWebhookBackoff     = time.Minute
+	webhookTimeout        = 10 * time.Second
+)
+
+// errWebhookClosed is returned for events written to a closed WebhookSink.
+var errWebhookClosed = errors.New("sqleak: webhook sink closed")
+
+// WebhookSink is a Sink posting every leak event as a JSON object, encoded like WithJSONOutput, to a webhook URL,
+// e.g. of an incident bot. Events are queued and posted by a background goroutine, so a slow endpoint never
+// blocks leak detection. Failed posts are retried with exponential backoff.
+type WebhookSink struct {
+	url     string
+	client  *http.Client
+	header  http.Header
+	retries int
+	backoff time.Duration
+
+	queue   chan []byte
+	mu      sync.RWMutex // guards closing queue against concurrent writes
+	closed  bool
+	done    chan struct{}
+	dropped atomic.Int64
+}
+
+// WebhookOption configures a WebhookSink.
+type WebhookOption func(*WebhookSink)
+
+// WithWebhookClient posts with client instead of an http.Client with a 10 second timeout.
+func WithWebhookClient(client *http.Client) WebhookOption {
+	return func(s *WebhookSink) {
+		s.client = client
+	}
+}
+
+// WithWebhookHeader adds a header to every post, e.g. for authentication.
+func WithWebhookHeader(key, value string) WebhookOption {
+	return func(s *WebhookSink) {
+		s.header.Add(key, value)
+	}
+}
+
+// WithWebhookRetries retries a failed post up to retries times, waiting backoff before the first retry and twice as
+// long before every further one, at most a minute. Posts fail on network errors, 429 and 5xx responses; a
+// Retry-After header in seconds replaces the backoff. The default is 3 retries starting at 1 second.
+func WithWebhookRetries(retries int, backoff time.Duration) WebhookOption {
+	return func(s *WebhookSink) {
+		s.retries, s.backoff = retries, backoff
+	}
+}
+
+// WithWebhookQueue sets how many events may wait to be posted, 64 by default. Events exceeding the queue are dropped
+// and counted, see WebhookSink.Dropped.
+func WithWebhookQueue(size int) WebhookOption {
+	return func(s *WebhookSink) {
+		s.queue = make(chan []byte, size)
+	}
+}
+
+// NewWebhookSink starts a sink posting to url. Close it to post the queued events and stop its goroutine.
+func NewWebhookSink(url string, opts ...WebhookOption) *WebhookSink {
+	s := &WebhookSink{
+		url:     url,
+		client:  &http.Client{Timeout: webhookTimeout},
+		header:  http.Header{"Content-Type": []string{"application/json"}},
+		retries: defaultWebhookRetries,
+		backoff: defaultWebhookBackoff,
+		queue:   make(chan []byte, defaultWebhookQueue),
+		done:    make(chan struct{}),
+	}
+	for _, opt := range opts {
+		opt(s)
+	}
+
+	go s.run()
+	return s
+}
+
+// WriteEvent queues ev for posting. It fails if the queue is full or the sink is closed.
+func (s *WebhookSink) WriteEvent(ev LeakEvent) error {
+	b, err := ev.jsonLine(false)
+	if err != nil {
+		return err
+	}
+
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	if s.closed {
+		return errWebhookClosed
+	}
+
+	select {
+	case s.queue <- b:
+		return nil
+	default:
+		s.dropped.Add(1)
+		return fmt.Errorf("sqleak: webhook queue full, dropped %d events so far", s.dropped.Load())
+	}
+}
+
+// Dropped returns the number of events dropped because the queue was full.
+func (s *WebhookSink) Dropped() int64 {
+	return s.dropped.Load()
+}
+
+// Close stops accepting events and waits until the queued ones were posted.
+func (s *WebhookSink) Close() error {
+	s.mu.Lock()
+	if !s.closed {
+		s.closed = true
+		close(s.queue)
+	}
+	s.mu.Unlock()
+
+	<-s.done
+	return nil
+}
+
+func (s *WebhookSink) run() {
+	defer close(s.done)
+
+	for body := range s.queue {
+		if err := s.post(body); err != nil {
+			log.Printf("sqleak: failed to post leak report to webhook: %v", err)
+		}
+	}
+}
+
+// post sends body, retrying failures.
+func (s *WebhookSink) post(body []byte) error {
+	backoff := s.backoff
+	for attempt := 0; ; attempt++ {
+		retryable, retryAfter, err := s.send(body)
+		if err == nil || !retryable || attempt >= s.retries {
+			return err
+		}
+
+		wait := backoff
+		if retryAfter > 0 {
+			wait = retryAfter
+		}
+		time.Sleep(wait)
+		backoff = min(2*backoff, maxWebhookBackoff)
+	}
+}
+
+// send posts body once, returning whether a failure is worth retrying and the delay requested by the endpoint.
+func (s *WebhookSink) send(body []byte) (bool, time.Duration, error) {
+	req, err := http.NewRequest(http.MethodPost, s.url, bytes.NewReader(body))
+	if err != nil {
+		return false, 0, err
+	}
+	for key, values := range s.header {
+		req.Header[key] = values
+	}
+
+	resp, err := s.client.Do(req)
+	if err != nil {
+		return true, 0, err
+	}
+	defer resp.Body.Close()
+	_, _ = io.Copy(io.Discard, resp.Body)
+
+	if resp.StatusCode < 300 {
+		return false, 0, nil
+	}
+
+	var retryAfter time.Duration
+	if seconds, err := strconv.Atoi(resp.Header.Get("Retry-After")); err == nil && seconds > 0 {
+		retryAfter = time.Duration(seconds) * time.Second
+	}
+	retryable := resp.StatusCode == http.StatusTooManyRequests || resp.StatusCode >= 500
+	return retryable, retryAfter, fmt.Errorf("%s responded %s", s.url, resp.Status)
+}
@@ -0,0 +1,92 @@
+//go:build !sqleak_noop
+
+package sqleak
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestWebhookSink(t *testing.T) {
+	var (
+		mu       sync.Mutex
+		attempts int
+		received []LeakEvent
+	)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		defer mu.Unlock()
+
+		attempts++
+		if r.Header.Get("Authorization") != "Bearer token" || r.Header.Get("Content-Type") != "application/json" {
+			t.Errorf("unexpected headers %v", r.Header)
+		}
+		if attempts == 1 {
+			w.WriteHeader(http.StatusServiceUnavailable)
+			return
+		}
+
+		var ev LeakEvent
+		if err := json.NewDecoder(r.Body).Decode(&ev); err != nil {
+			t.Error(err)
+		}
+		received = append(received, ev)
+	}))
+	defer server.Close()
+
+	sink := NewWebhookSink(server.URL, WithWebhookRetries(2, time.Millisecond), WithWebhookHeader("Authorization", "Bearer token"))
+	mc, fc, _ := newTestConn(t, WithTimeout(time.Second), WithSink(sink))
+
+	rows, _ := mc.QueryContext(context.Background(), "SELECT 1", nil)
+	fc.Advance(time.Second)
+	_ = rows.Close()
+	_ = sink.Close()
+
+	mu.Lock()
+	defer mu.Unlock()
+	if attempts != 3 || len(received) != 2 {
+		t.Fatalf("expected a retried leak and its late close, got %d attempts and %+v", attempts, received)
+	}
+	if received[0].Type != EventLeak || received[0].Query != "SELECT 1" || received[1].Type != EventClosedLate {
+		t.Errorf("unexpected events %+v", received)
+	}
+
+	if err := sink.WriteEvent(received[0]); err != errWebhookClosed {
+		t.Errorf("expected an error after Close, got %v", err)
+	}
+}
+
+func TestWebhookSinkGivesUp(t *testing.T) {
+	var (
+		mu       sync.Mutex
+		attempts int
+	)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		attempts++
+		mu.Unlock()
+		w.WriteHeader(http.StatusBadRequest)
+	}))
+	defer server.Close()
+
+	_, _, logOutput := newTestConn(t)
+	sink := NewWebhookSink(server.URL, WithWebhookRetries(5, time.Millisecond), WithWebhookQueue(1))
+	ev := LeakEvent{Type: EventLeak, Kind: KindRows, Timeout: time.Second}
+	_ = sink.WriteEvent(ev)
+	_ = sink.Close()
+
+	mu.Lock()
+	defer mu.Unlock()
+	if attempts != 1 {
+		t.Errorf("expected no retries of a client error, got %d attempts", attempts)
+	}
+	if !strings.Contains(logOutput.String(), "responded 400 Bad Request") {
+		t.Errorf("expected the failure to be logged, got:\n%s", logOutput.String())
+	}
+}